@@ -20,6 +20,7 @@ type ConsoleOptions struct {
 	AppName       string
 	EcsCluster    string
 	Task          string
+	TaskIndex     int
 	CustomPrompt  bool
 	ContainerName string
 	Explain       bool
@@ -74,8 +75,11 @@ func NewCmdConsole(project *config.Project) *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&o.EcsCluster, "ecs-cluster", "", "set ECS cluster name")
-	cmd.Flags().StringVar(&o.ContainerName, "container-name", "", "set container name")
 	cmd.Flags().StringVar(&o.Task, "task", "", "set task id")
+	cmd.Flags().IntVar(&o.TaskIndex, "task-index", -1, "select running task by index, the oldest task has index 0")
+	cmd.Flags().StringVar(&o.ContainerName, "container", "", "set container name")
+	cmd.Flags().StringVar(&o.ContainerName, "container-name", "", "set container name")
+	_ = cmd.Flags().MarkDeprecated("container-name", "use --container instead")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
 	cmd.Flags().BoolVar(&o.CustomPrompt, "custom-prompt", false, "enable custom prompt in the console")
 
@@ -97,10 +101,6 @@ func (o *ConsoleOptions) Complete(cmd *cobra.Command) error {
 
 	o.AppName = cmd.Flags().Args()[0]
 
-	if len(o.ContainerName) == 0 {
-		o.ContainerName = o.AppName
-	}
-
 	return nil
 }
 
@@ -131,33 +131,21 @@ func (o *ConsoleOptions) Run() error {
 	logrus.Infof("app name: %s, cluster name: %s", appName, o.EcsCluster)
 	logrus.Infof("region: %s, profile: %s", o.Config.AwsProfile, o.Config.AwsRegion)
 
-	s, _ := pterm.DefaultSpinner.WithRemoveWhenDone().Start("Getting access to container...")
-
-	if o.Task == "" {
-		lto, err := o.Config.AWSClient.ECSClient.ListTasks(&ecs.ListTasksInput{
-			Cluster:       &o.EcsCluster,
-			DesiredStatus: aws.String(ecs.DesiredStatusRunning),
-			ServiceName:   &appName,
-		})
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "ClusterNotFoundException":
-				return fmt.Errorf("ECS cluster %s not found", o.EcsCluster)
-			default:
-				return err
-			}
-		}
-
-		logrus.Debugf("list task output: %s", lto)
-
-		if len(lto.TaskArns) == 0 {
-			return fmt.Errorf("running task not found")
-		}
-
-		o.Task = *lto.TaskArns[0]
+	target := &execTarget{
+		Cluster:   o.EcsCluster,
+		Service:   appName,
+		AppName:   o.AppName,
+		Task:      o.Task,
+		TaskIndex: o.TaskIndex,
+		Container: o.ContainerName,
+	}
+	if err := target.resolve(o.Config); err != nil {
+		return err
 	}
 
-	s.UpdateText("Executing command...")
+	logrus.Infof("task: %s, container: %s", target.Task, target.Container)
+
+	s, _ := pterm.DefaultSpinner.WithRemoveWhenDone().Start("Executing command...")
 	consoleCommand := `/bin/sh`
 
 	if o.CustomPrompt {
@@ -168,10 +156,10 @@ func (o *ConsoleOptions) Run() error {
 	}
 
 	out, err := o.Config.AWSClient.ECSClient.ExecuteCommand(&ecs.ExecuteCommandInput{
-		Container:   &o.ContainerName,
+		Container:   &target.Container,
 		Interactive: aws.Bool(true),
 		Cluster:     &o.EcsCluster,
-		Task:        &o.Task,
+		Task:        &target.Task,
 		Command:     aws.String(consoleCommand),
 	})
 	if aerr, ok := err.(awserr.Error); ok {
@@ -30,6 +30,9 @@ func TestConsole(t *testing.T) {
 			NextToken: nil,
 			TaskArns:  []*string{aws.String("test")},
 		}, nil).Times(1)
+		m.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+			Tasks: []*ecs.Task{{TaskArn: aws.String("test")}},
+		}, nil).Times(1)
 		m.EXPECT().ExecuteCommand(gomock.Any()).Return(&ecs.ExecuteCommandOutput{
 			Session: &ecs.Session{
 				SessionId:  aws.String("test"),
@@ -118,6 +121,9 @@ func TestConsole(t *testing.T) {
 					NextToken: nil,
 					TaskArns:  []*string{aws.String("test")},
 				}, nil).Times(1)
+				m.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+					Tasks: []*ecs.Task{{TaskArn: aws.String("test")}},
+				}, nil).Times(1)
 				m.EXPECT().ExecuteCommand(gomock.Any()).Return(nil, awserr.New(ecs.ErrCodeClusterNotFoundException, "", nil)).Times(1)
 			},
 		},
@@ -131,6 +137,9 @@ func TestConsole(t *testing.T) {
 					NextToken: nil,
 					TaskArns:  []*string{aws.String("test")},
 				}, nil).Times(1)
+				m.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+					Tasks: []*ecs.Task{{TaskArn: aws.String("test")}},
+				}, nil).Times(1)
 				m.EXPECT().ExecuteCommand(gomock.Any()).Return(nil, awserr.New("", "", nil)).Times(1)
 			},
 		},
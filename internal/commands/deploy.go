@@ -8,6 +8,7 @@ import (
 	"github.com/hazelops/ize/internal/manager"
 	"github.com/hazelops/ize/internal/manager/alias"
 	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/manager/k8s"
 	"github.com/hazelops/ize/internal/manager/serverless"
 	"github.com/hazelops/ize/internal/requirements"
 	"github.com/hazelops/ize/pkg/templates"
@@ -103,6 +104,12 @@ func (o *DeployOptions) Complete(cmd *cobra.Command) error {
 		}
 	}
 
+	if len(o.Config.K8s) != 0 {
+		if err := requirements.CheckRequirements(k8sRequirements(o.Config)...); err != nil {
+			return err
+		}
+	}
+
 	o.AppName = cmd.Flags().Args()[0]
 
 	return nil
@@ -150,6 +157,13 @@ func (o *DeployOptions) Run() error {
 			App:     app,
 		}
 	}
+	if app, ok := o.Config.K8s[o.AppName]; ok {
+		app.Name = o.AppName
+		m = &k8s.Manager{
+			Project: o.Config,
+			App:     app,
+		}
+	}
 	if app, ok := o.Config.Alias[o.AppName]; ok {
 		app.Name = o.AppName
 		m = &alias.Manager{
@@ -36,7 +36,7 @@ func NewCmdDoc() *cobra.Command {
 				return err
 			}
 
-			sections := []string{"main", "terraform", "ecs", "alias", "serverless", "tunnel"}
+			sections := []string{"main", "terraform", "ecs", "k8s", "alias", "serverless", "tunnel"}
 
 			err = os.MkdirAll("./website/schema", 0777)
 			if err != nil {
@@ -10,6 +10,7 @@ import (
 	"github.com/hazelops/ize/internal/manager"
 	"github.com/hazelops/ize/internal/manager/alias"
 	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/manager/k8s"
 	"github.com/hazelops/ize/internal/manager/serverless"
 	"github.com/hazelops/ize/internal/requirements"
 	"github.com/hazelops/ize/internal/terraform"
@@ -27,6 +28,9 @@ type DownOptions struct {
 	AutoApprove      bool
 	SkipGen          bool
 	UseYarn          bool
+	Parallelism      int
+	Serial           bool
+	KeepGoing        bool
 	ui               terminal.UI
 }
 
@@ -95,6 +99,9 @@ func NewCmdDown(project *config.Project) *cobra.Command {
 	cmd.Flags().BoolVar(&o.AutoApprove, "auto-approve", false, "approve deploy all")
 	cmd.Flags().BoolVar(&o.UseYarn, "use-yarn", false, "execute commands using yarn")
 	cmd.Flags().BoolVar(&o.SkipGen, "skip-gen", false, "skip generating terraform files")
+	cmd.Flags().IntVar(&o.Parallelism, "parallelism", 0, "maximum number of apps processed at the same time (default is max_parallel from ize.toml or no limit)")
+	cmd.Flags().BoolVar(&o.Serial, "serial", false, "process apps one at a time in dependency order")
+	cmd.Flags().BoolVar(&o.KeepGoing, "keep-going", false, "continue with independent apps when an app fails (apps depending on it are skipped)")
 
 	cmd.AddCommand(
 		NewCmdDownInfra(project),
@@ -146,12 +153,26 @@ func (o *DownOptions) Complete(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if len(o.Config.K8s) != 0 {
+		if err := requirements.CheckRequirements(k8sRequirements(o.Config)...); err != nil {
+			return err
+		}
+	}
+
+	if o.Parallelism == 0 {
+		o.Parallelism = o.Config.MaxParallel
+	}
+
 	o.ui = terminal.ConsoleUI(context.Background(), o.Config.PlainText)
 
 	return nil
 }
 
 func (o *DownOptions) Validate() error {
+	if o.Parallelism < 0 {
+		return fmt.Errorf("can't validate options: parallelism must not be negative")
+	}
+
 	if o.AppName == "" {
 		err := o.validateAll()
 		if err != nil {
@@ -170,7 +191,10 @@ func (o *DownOptions) Validate() error {
 func (o *DownOptions) Run() error {
 	ui := o.ui
 	if o.AppName == "" {
-		err := destroyAll(ui, o)
+		report := &manager.Report{}
+
+		err := destroyAll(ui, o, report)
+		renderSummary(ui, report)
 		if err != nil {
 			return err
 		}
@@ -212,34 +236,41 @@ func (o *DownOptions) validateAll() error {
 	return nil
 }
 
-func destroyAll(ui terminal.UI, o *DownOptions) error {
+func destroyAll(ui terminal.UI, o *DownOptions, report *manager.Report) error {
 
-	ui.Output("Destroying apps...", terminal.WithHeaderStyle())
+	ui.Output("Destroying apps and stacks...", terminal.WithHeaderStyle())
 	sg := ui.StepGroup()
 	defer sg.Wait()
 
-	err := manager.InReversDependencyOrder(aws.BackgroundContext(), o.Config.GetApps(), func(c context.Context, name string) error {
-		o.Config.AwsProfile = o.Config.Terraform["infra"].AwsProfile
+	// apps and stacks are destroyed concurrently and share the config, so the profile is set once
+	if infra, ok := o.Config.Terraform["infra"]; ok {
+		o.Config.AwsProfile = infra.AwsProfile
+	}
+
+	err := manager.InReversDependencyOrder(aws.BackgroundContext(), o.Config.GetStatesAndApps(), func(c context.Context, name string) error {
+		if _, ok := o.Config.Terraform[name]; ok {
+			return destroyInfra(name, o.Config, o.SkipGen, ui)
+		}
 
 		return destroyApp(name, o.Config, o.AutoApprove, ui)
-	})
+	}, manager.WithParallelism(o.Parallelism), manager.WithSerial(o.Serial), manager.WithKeepGoing(o.KeepGoing), manager.WithReport(report))
 	if err != nil {
+		// Infra is kept while there is anything running on it
+		if _, ok := o.Config.Terraform["infra"]; ok {
+			report.Skip("infra")
+		}
 		return err
 	}
 
 	if _, ok := o.Config.Terraform["infra"]; ok {
-		err = destroyInfra("infra", o.Config, o.SkipGen, ui)
+		err = report.Run("infra", func() error {
+			return destroyInfra("infra", o.Config, o.SkipGen, ui)
+		})
 		if err != nil {
 			return err
 		}
 	}
 
-	err = manager.InReversDependencyOrder(aws.BackgroundContext(), o.Config.GetStates(), func(c context.Context, name string) error {
-		o.Config.AwsProfile = o.Config.Terraform["infra"].AwsProfile
-
-		return destroyInfra(name, o.Config, o.SkipGen, ui)
-	})
-
 	ui.Output("Destroy all completed!\n", terminal.WithSuccessStyle())
 	time.Sleep(time.Millisecond * 200)
 
@@ -325,6 +356,14 @@ func destroyApp(name string, cfg *config.Project, autoApprove bool, ui terminal.
 		}
 		icon = app.Icon
 	}
+	if app, ok := cfg.K8s[name]; ok {
+		app.Name = name
+		m = &k8s.Manager{
+			Project: cfg,
+			App:     app,
+		}
+		icon = app.Icon
+	}
 	if app, ok := cfg.Alias[name]; ok {
 		app.Name = name
 		m = &alias.Manager{
@@ -12,13 +12,16 @@ import (
 )
 
 type DownInfraOptions struct {
-	Config     *config.Project
-	ui         terminal.UI
-	Version    string
-	AwsProfile string
-	AwsRegion  string
-	SkipGen    bool
-	OnlyInfra  bool
+	Config      *config.Project
+	ui          terminal.UI
+	Version     string
+	AwsProfile  string
+	AwsRegion   string
+	SkipGen     bool
+	OnlyInfra   bool
+	Parallelism int
+	Serial      bool
+	KeepGoing   bool
 }
 
 func NewDownInfraFlags(project *config.Project) *DownInfraOptions {
@@ -59,6 +62,9 @@ func NewCmdDownInfra(project *config.Project) *cobra.Command {
 	cmd.Flags().StringVar(&o.AwsRegion, "infra.terraform.aws-region", "", "set aws region")
 	cmd.Flags().BoolVar(&o.SkipGen, "skip-gen", false, "skip generating terraform files")
 	cmd.Flags().BoolVar(&o.OnlyInfra, "only-infra", false, "down only infra state")
+	cmd.Flags().IntVar(&o.Parallelism, "parallelism", 0, "maximum number of apps processed at the same time (default is max_parallel from ize.toml or no limit)")
+	cmd.Flags().BoolVar(&o.Serial, "serial", false, "process apps one at a time in dependency order")
+	cmd.Flags().BoolVar(&o.KeepGoing, "keep-going", false, "continue with independent apps when an app fails (apps depending on it are skipped)")
 
 	return cmd
 }
@@ -98,12 +104,20 @@ func (o *DownInfraOptions) Complete() error {
 		}
 	}
 
+	if o.Parallelism == 0 {
+		o.Parallelism = o.Config.MaxParallel
+	}
+
 	o.ui = terminal.ConsoleUI(context.Background(), o.Config.PlainText)
 
 	return nil
 }
 
 func (o *DownInfraOptions) Validate() error {
+	if o.Parallelism < 0 {
+		return fmt.Errorf("can't validate options: parallelism must not be negative")
+	}
+
 	if len(o.Config.Env) == 0 {
 		return fmt.Errorf("env must be specified")
 	}
@@ -113,19 +127,25 @@ func (o *DownInfraOptions) Validate() error {
 
 func (o *DownInfraOptions) Run() error {
 	ui := o.ui
+	report := &manager.Report{}
 
 	if _, ok := o.Config.Terraform["infra"]; ok {
-		err := destroyInfra("infra", o.Config, o.SkipGen, ui)
+		err := report.Run("infra", func() error {
+			return destroyInfra("infra", o.Config, o.SkipGen, ui)
+		})
 		if err != nil {
+			report.Skip(graphNames(o.Config.GetStates())...)
+			renderSummary(ui, report)
 			return err
 		}
 	}
 
 	err := manager.InReversDependencyOrder(aws.BackgroundContext(), o.Config.GetStates(), func(c context.Context, name string) error {
 		return destroyInfra(name, o.Config, o.SkipGen, ui)
-	})
+	}, manager.WithParallelism(o.Parallelism), manager.WithSerial(o.Serial), manager.WithKeepGoing(o.KeepGoing), manager.WithReport(report))
+	renderSummary(ui, report)
 	if err != nil {
-
+		return err
 	}
 
 	return nil
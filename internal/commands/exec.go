@@ -2,13 +2,17 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/service/ecs"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager/ecs"
 	"github.com/hazelops/ize/internal/requirements"
 	"github.com/hazelops/ize/pkg/ssmsession"
 	"github.com/hazelops/ize/pkg/templates"
@@ -23,8 +27,11 @@ type ExecOptions struct {
 	EcsCluster    string
 	Command       []string
 	Task          string
+	TaskIndex     int
 	ContainerName string
 	Explain       bool
+	NoTTY         bool
+	AllTasks      bool
 }
 
 var explainExecTmpl = `
@@ -41,7 +48,16 @@ aws ecs execute-command  \
 
 var execExample = templates.Examples(`
 	# Connect to a container in the ECS via AWS SSM and run command.
-	ize exec goblin ps aux
+	ize exec goblin -- ps aux
+
+	# Run command in the sidecar container of the second oldest task
+	ize exec goblin --task-index 1 --container nginx -- nginx -T
+
+	# Run command without a terminal, ize exits with the exit code of the command
+	ize exec goblin --no-tty -- bin/rails db:migrate:status
+
+	# Run command on every task of the service
+	ize exec goblin --all-tasks -- cat /app/REVISION
 `)
 
 func NewExecFlags(project *config.Project) *ExecOptions {
@@ -84,8 +100,13 @@ func NewCmdExec(project *config.Project) *cobra.Command {
 
 	cmd.Flags().StringVar(&o.EcsCluster, "ecs-cluster", "", "set ECS cluster name")
 	cmd.Flags().StringVar(&o.Task, "task", "", "set task id")
+	cmd.Flags().IntVar(&o.TaskIndex, "task-index", -1, "select running task by index, the oldest task has index 0")
+	cmd.Flags().StringVar(&o.ContainerName, "container", "", "set container name")
 	cmd.Flags().StringVar(&o.ContainerName, "container-name", "", "set container name")
+	_ = cmd.Flags().MarkDeprecated("container-name", "use --container instead")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
+	cmd.Flags().BoolVar(&o.NoTTY, "no-tty", false, "run command without a terminal, print its output and exit with its exit code")
+	cmd.Flags().BoolVar(&o.AllTasks, "all-tasks", false, "run command on every running task of the service without a terminal, output is prefixed with task id")
 
 	return cmd
 }
@@ -101,10 +122,6 @@ func (o *ExecOptions) Complete(cmd *cobra.Command, args []string, argsLenAtDash
 
 	o.AppName = cmd.Flags().Args()[0]
 
-	if len(o.ContainerName) == 0 {
-		o.ContainerName = o.AppName
-	}
-
 	if argsLenAtDash > -1 {
 		o.Command = args[argsLenAtDash:]
 	}
@@ -121,6 +138,10 @@ func (o *ExecOptions) Validate() error {
 		return fmt.Errorf("can't validate: you must specify at least one command for the container")
 	}
 
+	if o.AllTasks && (len(o.Task) != 0 || o.TaskIndex >= 0) {
+		return fmt.Errorf("can't validate: --all-tasks can't be used with --task or --task-index")
+	}
+
 	return nil
 }
 
@@ -146,57 +167,193 @@ func (o *ExecOptions) Run() error {
 	logrus.Infof("app name: %s, cluster name: %s", appName, o.EcsCluster)
 	logrus.Infof("region: %s, profile: %s", o.Config.AwsProfile, o.Config.AwsRegion)
 
-	s, _ := pterm.DefaultSpinner.WithRemoveWhenDone().Start("Getting access to container...")
+	if o.AllTasks {
+		return o.runAllTasks(appName)
+	}
 
-	if o.Task == "" {
-		lto, err := o.Config.AWSClient.ECSClient.ListTasks(&ecs.ListTasksInput{
-			Cluster:       &o.EcsCluster,
-			DesiredStatus: aws.String(ecs.DesiredStatusRunning),
-			ServiceName:   &appName,
-		})
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "ClusterNotFoundException":
-				return fmt.Errorf("ECS cluster %s not found", o.EcsCluster)
-			default:
-				return err
-			}
+	target := &execTarget{
+		Cluster:        o.EcsCluster,
+		Service:        appName,
+		AppName:        o.AppName,
+		Task:           o.Task,
+		TaskIndex:      o.TaskIndex,
+		Container:      o.ContainerName,
+		NonInteractive: o.NoTTY,
+	}
+	if err := target.resolve(o.Config); err != nil {
+		return err
+	}
+
+	logrus.Infof("task: %s, container: %s", target.Task, target.Container)
+
+	ssmCmd := ssmsession.NewSSMPluginCommand(o.Config.AwsRegion)
+
+	if o.NoTTY {
+		session, err := o.executeCommand(target.Task, target.Container, ssmsession.WrapCommand(strings.Join(o.Command, " ")))
+		if err != nil {
+			return err
+		}
+
+		code, err := ssmCmd.StartNonInteractive(session, os.Stdout)
+		if err != nil {
+			return err
+		}
+
+		if code != 0 {
+			return &ExitCodeError{Code: code, Err: fmt.Errorf("command exited with code %d", code)}
+		}
+
+		return nil
+	}
+
+	s, _ := pterm.DefaultSpinner.WithRemoveWhenDone().Start("Executing command...")
+
+	session, err := o.executeCommand(target.Task, target.Container, strings.Join(o.Command, " "))
+	if err != nil {
+		return err
+	}
+
+	s.Success()
+
+	err = ssmCmd.Start(session)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// runAllTasks runs the command on every running task of the service concurrently.
+// The exit code is the first non-zero exit code of the command in the order of tasks.
+func (o *ExecOptions) runAllTasks(service string) error {
+	tasks, err := ecs.RunningTasks(o.Config.AWSClient.ECSClient, o.EcsCluster, service)
+	if err != nil {
+		return err
+	}
+
+	if len(tasks) == 0 {
+		return fmt.Errorf("running task not found")
+	}
+
+	container := o.ContainerName
+	if len(container) == 0 {
+		container = o.AppName
+	}
+
+	for _, t := range tasks {
+		if !t.HasContainer(container) {
+			return fmt.Errorf("task %s has no container %s, containers: %s", t.ID, container, strings.Join(t.Containers, ", "))
 		}
+	}
+
+	ssmCmd := ssmsession.NewSSMPluginCommand(o.Config.AwsRegion)
+	command := ssmsession.WrapCommand(strings.Join(o.Command, " "))
+
+	codes := make([]int, len(tasks))
+	errs := make([]error, len(tasks))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
 
-		logrus.Debugf("list task output: %s", lto)
+	for i, t := range tasks {
+		wg.Add(1)
+		go func(i int, t ecs.RunningTask) {
+			defer wg.Done()
+
+			w := &prefixWriter{mu: &mu, w: os.Stdout, prefix: pterm.FgCyan.Sprintf("[%s] ", shortTaskID(t.ID))}
+
+			session, err := o.executeCommand(t.Arn, container, command)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			codes[i], errs[i] = ssmCmd.StartNonInteractive(session, w)
+		}(i, t)
+	}
+
+	wg.Wait()
+
+	failed, code := 0, 0
+
+	for i, t := range tasks {
+		c := codes[i]
+
+		switch {
+		case errs[i] != nil:
+			pterm.Error.Printfln("%s: %s", t.ID, errs[i])
+			c = 1
+		case c != 0:
+			pterm.Error.Printfln("%s: command exited with code %d", t.ID, c)
+		default:
+			continue
+		}
 
-		if len(lto.TaskArns) == 0 {
-			return fmt.Errorf("running task not found")
+		failed++
+		if code == 0 {
+			code = c
 		}
+	}
 
-		o.Task = *lto.TaskArns[0]
+	if failed != 0 {
+		return &ExitCodeError{Code: code, Err: fmt.Errorf("command failed on %d of %d tasks", failed, len(tasks))}
 	}
 
-	s.UpdateText("Executing command...")
+	return nil
+}
 
-	out, err := o.Config.AWSClient.ECSClient.ExecuteCommand(&ecs.ExecuteCommandInput{
-		Container:   &o.AppName,
+func (o *ExecOptions) executeCommand(task, container, command string) (*awsecs.Session, error) {
+	out, err := o.Config.AWSClient.ECSClient.ExecuteCommand(&awsecs.ExecuteCommandInput{
+		Container:   &container,
 		Interactive: aws.Bool(true),
 		Cluster:     &o.EcsCluster,
-		Task:        &o.Task,
-		Command:     aws.String(strings.Join(o.Command, " ")),
+		Task:        &task,
+		Command:     aws.String(command),
 	})
 	if aerr, ok := err.(awserr.Error); ok {
 		switch aerr.Code() {
 		case "ClusterNotFoundException":
-			return fmt.Errorf("ECS cluster %s not found", o.EcsCluster)
+			return nil, fmt.Errorf("ECS cluster %s not found", o.EcsCluster)
 		default:
-			return err
+			return nil, err
 		}
 	}
+	if err != nil {
+		return nil, err
+	}
 
-	s.Success()
+	return out.Session, nil
+}
 
-	ssmCmd := ssmsession.NewSSMPluginCommand(o.Config.AwsRegion)
-	err = ssmCmd.Start(out.Session)
-	if err != nil {
-		return err
+// prefixWriter writes lines with the prefix, writes of concurrent prefixWriters sharing the mutex don't interleave
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, line := range strings.SplitAfter(string(b), "\n") {
+		if len(line) == 0 {
+			continue
+		}
+
+		if _, err := io.WriteString(p.w, p.prefix+line); err != nil {
+			return 0, err
+		}
 	}
 
-	return nil
+	return len(b), nil
+}
+
+// shortTaskID returns the first 8 characters of the task id
+func shortTaskID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+
+	return id
 }
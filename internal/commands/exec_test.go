@@ -30,6 +30,9 @@ func TestExec(t *testing.T) {
 			NextToken: nil,
 			TaskArns:  []*string{aws.String("test")},
 		}, nil).Times(1)
+		m.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+			Tasks: []*ecs.Task{{TaskArn: aws.String("test")}},
+		}, nil).Times(1)
 		m.EXPECT().ExecuteCommand(gomock.Any()).Return(&ecs.ExecuteCommandOutput{
 			Session: &ecs.Session{
 				SessionId:  aws.String("test"),
@@ -46,6 +49,7 @@ func TestExec(t *testing.T) {
 		withConfigFile bool
 		env            map[string]string
 		mockECSClient  func(m *mocks.MockECSAPI)
+		plugin         string
 	}{
 		{
 			name:           "success (only config file)",
@@ -90,6 +94,57 @@ func TestExec(t *testing.T) {
 			wantErr:       false,
 			mockECSClient: mockECS,
 		},
+		{
+			name:          "success (no tty)",
+			args:          []string{"exec", "goblin", "--no-tty", "--", "ls"},
+			env:           map[string]string{"ENV": "test", "AWS_PROFILE": "test", "NAMESPACE": "dev-testnut", "AWS_REGION": "us-west-2"},
+			wantErr:       false,
+			mockECSClient: mockECS,
+			plugin:        "#!/bin/bash\nprintf 'Starting session with SessionId: test\\r\\nfile\\r\\n__IZE_EXIT_CODE__:0\\r\\n'",
+		},
+		{
+			name:          "failed (no tty exit code)",
+			args:          []string{"exec", "goblin", "--no-tty", "--", "ls"},
+			env:           map[string]string{"ENV": "test", "AWS_PROFILE": "test", "NAMESPACE": "dev-testnut", "AWS_REGION": "us-west-2"},
+			wantErr:       true,
+			mockECSClient: mockECS,
+			plugin:        "#!/bin/bash\nprintf 'ls: missing\\r\\n__IZE_EXIT_CODE__:2\\r\\n'",
+		},
+		{
+			name:    "success (all tasks)",
+			args:    []string{"exec", "goblin", "--all-tasks", "--", "ls"},
+			env:     map[string]string{"ENV": "test", "AWS_PROFILE": "test", "NAMESPACE": "dev-testnut", "AWS_REGION": "us-west-2"},
+			wantErr: false,
+			mockECSClient: func(m *mocks.MockECSAPI) {
+				m.EXPECT().ListTasks(gomock.Any()).Return(&ecs.ListTasksOutput{
+					TaskArns: []*string{aws.String("task/1"), aws.String("task/2")},
+				}, nil).Times(1)
+				m.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+					Tasks: []*ecs.Task{
+						{TaskArn: aws.String("task/1"), Containers: []*ecs.Container{{Name: aws.String("goblin")}}},
+						{TaskArn: aws.String("task/2"), Containers: []*ecs.Container{{Name: aws.String("goblin")}}},
+					},
+				}, nil).Times(1)
+				m.EXPECT().ExecuteCommand(gomock.Any()).Return(&ecs.ExecuteCommandOutput{
+					Session: &ecs.Session{SessionId: aws.String("test")},
+				}, nil).Times(2)
+			},
+			plugin: "#!/bin/bash\nprintf 'file\\r\\n__IZE_EXIT_CODE__:0\\r\\n'",
+		},
+		{
+			name:    "failed (all tasks without container)",
+			args:    []string{"exec", "goblin", "--all-tasks", "--", "ls"},
+			env:     map[string]string{"ENV": "test", "AWS_PROFILE": "test", "NAMESPACE": "dev-testnut", "AWS_REGION": "us-west-2"},
+			wantErr: true,
+			mockECSClient: func(m *mocks.MockECSAPI) {
+				m.EXPECT().ListTasks(gomock.Any()).Return(&ecs.ListTasksOutput{
+					TaskArns: []*string{aws.String("task/1")},
+				}, nil).Times(1)
+				m.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+					Tasks: []*ecs.Task{{TaskArn: aws.String("task/1"), Containers: []*ecs.Container{{Name: aws.String("nginx")}}}},
+				}, nil).Times(1)
+			},
+		},
 		{
 			name:    "failed (list tasks cluster not found)",
 			args:    []string{"console", "goblin", "--plain-text-output"},
@@ -118,6 +173,9 @@ func TestExec(t *testing.T) {
 					NextToken: nil,
 					TaskArns:  []*string{aws.String("test")},
 				}, nil).Times(1)
+				m.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+					Tasks: []*ecs.Task{{TaskArn: aws.String("test")}},
+				}, nil).Times(1)
 				m.EXPECT().ExecuteCommand(gomock.Any()).Return(nil, awserr.New(ecs.ErrCodeClusterNotFoundException, "", nil)).Times(1)
 			},
 		},
@@ -131,6 +189,9 @@ func TestExec(t *testing.T) {
 					NextToken: nil,
 					TaskArns:  []*string{aws.String("test")},
 				}, nil).Times(1)
+				m.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+					Tasks: []*ecs.Task{{TaskArn: aws.String("test")}},
+				}, nil).Times(1)
 				m.EXPECT().ExecuteCommand(gomock.Any()).Return(nil, awserr.New("", "", nil)).Times(1)
 			},
 		},
@@ -165,7 +226,12 @@ func TestExec(t *testing.T) {
 				setConfigFile(filepath.Join(temp, "ize.toml"), buildToml, t)
 			}
 
-			err = os.WriteFile(filepath.Join(temp, "session-manager-plugin"), []byte("#!/bin/bash\necho \"session-manager-plugin\""), 0777)
+			plugin := tt.plugin
+			if len(plugin) == 0 {
+				plugin = "#!/bin/bash\necho \"session-manager-plugin\""
+			}
+
+			err = os.WriteFile(filepath.Join(temp, "session-manager-plugin"), []byte(plugin), 0777)
 			if err != nil {
 				t.Error(err)
 			}
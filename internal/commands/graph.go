@@ -0,0 +1,382 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager"
+	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	graphFormatDot     = "dot"
+	graphFormatMermaid = "mermaid"
+	graphFormatJSON    = "json"
+)
+
+type GraphOptions struct {
+	Config *config.Project
+	Format string
+	out    io.Writer
+}
+
+// graphNode is an app or a terraform stack of the project
+type graphNode struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Icon      string   `json:"icon,omitempty"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	InCycle   bool     `json:"in_cycle"`
+}
+
+// graphEdge is a depends_on reference. Missing is set for references to unknown apps or stacks.
+// Implicit is set for dependencies which aren't in depends_on: apps without stack dependencies
+// depend on all stacks and everything without dependencies depends on the infra stack
+type graphEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Cycle    bool   `json:"cycle,omitempty"`
+	Missing  bool   `json:"missing,omitempty"`
+	Implicit bool   `json:"implicit,omitempty"`
+}
+
+type projectGraph struct {
+	Nodes  []graphNode `json:"nodes"`
+	Edges  []graphEdge `json:"edges"`
+	Cycles [][]string  `json:"cycles"`
+}
+
+var graphLongDesc = templates.LongDesc(`
+	Show the dependency graph of apps and terraform stacks.
+	Edges point from an app or a stack to what it depends on.
+	Dotted edges are implicit dependencies: apps which don't depend on any stack are deployed
+	after all stacks, and the infra stack is deployed before everything else.
+	Cycles and depends_on references to unknown apps or stacks are highlighted.
+`)
+
+var graphExample = templates.Examples(`
+	# Render the graph with Graphviz
+	ize graph | dot -Tpng > graph.png
+
+	# Print the graph as a Mermaid flowchart
+	ize graph --format mermaid
+
+	# Print the graph as JSON
+	ize graph --format json
+`)
+
+func NewGraphFlags(project *config.Project) *GraphOptions {
+	return &GraphOptions{
+		Config: project,
+	}
+}
+
+func NewCmdGraph(project *config.Project) *cobra.Command {
+	o := NewGraphFlags(project)
+
+	cmd := &cobra.Command{
+		Use:     "graph",
+		Short:   "Show dependency graph of apps and terraform stacks",
+		Long:    graphLongDesc,
+		Example: graphExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			err := o.Complete(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Format, "format", graphFormatDot, "output format: dot, mermaid or json")
+
+	return cmd
+}
+
+func (o *GraphOptions) Complete(cmd *cobra.Command) error {
+	if err := requirements.CheckRequirements(requirements.WithIzeStructure(), requirements.WithConfigFile()); err != nil {
+		return err
+	}
+
+	// the graph is shown for configs with invalid depends_on too, it helps to fix them
+	if err := o.Config.GetRawConfig(); err != nil {
+		return fmt.Errorf("can't load config: %w", err)
+	}
+
+	o.out = cmd.OutOrStdout()
+
+	return nil
+}
+
+func (o *GraphOptions) Validate() error {
+	switch o.Format {
+	case graphFormatDot, graphFormatMermaid, graphFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("can't validate options: unknown format %s (supported: dot, mermaid, json)", o.Format)
+	}
+}
+
+func (o *GraphOptions) Run() error {
+	g := buildProjectGraph(o.Config)
+
+	var err error
+	switch o.Format {
+	case graphFormatMermaid:
+		err = g.mermaid(o.out)
+	case graphFormatJSON:
+		enc := json.NewEncoder(o.out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(g)
+	default:
+		err = g.dot(o.out)
+	}
+	if err != nil {
+		return fmt.Errorf("can't render graph: %w", err)
+	}
+
+	// Warnings go to stderr, so the graph can be piped
+	warning := pterm.Warning.WithWriter(os.Stderr)
+	for _, c := range g.Cycles {
+		warning.Printfln("cycle found: %s", strings.Join(c, ", "))
+	}
+	for _, e := range g.Edges {
+		if e.Missing {
+			warning.Printfln("%s depends on %s that is not found", e.From, e.To)
+		}
+	}
+
+	return nil
+}
+
+// buildProjectGraph returns apps and terraform stacks of the project with their dependencies
+func buildProjectGraph(project *config.Project) *projectGraph {
+	nodes := map[string]*graphNode{}
+
+	for name, tf := range project.Terraform {
+		nodes[name] = &graphNode{Name: name, Type: "terraform", DependsOn: tf.DependsOn}
+	}
+	for name, app := range project.Ecs {
+		nodes[name] = &graphNode{Name: name, Type: "ecs", Icon: app.Icon, DependsOn: app.DependsOn}
+	}
+	for name, app := range project.K8s {
+		nodes[name] = &graphNode{Name: name, Type: "k8s", Icon: app.Icon, DependsOn: app.DependsOn}
+	}
+	for name, app := range project.Serverless {
+		nodes[name] = &graphNode{Name: name, Type: "serverless", Icon: app.Icon, DependsOn: app.DependsOn}
+	}
+	for name, app := range project.Alias {
+		nodes[name] = &graphNode{Name: name, Type: "alias", Icon: app.Icon, DependsOn: app.DependsOn}
+	}
+
+	// dependencies the apps are deployed by, infra is deployed before everything else
+	implicit := map[string][]string{}
+	for name, v := range project.GetStatesAndApps() {
+		deps, _ := (*v).(map[string]interface{})["depends_on"].([]string)
+
+		for _, d := range deps {
+			if !slices.Contains(nodes[name].DependsOn, d) {
+				implicit[name] = append(implicit[name], d)
+			}
+		}
+
+		if _, ok := nodes["infra"]; ok && len(deps) == 0 {
+			implicit[name] = append(implicit[name], "infra")
+		}
+
+		sort.Strings(implicit[name])
+	}
+
+	deps := map[string]*interface{}{}
+	for name, n := range nodes {
+		var v interface{} = map[string]interface{}{
+			"depends_on": append(append([]string{}, n.DependsOn...), implicit[name]...),
+		}
+		deps[name] = &v
+	}
+
+	mg := manager.NewGraph(deps, manager.AppStopped)
+	cycles := mg.Cycles()
+
+	cycle := map[string]int{}
+	for i, c := range cycles {
+		for _, name := range c {
+			cycle[name] = i + 1
+		}
+	}
+
+	g := &projectGraph{
+		Cycles: cycles,
+	}
+
+	for _, name := range graphNames(deps) {
+		n := nodes[name]
+		n.InCycle = cycle[name] != 0
+		g.Nodes = append(g.Nodes, *n)
+
+		for _, d := range n.DependsOn {
+			missing := slices.Contains(mg.Dangling[name], d)
+			g.Edges = append(g.Edges, graphEdge{
+				From:    name,
+				To:      d,
+				Cycle:   !missing && cycle[name] != 0 && cycle[name] == cycle[d],
+				Missing: missing,
+			})
+		}
+
+		for _, d := range implicit[name] {
+			g.Edges = append(g.Edges, graphEdge{
+				From:     name,
+				To:       d,
+				Cycle:    cycle[name] != 0 && cycle[name] == cycle[d],
+				Implicit: true,
+			})
+		}
+	}
+
+	if g.Cycles == nil {
+		g.Cycles = [][]string{}
+	}
+
+	return g
+}
+
+func graphLabel(n graphNode) string {
+	if len(n.Icon) != 0 {
+		return fmt.Sprintf("%s %s (%s)", n.Icon, n.Name, n.Type)
+	}
+
+	return fmt.Sprintf("%s (%s)", n.Name, n.Type)
+}
+
+func (g *projectGraph) dot(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("digraph ize {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for _, n := range g.Nodes {
+		attrs := fmt.Sprintf("label=%q", graphLabel(n))
+		if n.Type == "terraform" {
+			attrs += ", shape=cylinder"
+		}
+		if n.InCycle {
+			attrs += ", color=red"
+		}
+		fmt.Fprintf(&b, "  %q [%s];\n", n.Name, attrs)
+	}
+
+	missing := map[string]bool{}
+	for _, e := range g.Edges {
+		switch {
+		case e.Missing:
+			if !missing[e.To] {
+				missing[e.To] = true
+				fmt.Fprintf(&b, "  %q [label=%q, style=dashed, color=red];\n", e.To, e.To+" (not found)")
+			}
+			fmt.Fprintf(&b, "  %q -> %q [style=dashed, color=red];\n", e.From, e.To)
+		case e.Cycle && e.Implicit:
+			fmt.Fprintf(&b, "  %q -> %q [style=dotted, color=red, penwidth=2];\n", e.From, e.To)
+		case e.Cycle:
+			fmt.Fprintf(&b, "  %q -> %q [color=red, penwidth=2];\n", e.From, e.To)
+		case e.Implicit:
+			fmt.Fprintf(&b, "  %q -> %q [style=dotted, color=gray];\n", e.From, e.To)
+		default:
+			fmt.Fprintf(&b, "  %q -> %q;\n", e.From, e.To)
+		}
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+var mermaidIDRe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func mermaidID(name string) string {
+	return mermaidIDRe.ReplaceAllString(name, "_")
+}
+
+func (g *projectGraph) mermaid(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("graph LR\n")
+
+	var inCycle []string
+	for _, n := range g.Nodes {
+		if n.Type == "terraform" {
+			fmt.Fprintf(&b, "  %s[(%q)]\n", mermaidID(n.Name), graphLabel(n))
+		} else {
+			fmt.Fprintf(&b, "  %s[%q]\n", mermaidID(n.Name), graphLabel(n))
+		}
+
+		if n.InCycle {
+			inCycle = append(inCycle, mermaidID(n.Name))
+		}
+	}
+
+	var missing []string
+	seen := map[string]bool{}
+	for _, e := range g.Edges {
+		switch {
+		case e.Missing:
+			id := "missing_" + mermaidID(e.To)
+			if !seen[id] {
+				seen[id] = true
+				missing = append(missing, id)
+				fmt.Fprintf(&b, "  %s[%q]\n", id, e.To+" (not found)")
+			}
+			fmt.Fprintf(&b, "  %s -.->|missing| %s\n", mermaidID(e.From), id)
+		case e.Cycle && e.Implicit:
+			fmt.Fprintf(&b, "  %s -.->|cycle| %s\n", mermaidID(e.From), mermaidID(e.To))
+		case e.Cycle:
+			fmt.Fprintf(&b, "  %s -->|cycle| %s\n", mermaidID(e.From), mermaidID(e.To))
+		case e.Implicit:
+			fmt.Fprintf(&b, "  %s -.-> %s\n", mermaidID(e.From), mermaidID(e.To))
+		default:
+			fmt.Fprintf(&b, "  %s --> %s\n", mermaidID(e.From), mermaidID(e.To))
+		}
+	}
+
+	if len(inCycle) != 0 {
+		b.WriteString("  classDef cycle stroke:#f00,stroke-width:2px\n")
+		fmt.Fprintf(&b, "  class %s cycle\n", strings.Join(inCycle, ","))
+	}
+
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		b.WriteString("  classDef missing stroke:#f00,stroke-dasharray:5 5\n")
+		fmt.Fprintf(&b, "  class %s missing\n", strings.Join(missing, ","))
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
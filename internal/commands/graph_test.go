@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hazelops/ize/internal/config"
+)
+
+func TestGraphOptions_Run(t *testing.T) {
+	cfg := &config.Project{
+		Terraform: map[string]*config.Terraform{
+			"infra":    {},
+			"vpc":      {},
+			"database": {DependsOn: []string{"wisp"}},
+		},
+		Ecs: map[string]*config.Ecs{
+			"goblin":  {Icon: "👺", DependsOn: []string{"troll"}},
+			"squibby": {DependsOn: []string{"goblin", "missing"}},
+			"troll":   {DependsOn: []string{"goblin"}},
+		},
+		Serverless: map[string]*config.Serverless{
+			"wisp": {},
+		},
+	}
+
+	g := buildProjectGraph(cfg)
+
+	if want := [][]string{{"database", "wisp"}, {"goblin", "troll"}}; !reflect.DeepEqual(g.Cycles, want) {
+		t.Errorf("cycles = %v, want %v", g.Cycles, want)
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{
+			format: graphFormatDot,
+			want: []string{
+				`"goblin" [label="👺 goblin (ecs)", color=red];`,
+				`"vpc" [label="vpc (terraform)", shape=cylinder];`,
+				`"goblin" -> "troll" [color=red, penwidth=2];`,
+				`"squibby" -> "goblin";`,
+				`"squibby" -> "missing" [style=dashed, color=red];`,
+				`"squibby" -> "vpc" [style=dotted, color=gray];`,
+				`"vpc" -> "infra" [style=dotted, color=gray];`,
+				`"wisp" -> "database" [style=dotted, color=red, penwidth=2];`,
+			},
+		},
+		{
+			format: graphFormatMermaid,
+			want: []string{
+				`wisp["wisp (serverless)"]`,
+				`goblin -->|cycle| troll`,
+				`squibby -.->|missing| missing_missing`,
+				`troll -.-> vpc`,
+				`class database,goblin,troll,wisp cycle`,
+			},
+		},
+		{
+			format: graphFormatJSON,
+			want: []string{
+				`"type": "ecs"`,
+				`"icon": "👺"`,
+				`"missing": true`,
+				`"implicit": true`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			o := &GraphOptions{Config: cfg, Format: tt.format, out: out}
+
+			if err := o.Run(); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			for _, w := range tt.want {
+				if !strings.Contains(out.String(), w) {
+					t.Errorf("output doesn't contain %s:\n%s", w, out.String())
+				}
+			}
+		})
+	}
+}
+
+func Test_isGraphCmd(t *testing.T) {
+	root := newRootCmd(new(config.Project))
+
+	tests := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{name: "graph", args: []string{"graph"}, want: true},
+		{name: "graph with flags", args: []string{"-e", "dev", "graph", "--format", "dot"}, want: true},
+		{name: "app named graph", args: []string{"deploy", "graph"}},
+		{name: "other command", args: []string{"up"}},
+		{name: "no command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isGraphCmd(root, tt.args); got != tt.want {
+				t.Errorf("isGraphCmd(%v) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/hazelops/ize/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+type HistoryOptions struct {
+	Config  *config.Project
+	AppName string
+	Limit   int
+}
+
+var historyLongDesc = templates.LongDesc(`
+	Show active task definition revisions of the ECS app with image tag, registration time and deployer.
+	The number of revisions kept after a deployment is set by keep_revisions (default 5).
+`)
+
+var historyExample = templates.Examples(`
+	# Show revisions of the app
+	ize history <app name>
+
+	# Show 20 latest revisions of the app
+	ize history <app name> --limit 20
+`)
+
+func NewHistoryFlags(project *config.Project) *HistoryOptions {
+	return &HistoryOptions{
+		Config: project,
+	}
+}
+
+func NewCmdHistory(project *config.Project) *cobra.Command {
+	o := NewHistoryFlags(project)
+
+	cmd := &cobra.Command{
+		Use:               "history <app name>",
+		Short:             "Show deployment history of the ECS app",
+		Long:              historyLongDesc,
+		Example:           historyExample,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: config.GetApps,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			err := o.Complete(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&o.Limit, "limit", 0, "number of the latest revisions shown (default keep_revisions)")
+
+	return cmd
+}
+
+func (o *HistoryOptions) Complete(cmd *cobra.Command) error {
+	if err := requirements.CheckRequirements(requirements.WithIzeStructure(), requirements.WithConfigFile()); err != nil {
+		return err
+	}
+
+	o.AppName = cmd.Flags().Args()[0]
+
+	return nil
+}
+
+func (o *HistoryOptions) Validate() error {
+	if o.Limit < 0 {
+		return fmt.Errorf("can't validate options: --limit must not be negative")
+	}
+
+	return validateEcsApp(o.Config, o.AppName)
+}
+
+func (o *HistoryOptions) Run() error {
+	ui := terminal.ConsoleUI(aws.BackgroundContext(), o.Config.PlainText)
+
+	m := &ecs.Manager{
+		Project: o.Config,
+		App:     ecsApp(o.Config, o.AppName),
+	}
+
+	revisions, err := m.History(o.Limit)
+	if err != nil {
+		return fmt.Errorf("can't get history of %s: %w", o.AppName, err)
+	}
+
+	t := terminal.NewTable("Revision", "Tag", "Registered", "Deployer", "")
+
+	for _, r := range revisions {
+		current, color := "", ""
+		if r.Current {
+			current, color = "running", terminal.Green
+		}
+
+		t.Rich([]string{
+			strconv.FormatInt(r.Revision, 10),
+			r.Tag(),
+			r.RegisteredAt.Local().Format(time.RFC822),
+			r.RegisteredBy,
+			current,
+		}, []string{color, color, color, color, color})
+	}
+
+	ui.Output("%s-%s revisions:", o.Config.Env, o.AppName, terminal.WithHeaderStyle())
+	ui.Table(t)
+
+	return nil
+}
+
+// validateEcsApp checks that env, namespace and the name of an ECS app are set
+func validateEcsApp(project *config.Project, name string) error {
+	if len(project.Env) == 0 {
+		return fmt.Errorf("can't validate options: env must be specified")
+	}
+
+	if len(project.Namespace) == 0 {
+		return fmt.Errorf("can't validate options: namespace must be specified")
+	}
+
+	if len(name) == 0 {
+		return fmt.Errorf("can't validate options: app name must be specified")
+	}
+
+	if project.Serverless[name] != nil || project.K8s[name] != nil || project.Alias[name] != nil {
+		return fmt.Errorf("can't validate options: %s is not an ECS app", name)
+	}
+
+	return nil
+}
+
+// ecsApp returns the ECS app config by name. Apps missing in the config get default settings
+func ecsApp(project *config.Project, name string) *config.Ecs {
+	app, ok := project.Ecs[name]
+	if !ok {
+		app = &config.Ecs{}
+	}
+	app.Name = name
+
+	return app
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -80,13 +81,17 @@ func newRootCmd(project *config.Project) *cobra.Command {
 		NewCmdSecrets(project),
 		NewCmdInit(),
 		NewCmdTunnel(project),
+		NewCmdPortForward(project),
 		NewCmdExec(project),
 		NewCmdStart(project),
 		NewCmdConfig(),
 		NewCmdLogs(project),
 		NewDebugCmd(project),
 		NewCmdGen(project),
+		NewCmdGraph(project),
+		NewCmdHistory(project),
 		NewCmdPush(project),
+		NewCmdRollback(project),
 		NewCmdUp(project),
 		NewCmdNvm(project),
 		NewValidateCmd(),
@@ -108,17 +113,37 @@ func Execute() {
 
 		config.InitConfig()
 
-		getConfig(cfg)
+		getConfig(cfg, cmd)
 	})
 
 	if err := cmd.Execute(); err != nil {
 		fmt.Println()
 		pterm.Error.Println(err)
+
+		var exitErr *ExitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+
 		os.Exit(1)
 	}
 }
 
-func getConfig(cfg *config.Project) {
+// ExitCodeError makes ize exit with the code, e.g. the exit code of a container
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
+func getConfig(cfg *config.Project, root *cobra.Command) {
 	if slices.Contains(os.Args, "terraform") || 
 	slices.Contains(os.Args, "nvm") ||
 		!(slices.Contains(os.Args, "aws-profile") ||
@@ -127,6 +152,7 @@ func getConfig(cfg *config.Project) {
 			slices.Contains(os.Args, "version") ||
 			slices.Contains(os.Args, "init") ||
 			slices.Contains(os.Args, "validate") ||
+			isGraphCmd(root, os.Args[1:]) ||
 			slices.Contains(os.Args, "config")) {
 		err := cfg.GetConfig()
 		if err != nil {
@@ -137,6 +163,12 @@ func getConfig(cfg *config.Project) {
 	}
 }
 
+// isGraphCmd reports whether args run ize graph, "graph" may be also an app name in args of other commands
+func isGraphCmd(root *cobra.Command, args []string) bool {
+	c, _, err := root.Find(args)
+	return err == nil && c.Parent() == root && c.Name() == "graph"
+}
+
 func init() {
 	initLogger()
 	customizeDefaultPtermPrefix()
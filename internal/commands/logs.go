@@ -1,31 +1,65 @@
 package commands
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
-	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/pterm/pterm"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+const (
+	logsFormatText = "text"
+	logsFormatJSON = "json"
+)
+
 type LogsOptions struct {
 	Config     *config.Project
 	AppName    string
 	EcsCluster string
 	Task       string
+	Container  string
+	Filter     string
+	Since      string
+	Until      string
+	NoFollow   bool
+	Format     string
+	since      time.Time
+	until      time.Time
+	out        io.Writer
 }
 
+var logsExample = templates.Examples(`
+	# Follow logs of all tasks and containers of the app
+	ize logs goblin
+
+	# Show errors of the app container for the last hour and exit
+	ize logs goblin --container goblin --filter ERROR --since 1h --no-follow
+
+	# Show logs of the time range
+	ize logs goblin --since 2022-08-01T10:00:00Z --until 2022-08-01T11:00:00Z
+
+	# Pipe logs as JSON lines
+	ize logs goblin --format json | jq .message
+`)
+
+// logPrefixColors are colors of the task prefixes, tasks get them in the order of appearance
+var logPrefixColors = []pterm.Color{pterm.FgCyan, pterm.FgMagenta, pterm.FgYellow, pterm.FgGreen, pterm.FgBlue, pterm.FgLightRed}
+
 func NewLogsFlags(project *config.Project) *LogsOptions {
 	return &LogsOptions{
 		Config: project,
+		out:    os.Stdout,
 	}
 }
 
@@ -34,7 +68,9 @@ func NewCmdLogs(project *config.Project) *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:               "logs [app-name]",
-		Short:             "Stream logs of container in the ECS",
+		Example:           logsExample,
+		Short:             "Stream logs of containers in the ECS",
+		Long:              "Stream logs of all tasks and containers of the ECS app.\nIt uses app name as an argument.",
 		Args:              cobra.MinimumNArgs(1),
 		ValidArgsFunction: config.GetApps,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -49,7 +85,7 @@ func NewCmdLogs(project *config.Project) *cobra.Command {
 				return err
 			}
 
-			err = o.Run()
+			err = o.Run(cmd.Context())
 			if err != nil {
 				return err
 			}
@@ -59,17 +95,37 @@ func NewCmdLogs(project *config.Project) *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&o.EcsCluster, "ecs-cluster", "", "set ECS cluster name")
-	cmd.Flags().StringVar(&o.Task, "task", "", "set ECS task id")
+	_ = cmd.Flags().MarkDeprecated("ecs-cluster", "logs are read from the log group of the app")
+	cmd.Flags().StringVar(&o.Task, "task", "", "show logs of the ECS task id only")
+	cmd.Flags().StringVar(&o.Container, "container", "", "show logs of the container only")
+	cmd.Flags().StringVar(&o.Filter, "filter", "", "show log events matching CloudWatch Logs filter pattern")
+	cmd.Flags().StringVar(&o.Since, "since", "10m", "show logs since duration (e.g. 30m, 2h) or RFC3339 timestamp")
+	cmd.Flags().StringVar(&o.Until, "until", "", "show logs until duration (e.g. 5m) or RFC3339 timestamp, disables following")
+	cmd.Flags().BoolVar(&o.NoFollow, "no-follow", false, "exit after showing existing logs")
+	cmd.Flags().StringVar(&o.Format, "format", logsFormatText, "output format: text or json")
+
+	cmd.AddCommand(NewCmdLogsQuery(project))
 
 	return cmd
 }
 
 func (o *LogsOptions) Complete(cmd *cobra.Command) error {
-	if o.EcsCluster == "" {
-		o.EcsCluster = fmt.Sprintf("%s-%s", o.Config.Env, o.Config.Namespace)
+	o.AppName = cmd.Flags().Args()[0]
+
+	var err error
+	now := time.Now()
+
+	o.since, err = parseLogsTime(o.Since, now)
+	if err != nil {
+		return fmt.Errorf("can't complete: invalid --since: %w", err)
 	}
 
-	o.AppName = cmd.Flags().Args()[0]
+	if len(o.Until) != 0 {
+		o.until, err = parseLogsTime(o.Until, now)
+		if err != nil {
+			return fmt.Errorf("can't complete: invalid --until: %w", err)
+		}
+	}
 
 	return nil
 }
@@ -78,72 +134,83 @@ func (o *LogsOptions) Validate() error {
 	if len(o.AppName) == 0 {
 		return fmt.Errorf("can't validate: app name must be specified\n")
 	}
-	return nil
-}
 
-func (o *LogsOptions) Run() error {
-	logGroup := fmt.Sprintf("%s-%s", o.Config.Env, o.AppName)
-
-	taskID := o.Task
-	if len(taskID) == 0 {
-		lto, err := o.Config.AWSClient.ECSClient.ListTasks(&ecs.ListTasksInput{
-			Cluster:       &o.EcsCluster,
-			DesiredStatus: aws.String("RUNNING"),
-			ServiceName:   &logGroup,
-			MaxResults:    aws.Int64(1),
-		})
+	if o.Format != logsFormatText && o.Format != logsFormatJSON {
+		return fmt.Errorf("can't validate: unknown format %s, must be %s or %s", o.Format, logsFormatText, logsFormatJSON)
+	}
 
-		logrus.Infof("log group: %s, cluster name: %s", logGroup, o.EcsCluster)
+	if !o.until.IsZero() && !o.until.After(o.since) {
+		return fmt.Errorf("can't validate: --until must be later than --since")
+	}
 
-		if err != nil {
-			return fmt.Errorf("can't run logs: %w", err)
-		}
+	return nil
+}
 
-		taskID = *lto.TaskArns[0]
-		taskID = taskID[strings.LastIndex(taskID, "/")+1:]
+func (o *LogsOptions) Run(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
-	var token *string
-	logStreamName := fmt.Sprintf("main/%s/%s", o.AppName, taskID)
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	GetLogs(o.Config.AWSClient.CloudWatchLogsClient, logGroup, logStreamName, token)
+	logGroup := fmt.Sprintf("%s-%s", o.Config.Env, o.AppName)
 
-	return nil
-}
+	logrus.Debugf("log group: %s, since: %s, until: %s", logGroup, o.since, o.until)
+
+	colors := map[string]pterm.Color{}
+	enc := json.NewEncoder(o.out)
+
+	return ecs.FilterLogs(ctx, o.Config.AWSClient.CloudWatchLogsClient, ecs.LogFilter{
+		LogGroup:  logGroup,
+		Container: o.Container,
+		Task:      o.Task,
+		Pattern:   o.Filter,
+		Since:     o.since,
+		Until:     o.until,
+		Follow:    !o.NoFollow,
+	}, func(e ecs.LogEvent) error {
+		if o.Format == logsFormatJSON {
+			return enc.Encode(e)
+		}
 
-func GetLogs(clw cloudwatchlogsiface.CloudWatchLogsAPI, logGroup string, logStreamName string, token *string) {
-	for {
-		logEvents, err := clw.GetLogEvents(&cloudwatchlogs.GetLogEventsInput{
-			LogGroupName:  &logGroup,
-			LogStreamName: &logStreamName,
-			NextToken:     token,
-		})
-		if err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "%s: %v\n", logStreamName, err)
-			continue
+		color, ok := colors[e.Task]
+		if !ok {
+			color = logPrefixColors[len(colors)%len(logPrefixColors)]
+			colors[e.Task] = color
 		}
 
-		for _, e := range logEvents.Events {
-			_, m := formatMessage(e)
-			fmt.Println(m)
+		task := e.Task
+		if len(task) > 8 {
+			task = task[:8]
 		}
 
-		token = logEvents.NextForwardToken
+		_, err := fmt.Fprintf(o.out, "%s %s %s\n", e.Timestamp.Format("2006-01-02 15:04:05"), color.Sprintf("[%s %s]", task, e.Container), formatMessage(e.Message))
+		return err
+	})
+}
 
-		time.Sleep(time.Second * 5)
+// parseLogsTime parses RFC3339 timestamp or duration before now
+func parseLogsTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is neither a duration nor an RFC3339 timestamp", s)
 	}
-}
 
-func formatMessage(e *cloudwatchlogs.OutputLogEvent) (t time.Time, m string) {
-	m = *e.Message
+	return now.Add(-d), nil
+}
 
+// formatMessage strips syslog timestamp the message may start with
+func formatMessage(m string) string {
 	if len(m) > 16 {
 		if _, err := time.Parse("Jan  2 15:04:05 ", m[:16]); err == nil {
 			m = m[16:]
 		}
 	}
 
-	t = time.Unix(0, *e.Timestamp*1000000)
-	m = t.Format("2006-01-02 15:04:05 ") + m
-	return
+	return m
 }
@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/hazelops/ize/pkg/terminal"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	queryFormatTable = "table"
+	queryFormatCSV   = "csv"
+	queryFormatJSON  = "json"
+)
+
+type LogsQueryOptions struct {
+	Config  *config.Project
+	AppName string
+	Query   string
+	Since   string
+	Until   string
+	Limit   int64
+	Format  string
+	since   time.Time
+	until   time.Time
+	out     io.Writer
+}
+
+var logsQueryLongDesc = templates.LongDesc(`
+	Run CloudWatch Logs Insights query over the log group of the ECS app and show the results.
+	The query is either a query string or the name of a query saved in the queries section of the app.
+`)
+
+var logsQueryExample = templates.Examples(`
+	# Show the latest errors of the last hour
+	ize logs query goblin 'fields @timestamp, @message | filter @message like /ERROR/ | sort @timestamp desc'
+
+	# Run the query saved in ize.toml as [ecs.goblin.queries] errors = "..."
+	ize logs query goblin errors --since 24h
+
+	# Export results as CSV
+	ize logs query goblin errors --format csv > errors.csv
+`)
+
+func NewLogsQueryFlags(project *config.Project) *LogsQueryOptions {
+	return &LogsQueryOptions{
+		Config: project,
+		out:    os.Stdout,
+	}
+}
+
+func NewCmdLogsQuery(project *config.Project) *cobra.Command {
+	o := NewLogsQueryFlags(project)
+
+	cmd := &cobra.Command{
+		Use:               "query [flags] <app name> <query or saved query name>",
+		Example:           logsQueryExample,
+		Short:             "Run CloudWatch Logs Insights query",
+		Long:              logsQueryLongDesc,
+		Args:              cobra.RangeArgs(1, 2),
+		ValidArgsFunction: config.GetApps,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			err := o.Complete(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Since, "since", "1h", "query logs since duration (e.g. 30m, 2h) or RFC3339 timestamp")
+	cmd.Flags().StringVar(&o.Until, "until", "", "query logs until duration (e.g. 5m) or RFC3339 timestamp, now by default")
+	cmd.Flags().Int64Var(&o.Limit, "limit", 0, "maximum number of results (up to 10000)")
+	cmd.Flags().StringVar(&o.Format, "format", queryFormatTable, "output format: table, csv or json")
+
+	return cmd
+}
+
+func (o *LogsQueryOptions) Complete(cmd *cobra.Command) error {
+	args := cmd.Flags().Args()
+	o.AppName = args[0]
+
+	saved := ecsApp(o.Config, o.AppName).Queries
+
+	if len(args) == 1 {
+		if len(saved) == 0 {
+			return fmt.Errorf("can't complete: query must be specified")
+		}
+
+		return fmt.Errorf("can't complete: query must be specified, saved queries of %s: %s", o.AppName, strings.Join(savedQueryNames(saved), ", "))
+	}
+
+	o.Query = args[1]
+	if q, ok := saved[o.Query]; ok {
+		o.Query = q
+	}
+
+	var err error
+	now := time.Now()
+
+	o.since, err = parseLogsTime(o.Since, now)
+	if err != nil {
+		return fmt.Errorf("can't complete: invalid --since: %w", err)
+	}
+
+	o.until = now
+	if len(o.Until) != 0 {
+		o.until, err = parseLogsTime(o.Until, now)
+		if err != nil {
+			return fmt.Errorf("can't complete: invalid --until: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (o *LogsQueryOptions) Validate() error {
+	if err := validateEcsApp(o.Config, o.AppName); err != nil {
+		return err
+	}
+
+	if len(strings.TrimSpace(o.Query)) == 0 {
+		return fmt.Errorf("can't validate: query must be specified")
+	}
+
+	switch o.Format {
+	case queryFormatTable, queryFormatCSV, queryFormatJSON:
+	default:
+		return fmt.Errorf("can't validate: unknown format %s, must be one of %s, %s or %s", o.Format, queryFormatTable, queryFormatCSV, queryFormatJSON)
+	}
+
+	if !o.until.After(o.since) {
+		return fmt.Errorf("can't validate: --until must be later than --since")
+	}
+
+	if o.Limit < 0 || o.Limit > 10000 {
+		return fmt.Errorf("can't validate: --limit must be between 0 and 10000")
+	}
+
+	return nil
+}
+
+func (o *LogsQueryOptions) Run(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logGroup := fmt.Sprintf("%s-%s", o.Config.Env, o.AppName)
+
+	logrus.Debugf("log group: %s, query: %s, since: %s, until: %s", logGroup, o.Query, o.since, o.until)
+
+	res, err := ecs.RunQuery(ctx, o.Config.AWSClient.CloudWatchLogsClient, ecs.Query{
+		LogGroups: []string{logGroup},
+		Query:     o.Query,
+		Since:     o.since,
+		Until:     o.until,
+		Limit:     o.Limit,
+	})
+	if err != nil {
+		return fmt.Errorf("can't run query over %s: %w", logGroup, err)
+	}
+
+	switch o.Format {
+	case queryFormatCSV:
+		return writeQueryCSV(o.out, res)
+	case queryFormatJSON:
+		enc := json.NewEncoder(o.out)
+		enc.SetIndent("", "  ")
+		if res.Rows == nil {
+			res.Rows = []map[string]string{}
+		}
+		return enc.Encode(res.Rows)
+	}
+
+	ui := terminal.ConsoleUI(aws.BackgroundContext(), o.Config.PlainText)
+
+	if len(res.Rows) == 0 {
+		ui.Output("No results (%.0f records scanned)", res.RecordsScanned, terminal.WithWarningStyle())
+		return nil
+	}
+
+	t := terminal.NewTable(res.Fields...)
+	for _, row := range res.Rows {
+		var cols []string
+		for _, f := range res.Fields {
+			cols = append(cols, row[f])
+		}
+		t.Rich(cols, nil)
+	}
+
+	ui.Table(t)
+	ui.Output("%d results, %.0f records matched, %.0f records scanned", len(res.Rows), res.RecordsMatched, res.RecordsScanned, terminal.WithInfoStyle())
+
+	return nil
+}
+
+func writeQueryCSV(out io.Writer, res *ecs.QueryResult) error {
+	w := csv.NewWriter(out)
+
+	if err := w.Write(res.Fields); err != nil {
+		return err
+	}
+
+	for _, row := range res.Rows {
+		var cols []string
+		for _, f := range res.Fields {
+			cols = append(cols, row[f])
+		}
+
+		if err := w.Write(cols); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
+
+// savedQueryNames returns sorted names of the saved queries
+func savedQueryNames(queries map[string]string) []string {
+	var names []string
+	for name := range queries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
-	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/golang/mock/gomock"
 	_ "github.com/golang/mock/mockgen/model"
 	"github.com/hazelops/ize/internal/config"
@@ -28,24 +27,23 @@ import (
 var logsToml string
 
 func TestLogs(t *testing.T) {
-	mockECS := func(m *mocks.MockECSAPI) {
-		m.EXPECT().ListTasks(gomock.Any()).Return(&ecs.ListTasksOutput{
-			NextToken: nil,
-			TaskArns:  []*string{aws.String("test")},
-		}, nil).Times(1)
-	}
+	mockECS := func(m *mocks.MockECSAPI) {}
 
 	mockCWL := func(m *mocks.MockCloudWatchLogsAPI) {
-		m.EXPECT().GetLogEvents(gomock.Any()).Return(&cloudwatchlogs.GetLogEventsOutput{
-			Events: []*cloudwatchlogs.OutputLogEvent{
-				{
-					IngestionTime: nil,
-					Message:       aws.String("test"),
-					Timestamp:     aws.Int64(1),
-				},
-			},
-			NextForwardToken: nil,
-		}, nil).AnyTimes()
+		m.EXPECT().FilterLogEventsPagesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, input *cloudwatchlogs.FilterLogEventsInput, fn func(*cloudwatchlogs.FilterLogEventsOutput, bool) bool, opts ...interface{}) error {
+				fn(&cloudwatchlogs.FilterLogEventsOutput{
+					Events: []*cloudwatchlogs.FilteredLogEvent{
+						{
+							EventId:       aws.String("1"),
+							LogStreamName: aws.String("main/goblin/test"),
+							Message:       aws.String("test"),
+							Timestamp:     aws.Int64(1),
+						},
+					},
+				}, true)
+				return nil
+			}).AnyTimes()
 	}
 
 	tests := []struct {
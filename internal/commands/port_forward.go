@@ -0,0 +1,761 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/pkg/ssmsession"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/hazelops/ize/pkg/terminal"
+	"github.com/pterm/pterm"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const portForwardDocument = "AWS-StartPortForwardingSessionToRemoteHost"
+
+// ssmPluginProcess is the name of processes serving forwards. Saved PIDs of other processes are never signaled,
+// since they may be reused after the plugin has exited, e.g. after a reboot.
+var ssmPluginProcess = "session-manager-plugin"
+
+// portForwardTimeout is how long port forwarding waits for the local port to accept connections
+var portForwardTimeout = 30 * time.Second
+
+type PortForwardOptions struct {
+	Config     *config.Project
+	AppName    string
+	EcsCluster string
+	Task       string
+	TaskIndex  int
+	Container  string
+	Forwards   []portForward
+}
+
+// portForward forwards the local port to the remote host and port through an SSM session of an ECS task
+type portForward struct {
+	LocalPort  int    `json:"local_port"`
+	Host       string `json:"host"`
+	RemotePort int    `json:"remote_port"`
+	PID        int    `json:"pid,omitempty"`
+	SessionID  string `json:"session_id,omitempty"`
+	// freeLocalPort is set if the local port has been selected by ize
+	freeLocalPort bool
+}
+
+func (f portForward) String() string {
+	return fmt.Sprintf("%s:%d ➡ localhost:%d", f.Host, f.RemotePort, f.LocalPort)
+}
+
+// up reports whether the plugin process serving the forward is running and the local port accepts connections
+func (f portForward) up() bool {
+	if !pluginAlive(f.PID) {
+		return false
+	}
+
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", f.LocalPort), time.Second)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+
+	return true
+}
+
+// portForwardState is port forwarding of an app saved in <env dir>/port-forward-<app>.json while it's up
+type portForwardState struct {
+	App       string        `json:"app"`
+	Task      string        `json:"task"`
+	Container string        `json:"container"`
+	StartedAt time.Time     `json:"started_at"`
+	Forwards  []portForward `json:"forwards"`
+}
+
+var portForwardLongDesc = templates.LongDesc(`
+	Forward local ports to hosts reachable from a running task of the ECS app through AWS SSM, no bastion host is needed.
+	Forwards are set as [local port:]remote host:remote port, a free local port is selected if it's omitted.
+	Port forwarding runs in the background until ize port-forward down.
+`)
+
+var portForwardExample = templates.Examples(`
+	# Forward local port 5432 to the database through a task of goblin
+	ize port-forward goblin 5432:db.internal:5432
+
+	# Forward several ports through the second oldest task, free local ports are selected
+	ize port-forward up goblin db.internal:5432 redis.internal:6379 --task-index 1
+
+	# Show active port forwarding
+	ize port-forward status
+
+	# Stop port forwarding of goblin
+	ize port-forward down goblin
+`)
+
+func NewPortForwardFlags(project *config.Project) *PortForwardOptions {
+	return &PortForwardOptions{
+		Config: project,
+	}
+}
+
+func NewCmdPortForward(project *config.Project) *cobra.Command {
+	cmd := newCmdPortForwardUp(project)
+	cmd.Use = "port-forward [flags] <app name> <[local port:]host:port>..."
+	cmd.Short = "Forward local ports through ECS tasks"
+
+	cmd.AddCommand(
+		newCmdPortForwardUp(project),
+		NewCmdPortForwardDown(project),
+		NewCmdPortForwardStatus(project),
+	)
+
+	return cmd
+}
+
+func newCmdPortForwardUp(project *config.Project) *cobra.Command {
+	o := NewPortForwardFlags(project)
+
+	cmd := &cobra.Command{
+		Use:               "up [flags] <app name> <[local port:]host:port>...",
+		Example:           portForwardExample,
+		Short:             "Start port forwarding",
+		Long:              portForwardLongDesc,
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: config.GetApps,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			err := o.Complete(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.EcsCluster, "ecs-cluster", "", "set ECS cluster name")
+	cmd.Flags().StringVar(&o.Task, "task", "", "set task id")
+	cmd.Flags().IntVar(&o.TaskIndex, "task-index", -1, "select running task by index, the oldest task has index 0")
+	cmd.Flags().StringVar(&o.Container, "container", "", "set container name")
+
+	return cmd
+}
+
+func (o *PortForwardOptions) Complete(cmd *cobra.Command) error {
+	if err := requirements.CheckRequirements(requirements.WithSSMPlugin()); err != nil {
+		return err
+	}
+
+	if o.EcsCluster == "" {
+		o.EcsCluster = fmt.Sprintf("%s-%s", o.Config.Env, o.Config.Namespace)
+	}
+
+	args := cmd.Flags().Args()
+	o.AppName = args[0]
+
+	for _, spec := range args[1:] {
+		f, err := parsePortForward(spec)
+		if err != nil {
+			return fmt.Errorf("can't complete: %w", err)
+		}
+
+		o.Forwards = append(o.Forwards, f)
+	}
+
+	return nil
+}
+
+func (o *PortForwardOptions) Validate() error {
+	if err := validateEcsApp(o.Config, o.AppName); err != nil {
+		return err
+	}
+
+	ports := map[int]bool{}
+	for _, f := range o.Forwards {
+		if ports[f.LocalPort] {
+			return fmt.Errorf("can't validate: local port %d is used by several forwards", f.LocalPort)
+		}
+		ports[f.LocalPort] = true
+	}
+
+	return nil
+}
+
+func (o *PortForwardOptions) Run() error {
+	state, err := readPortForwardState(o.Config.EnvDir, o.AppName)
+	if err != nil {
+		return err
+	}
+
+	if state != nil && !state.up() {
+		stopPortForwards(o.Config, state.Forwards)
+
+		if err = removePortForwardState(o.Config.EnvDir, o.AppName); err != nil {
+			return err
+		}
+
+		state = nil
+	}
+
+	forwards := o.Forwards
+
+	if state != nil {
+		// forwards which are down are replaced by the requested ones
+		var up, down []portForward
+		for _, f := range state.Forwards {
+			if f.up() {
+				up = append(up, f)
+			} else {
+				down = append(down, f)
+			}
+		}
+
+		stopPortForwards(o.Config, down)
+		state.Forwards = up
+
+		forwards, err = missingPortForwards(state.Forwards, o.Forwards)
+		if err != nil {
+			return fmt.Errorf("can't forward ports of %s: %w", o.AppName, err)
+		}
+
+		if len(forwards) == 0 {
+			if len(down) != 0 {
+				if err = writePortForwardState(o.Config.EnvDir, state); err != nil {
+					return err
+				}
+			}
+
+			pterm.Success.Printfln("Port forwarding of %s is already up:", o.AppName)
+			printPortForwards(state.Forwards)
+			return nil
+		}
+	}
+
+	for _, f := range forwards {
+		if err := checkLocalPort(f.LocalPort); err != nil {
+			return err
+		}
+	}
+
+	target := &execTarget{
+		Cluster:   o.EcsCluster,
+		Service:   fmt.Sprintf("%s-%s", o.Config.Env, o.AppName),
+		AppName:   o.AppName,
+		Task:      o.Task,
+		TaskIndex: o.TaskIndex,
+		Container: o.Container,
+	}
+
+	// new forwards of the app go through the task of the running ones
+	if state != nil && len(o.Task) == 0 && o.TaskIndex < 0 && len(o.Container) == 0 {
+		target.Task, target.Container = state.Task, state.Container
+	}
+
+	if err := target.resolve(o.Config); err != nil {
+		return err
+	}
+
+	if state != nil && (shortTaskID(taskID(target.Task)) != shortTaskID(taskID(state.Task)) || target.Container != state.Container) {
+		return fmt.Errorf("port forwarding of %s is up through container %s of task %s, run ize port-forward down %s to change them",
+			o.AppName, state.Container, shortTaskID(taskID(state.Task)), o.AppName)
+	}
+
+	ssmTarget, err := ecs.SSMTarget(o.Config.AWSClient.ECSClient, o.EcsCluster, target.Task, target.Container)
+	if err != nil {
+		return err
+	}
+
+	logrus.Debugf("SSM target: %s", ssmTarget)
+
+	logFile, err := os.OpenFile(filepath.Join(o.Config.EnvDir, fmt.Sprintf("port-forward-%s.log", o.AppName)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("can't open port forwarding log: %w", err)
+	}
+	defer logFile.Close()
+
+	if state == nil {
+		state = &portForwardState{
+			App:       o.AppName,
+			Task:      target.Task,
+			Container: target.Container,
+			StartedAt: time.Now(),
+		}
+	}
+
+	ssmCmd := ssmsession.NewSSMPluginCommand(o.Config.AwsRegion)
+
+	s, _ := pterm.DefaultSpinner.WithRemoveWhenDone().Start("Starting port forwarding...")
+
+	var started []portForward
+
+	for _, f := range forwards {
+		f, err = o.startForward(ssmCmd, ssmTarget, f, logFile)
+		if err != nil {
+			s.Fail()
+			stopPortForwards(o.Config, append(started, f))
+			return fmt.Errorf("can't forward %s: %w", f, err)
+		}
+
+		started = append(started, f)
+	}
+
+	state.Forwards = append(state.Forwards, started...)
+
+	if err = writePortForwardState(o.Config.EnvDir, state); err != nil {
+		s.Fail()
+		stopPortForwards(o.Config, started)
+		return err
+	}
+
+	s.Success()
+
+	pterm.Success.Printfln("Port forwarding of %s is up! Forwarded ports:", o.AppName)
+	printPortForwards(state.Forwards)
+
+	return nil
+}
+
+// missingPortForwards returns the requested forwards which are not among the running ones.
+// A requested forward without a local port matches a running forward of the same host and port.
+func missingPortForwards(running, requested []portForward) ([]portForward, error) {
+	var missing []portForward
+
+	for _, f := range requested {
+		found := false
+
+		for _, r := range running {
+			if r.Host == f.Host && r.RemotePort == f.RemotePort && (f.freeLocalPort || r.LocalPort == f.LocalPort) {
+				found = true
+				break
+			}
+
+			if !f.freeLocalPort && r.LocalPort == f.LocalPort {
+				return nil, fmt.Errorf("local port %d is already forwarded to %s:%d", f.LocalPort, r.Host, r.RemotePort)
+			}
+		}
+
+		if !found {
+			missing = append(missing, f)
+		}
+	}
+
+	return missing, nil
+}
+
+// taskID returns the ID of the task ARN
+func taskID(task string) string {
+	return task[strings.LastIndex(task, "/")+1:]
+}
+
+// startForward starts the SSM session of the forward and the plugin serving it in the background
+func (o *PortForwardOptions) startForward(ssmCmd ssmsession.SSMPluginCommand, target string, f portForward, logFile *os.File) (portForward, error) {
+	input := &ssm.StartSessionInput{
+		Target:       aws.String(target),
+		DocumentName: aws.String(portForwardDocument),
+		Parameters: map[string][]*string{
+			"host":            aws.StringSlice([]string{f.Host}),
+			"portNumber":      aws.StringSlice([]string{strconv.Itoa(f.RemotePort)}),
+			"localPortNumber": aws.StringSlice([]string{strconv.Itoa(f.LocalPort)}),
+		},
+	}
+
+	out, err := o.Config.AWSClient.SSMClient.StartSession(input)
+	if err != nil {
+		return f, fmt.Errorf("can't start session: %w", err)
+	}
+
+	f.SessionID = aws.StringValue(out.SessionId)
+
+	cmd, err := ssmCmd.PortForwardingCommand(out, input)
+	if err != nil {
+		return f, err
+	}
+
+	cmd.Dir = o.Config.EnvDir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachProcess(cmd)
+
+	if err = cmd.Start(); err != nil {
+		return f, fmt.Errorf("can't start session-manager-plugin: %w", err)
+	}
+
+	f.PID = cmd.Process.Pid
+
+	if err = waitForLocalPort(cmd, f.LocalPort, portForwardTimeout); err != nil {
+		_ = terminateProcess(f.PID)
+		return f, fmt.Errorf("%w, see %s", err, logFile.Name())
+	}
+
+	return f, nil
+}
+
+// waitForLocalPort waits until the local port accepts connections or the command exits
+func waitForLocalPort(cmd *exec.Cmd, port int, timeout time.Duration) error {
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for {
+		conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", port), time.Second)
+		if err == nil {
+			_ = conn.Close()
+			return nil
+		}
+
+		select {
+		case err := <-exited:
+			return fmt.Errorf("session-manager-plugin exited: %v", err)
+		case <-ctx.Done():
+			return fmt.Errorf("local port %d doesn't accept connections after %s", port, timeout)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// stopPortForwards terminates plugin processes and SSM sessions of the forwards
+func stopPortForwards(project *config.Project, forwards []portForward) {
+	for _, f := range forwards {
+		if !pluginAlive(f.PID) {
+			logrus.Debugf("process %d is not %s, it's not terminated", f.PID, ssmPluginProcess)
+		} else if err := terminateProcess(f.PID); err != nil {
+			logrus.Debugf("can't terminate process %d: %s", f.PID, err)
+		}
+
+		if len(f.SessionID) == 0 {
+			continue
+		}
+
+		_, err := project.AWSClient.SSMClient.TerminateSession(&ssm.TerminateSessionInput{
+			SessionId: aws.String(f.SessionID),
+		})
+		if err != nil {
+			logrus.Debugf("can't terminate session %s: %s", f.SessionID, err)
+		}
+	}
+}
+
+// pluginAlive reports whether the process with the pid is a running session-manager-plugin
+func pluginAlive(pid int) bool {
+	if !processAlive(pid) {
+		return false
+	}
+
+	name, err := processName(pid)
+	if err != nil {
+		logrus.Debugf("can't get name of process %d: %s", pid, err)
+		return false
+	}
+
+	return name == ssmPluginProcess
+}
+
+// parsePortForward parses [local port:]host:port. A free local port is selected if it's omitted.
+func parsePortForward(spec string) (portForward, error) {
+	invalid := fmt.Errorf("invalid forward %s, must be [local port:]host:port", spec)
+
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return portForward{}, invalid
+	}
+
+	var f portForward
+
+	if len(parts) == 3 {
+		p, err := parsePort(parts[0])
+		if err != nil {
+			return portForward{}, invalid
+		}
+		f.LocalPort = p
+		parts = parts[1:]
+	}
+
+	f.Host = parts[0]
+	if len(f.Host) == 0 {
+		return portForward{}, invalid
+	}
+
+	p, err := parsePort(parts[1])
+	if err != nil {
+		return portForward{}, invalid
+	}
+	f.RemotePort = p
+
+	if f.LocalPort == 0 {
+		f.LocalPort, err = getFreePort()
+		if err != nil {
+			return portForward{}, fmt.Errorf("can't get free local port: %w", err)
+		}
+		f.freeLocalPort = true
+	}
+
+	return f, nil
+}
+
+func parsePort(s string) (int, error) {
+	p, err := strconv.Atoi(s)
+	if err != nil || p < 1 || p > 65535 {
+		return 0, fmt.Errorf("invalid port %s", s)
+	}
+
+	return p, nil
+}
+
+// checkLocalPort checks that the local port is free
+func checkLocalPort(port int) error {
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return fmt.Errorf("local port %d is already in use", port)
+	}
+
+	return l.Close()
+}
+
+func printPortForwards(forwards []portForward) {
+	for _, f := range forwards {
+		pterm.Println(f)
+	}
+}
+
+// up reports whether any forward of the state is up
+func (s *portForwardState) up() bool {
+	for _, f := range s.Forwards {
+		if f.up() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func portForwardStatePath(dir, app string) string {
+	return filepath.Join(dir, fmt.Sprintf("port-forward-%s.json", app))
+}
+
+// readPortForwardState returns the saved port forwarding of the app or nil
+func readPortForwardState(dir, app string) (*portForwardState, error) {
+	b, err := os.ReadFile(portForwardStatePath(dir, app))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read port forwarding state: %w", err)
+	}
+
+	state := &portForwardState{}
+	if err = json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("can't read port forwarding state: %w", err)
+	}
+
+	return state, nil
+}
+
+// readPortForwardStates returns saved port forwarding of all apps
+func readPortForwardStates(dir string) ([]*portForwardState, error) {
+	paths, err := filepath.Glob(portForwardStatePath(dir, "*"))
+	if err != nil {
+		return nil, fmt.Errorf("can't read port forwarding state: %w", err)
+	}
+
+	var states []*portForwardState
+
+	for _, p := range paths {
+		app := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(p), "port-forward-"), ".json")
+
+		state, err := readPortForwardState(dir, app)
+		if err != nil {
+			return nil, err
+		}
+
+		if state != nil {
+			states = append(states, state)
+		}
+	}
+
+	return states, nil
+}
+
+func writePortForwardState(dir string, state *portForwardState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't write port forwarding state: %w", err)
+	}
+
+	if err = os.WriteFile(portForwardStatePath(dir, state.App), b, 0644); err != nil {
+		return fmt.Errorf("can't write port forwarding state: %w", err)
+	}
+
+	return nil
+}
+
+func removePortForwardState(dir, app string) error {
+	err := os.Remove(portForwardStatePath(dir, app))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("can't remove port forwarding state: %w", err)
+	}
+
+	return nil
+}
+
+type PortForwardDownOptions struct {
+	Config  *config.Project
+	AppName string
+}
+
+func NewPortForwardDownOptions(project *config.Project) *PortForwardDownOptions {
+	return &PortForwardDownOptions{
+		Config: project,
+	}
+}
+
+func NewCmdPortForwardDown(project *config.Project) *cobra.Command {
+	o := NewPortForwardDownOptions(project)
+
+	cmd := &cobra.Command{
+		Use:               "down [app name]",
+		Short:             "Stop port forwarding",
+		Long:              "Stop port forwarding of the app or of all apps if the app isn't set",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: config.GetApps,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			if len(args) != 0 {
+				o.AppName = args[0]
+			}
+
+			if len(o.Config.Env) == 0 {
+				return fmt.Errorf("env must be specified")
+			}
+
+			return o.Run()
+		},
+	}
+
+	return cmd
+}
+
+func (o *PortForwardDownOptions) Run() error {
+	states, err := readPortForwardStates(o.Config.EnvDir)
+	if err != nil {
+		return err
+	}
+
+	down := 0
+
+	for _, state := range states {
+		if len(o.AppName) != 0 && state.App != o.AppName {
+			continue
+		}
+
+		stopPortForwards(o.Config, state.Forwards)
+
+		if err = removePortForwardState(o.Config.EnvDir, state.App); err != nil {
+			return err
+		}
+
+		pterm.Success.Printfln("Port forwarding of %s is down!", state.App)
+		down++
+	}
+
+	if down == 0 {
+		return fmt.Errorf("unable to stop port forwarding: port forwarding is not active")
+	}
+
+	return nil
+}
+
+type PortForwardStatusOptions struct {
+	Config *config.Project
+}
+
+func NewPortForwardStatusOptions(project *config.Project) *PortForwardStatusOptions {
+	return &PortForwardStatusOptions{
+		Config: project,
+	}
+}
+
+func NewCmdPortForwardStatus(project *config.Project) *cobra.Command {
+	o := NewPortForwardStatusOptions(project)
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Port forwarding status",
+		Long:  "Show port forwarding of all apps and whether their forwards are up",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			if len(o.Config.Env) == 0 {
+				return fmt.Errorf("env must be specified")
+			}
+
+			return o.Run()
+		},
+	}
+
+	return cmd
+}
+
+func (o *PortForwardStatusOptions) Run() error {
+	states, err := readPortForwardStates(o.Config.EnvDir)
+	if err != nil {
+		return err
+	}
+
+	if len(states) == 0 {
+		return fmt.Errorf("can't get port forwarding status: port forwarding is down")
+	}
+
+	t := terminal.NewTable("App", "Task", "Forward", "PID", "Status", "Started")
+
+	for _, state := range states {
+		task := taskID(state.Task)
+
+		for _, f := range state.Forwards {
+			status, color := "up", terminal.Green
+			if !f.up() {
+				status, color = "down", terminal.Red
+			}
+
+			t.Rich([]string{
+				state.App,
+				shortTaskID(task),
+				f.String(),
+				strconv.Itoa(f.PID),
+				status,
+				state.StartedAt.Local().Format(time.RFC822),
+			}, []string{"", "", "", "", color, ""})
+		}
+	}
+
+	ui := terminal.ConsoleUI(context.Background(), o.Config.PlainText)
+	ui.Table(t)
+
+	return nil
+}
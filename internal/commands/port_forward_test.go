@@ -0,0 +1,231 @@
+package commands
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/mocks"
+)
+
+func TestParsePortForward(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          string
+		want          portForward
+		wantFreeLocal bool
+		wantErr       bool
+	}{
+		{name: "local port, host and port", spec: "15432:db.internal:5432", want: portForward{LocalPort: 15432, Host: "db.internal", RemotePort: 5432}},
+		{name: "free local port", spec: "db.internal:5432", want: portForward{Host: "db.internal", RemotePort: 5432, freeLocalPort: true}, wantFreeLocal: true},
+		{name: "invalid port", spec: "db.internal:postgres", wantErr: true},
+		{name: "invalid local port", spec: "70000:db.internal:5432", wantErr: true},
+		{name: "empty host", spec: "5432::5432", wantErr: true},
+		{name: "too many parts", spec: "1:2:3:4", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePortForward(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePortForward() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if tt.wantFreeLocal {
+				if got.LocalPort == 0 {
+					t.Errorf("parsePortForward() local port is not selected")
+				}
+				got.LocalPort = 0
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePortForward() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortForwardState_up(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(name string) { ssmPluginProcess = name }(ssmPluginProcess)
+	ssmPluginProcess = "other"
+
+	state := &portForwardState{Forwards: []portForward{{LocalPort: l.Addr().(*net.TCPAddr).Port, PID: os.Getpid()}}}
+
+	if state.up() {
+		t.Errorf("up() = true, want false for process of another program")
+	}
+
+	ssmPluginProcess = filepath.Base(os.Args[0])
+
+	if !state.up() {
+		t.Errorf("up() = false, want true for listening port")
+	}
+
+	l.Close()
+
+	if state.up() {
+		t.Errorf("up() = true, want false for closed port")
+	}
+
+	state.Forwards[0].PID = 0
+	if state.up() {
+		t.Errorf("up() = true, want false without process")
+	}
+}
+
+func TestPortForwardDown(t *testing.T) {
+	dir := t.TempDir()
+
+	defer func(name string) { ssmPluginProcess = name }(ssmPluginProcess)
+	ssmPluginProcess = "sleep"
+
+	plugin := exec.Command("sleep", "60")
+	if err := plugin.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = plugin.Wait()
+		close(exited)
+	}()
+
+	err := writePortForwardState(dir, &portForwardState{
+		App:      "goblin",
+		Forwards: []portForward{{LocalPort: 5432, Host: "db.internal", RemotePort: 5432, PID: plugin.Process.Pid, SessionID: "session"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSSM := mocks.NewMockSSMAPI(ctrl)
+	mockSSM.EXPECT().TerminateSession(gomock.Any()).Return(nil, nil).Times(1)
+
+	o := NewPortForwardDownOptions(&config.Project{
+		EnvDir:    dir,
+		AWSClient: config.NewAWSClient(config.WithSSMClient(mockSSM)),
+	})
+
+	if err = o.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Errorf("plugin process is not terminated")
+	}
+
+	if state, _ := readPortForwardState(dir, "goblin"); state != nil {
+		t.Errorf("port forwarding state is not removed")
+	}
+
+	if err = o.Run(); err == nil {
+		t.Errorf("Run() error = nil, want error when port forwarding is not active")
+	}
+}
+
+func TestPortForwardDown_otherProcess(t *testing.T) {
+	dir := t.TempDir()
+
+	// the saved pid has been reused by another program
+	other := exec.Command("sleep", "60")
+	if err := other.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = other.Process.Kill()
+		_ = other.Wait()
+	}()
+
+	err := writePortForwardState(dir, &portForwardState{
+		App:      "goblin",
+		Forwards: []portForward{{LocalPort: 5432, Host: "db.internal", RemotePort: 5432, PID: other.Process.Pid, SessionID: "session"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSSM := mocks.NewMockSSMAPI(ctrl)
+	mockSSM.EXPECT().TerminateSession(gomock.Any()).Return(nil, nil).Times(1)
+
+	o := NewPortForwardDownOptions(&config.Project{
+		EnvDir:    dir,
+		AWSClient: config.NewAWSClient(config.WithSSMClient(mockSSM)),
+	})
+
+	if err = o.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if !processAlive(other.Process.Pid) {
+		t.Errorf("process of another program is terminated")
+	}
+}
+
+func TestMissingPortForwards(t *testing.T) {
+	running := []portForward{
+		{LocalPort: 15432, Host: "db.internal", RemotePort: 5432},
+		{LocalPort: 16379, Host: "redis", RemotePort: 6379},
+	}
+
+	tests := []struct {
+		name      string
+		requested []portForward
+		want      []portForward
+		wantErr   bool
+	}{
+		{
+			name:      "all running",
+			requested: []portForward{{LocalPort: 15432, Host: "db.internal", RemotePort: 5432}, {LocalPort: 20000, Host: "redis", RemotePort: 6379, freeLocalPort: true}},
+		},
+		{
+			name:      "new forward",
+			requested: []portForward{{LocalPort: 15432, Host: "db.internal", RemotePort: 5432}, {LocalPort: 16380, Host: "redis", RemotePort: 6380}},
+			want:      []portForward{{LocalPort: 16380, Host: "redis", RemotePort: 6380}},
+		},
+		{
+			name:      "same host on another local port",
+			requested: []portForward{{LocalPort: 25432, Host: "db.internal", RemotePort: 5432}},
+			want:      []portForward{{LocalPort: 25432, Host: "db.internal", RemotePort: 5432}},
+		},
+		{
+			name:      "local port is forwarded to another host",
+			requested: []portForward{{LocalPort: 16379, Host: "cache", RemotePort: 6379}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := missingPortForwards(running, tt.requested)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("missingPortForwards() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingPortForwards() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
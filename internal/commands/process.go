@@ -0,0 +1,60 @@
+//go:build !windows
+// +build !windows
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// detachProcess starts the command in a new session, so that it keeps running after ize exits
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// processAlive reports whether the process with the pid is running
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// terminateProcess asks the process with the pid to exit
+func terminateProcess(pid int) error {
+	// signals to pid 0 and negative pids are sent to process groups
+	if pid <= 0 {
+		return nil
+	}
+
+	err := syscall.Kill(pid, syscall.SIGTERM)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+
+	return err
+}
+
+// processName returns the name of the executable of the process with the pid
+func processName(pid int) (string, error) {
+	// comm of /proc is truncated to 15 characters, so the command line is used
+	if b, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil {
+		return filepath.Base(strings.SplitN(string(b), "\x00", 2)[0]), nil
+	}
+
+	out, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", fmt.Errorf("can't get process %d: %w", pid, err)
+	}
+
+	return filepath.Base(strings.TrimSpace(string(out))), nil
+}
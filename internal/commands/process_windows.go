@@ -0,0 +1,62 @@
+//go:build windows
+// +build windows
+
+package commands
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// detachProcess starts the command in a new process group, so that it keeps running after ize exits
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// processAlive reports whether the process with the pid is running
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+
+	return true
+}
+
+// terminateProcess asks the process with the pid to exit
+func terminateProcess(pid int) error {
+	if pid <= 0 {
+		return nil
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+
+	return p.Kill()
+}
+
+// processName returns the name of the executable of the process with the pid without the .exe extension
+func processName(pid int) (string, error) {
+	out, err := exec.Command("tasklist", "/FI", fmt.Sprintf("PID eq %d", pid), "/FO", "CSV", "/NH").Output()
+	if err != nil {
+		return "", fmt.Errorf("can't get process %d: %w", pid, err)
+	}
+
+	record, err := csv.NewReader(strings.NewReader(string(out))).Read()
+	if err != nil || len(record) < 2 {
+		return "", fmt.Errorf("process %d not found", pid)
+	}
+
+	return strings.TrimSuffix(record[0], ".exe"), nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/hazelops/ize/internal/manager"
 	"github.com/hazelops/ize/internal/manager/alias"
 	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/manager/k8s"
 	"github.com/hazelops/ize/internal/manager/serverless"
 	"github.com/hazelops/ize/pkg/templates"
 	"github.com/hazelops/ize/pkg/terminal"
@@ -96,6 +97,11 @@ func (o *PushOptions) Run() error {
 
 	var m manager.Manager
 
+	m = &ecs.Manager{
+		Project: o.Config,
+		App:     &config.Ecs{Name: o.AppName},
+	}
+
 	if app, ok := o.Config.Serverless[o.AppName]; ok {
 		app.Name = o.AppName
 		m = &serverless.Manager{
@@ -103,6 +109,13 @@ func (o *PushOptions) Run() error {
 			App:     app,
 		}
 	}
+	if app, ok := o.Config.K8s[o.AppName]; ok {
+		app.Name = o.AppName
+		m = &k8s.Manager{
+			Project: o.Config,
+			App:     app,
+		}
+	}
 	if app, ok := o.Config.Alias[o.AppName]; ok {
 		app.Name = o.AppName
 		m = &alias.Manager{
@@ -116,11 +129,6 @@ func (o *PushOptions) Run() error {
 			Project: o.Config,
 			App:     app,
 		}
-	} else {
-		m = &ecs.Manager{
-			Project: o.Config,
-			App:     &config.Ecs{Name: o.AppName},
-		}
 	}
 
 	if o.Explain {
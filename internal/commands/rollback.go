@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/hazelops/ize/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+type RollbackOptions struct {
+	Config   *config.Project
+	AppName  string
+	Revision int64
+}
+
+var rollbackLongDesc = templates.LongDesc(`
+	Roll back the ECS app to a previous revision of its task definition.
+	By default the latest active revision older than the running one is deployed.
+	Use ize history to list revisions available for rollback.
+`)
+
+var rollbackExample = templates.Examples(`
+	# Roll back the app to the previous revision
+	ize rollback <app name>
+
+	# Roll back the app to the revision 42
+	ize rollback <app name> --to 42
+`)
+
+func NewRollbackFlags(project *config.Project) *RollbackOptions {
+	return &RollbackOptions{
+		Config: project,
+	}
+}
+
+func NewCmdRollback(project *config.Project) *cobra.Command {
+	o := NewRollbackFlags(project)
+
+	cmd := &cobra.Command{
+		Use:               "rollback [flags] <app name>",
+		Short:             "Roll back the ECS app to a previous revision",
+		Long:              rollbackLongDesc,
+		Example:           rollbackExample,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: config.GetApps,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			err := o.Complete(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64Var(&o.Revision, "to", 0, "task definition revision to roll back to (default: the previous one)")
+
+	return cmd
+}
+
+func (o *RollbackOptions) Complete(cmd *cobra.Command) error {
+	if err := requirements.CheckRequirements(requirements.WithIzeStructure(), requirements.WithConfigFile()); err != nil {
+		return err
+	}
+
+	o.AppName = cmd.Flags().Args()[0]
+
+	return nil
+}
+
+func (o *RollbackOptions) Validate() error {
+	if o.Revision < 0 {
+		return fmt.Errorf("can't validate options: revision must be positive")
+	}
+
+	return validateEcsApp(o.Config, o.AppName)
+}
+
+func (o *RollbackOptions) Run() error {
+	ui := terminal.ConsoleUI(aws.BackgroundContext(), o.Config.PlainText)
+
+	ui.Output("Rolling back %s app...\n", o.AppName, terminal.WithHeaderStyle())
+
+	m := &ecs.Manager{
+		Project: o.Config,
+		App:     ecsApp(o.Config, o.AppName),
+	}
+
+	err := m.Rollback(ui, o.Revision)
+	if err != nil {
+		return err
+	}
+
+	ui.Output("Rollback app %s completed\n", o.AppName, terminal.WithSuccessStyle())
+
+	return nil
+}
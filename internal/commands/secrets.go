@@ -18,6 +18,7 @@ func NewCmdSecrets(project *config.Project) *cobra.Command {
 		NewCmdSecretsPush(project),
 		NewCmdSecretsEdit(project),
 		NewCmdSecretsPull(project),
+		NewCmdSecretsDiff(project),
 	)
 
 	return cmd
@@ -0,0 +1,173 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/secrets"
+	"github.com/hazelops/ize/pkg/templates"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+type SecretsDiffOptions struct {
+	Config         *config.Project
+	AppName        string
+	Backend        string
+	FilePath       string
+	SecretsPath    string
+	IncludeStrings bool
+	ShowValues     bool
+	ExitCode       bool
+}
+
+var secretsDiffExample = templates.Examples(`
+	# Show difference between local and remote secrets:
+
+    # This will compare secrets of "squibby" app in the local file with the ones stored in the backend
+    ize secrets diff squibby
+
+    # This will fail if secrets differ (useful to detect a drift in CI)
+    ize secrets diff squibby --exit-code
+`)
+
+func NewSecretsDiffFlags(project *config.Project) *SecretsDiffOptions {
+	return &SecretsDiffOptions{
+		Config: project,
+	}
+}
+
+func NewCmdSecretsDiff(project *config.Project) *cobra.Command {
+	o := NewSecretsDiffFlags(project)
+
+	cmd := &cobra.Command{
+		Use:               "diff <app>",
+		Example:           secretsDiffExample,
+		Short:             "Show difference between local and remote secrets",
+		Long:              "This command compares secrets from a local file with secrets in a key-value storage (like SSM).\nValues are masked unless --show-values is set.",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: config.GetApps,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			err := o.Complete(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = o.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = o.Run()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Backend, "backend", "", "backend type: ssm, secrets-manager or local (default is secrets_backend of the app or ssm)")
+	cmd.Flags().StringVar(&o.FilePath, "file", "", "file with secrets")
+	cmd.Flags().StringVar(&o.SecretsPath, "path", "", "path where secrets are stored (/<env>/<app> by default)")
+	cmd.Flags().BoolVar(&o.IncludeStrings, "include-strings", false, "include plaintext strings")
+	cmd.Flags().BoolVar(&o.ShowValues, "show-values", false, "show values instead of masking them")
+	cmd.Flags().BoolVar(&o.ExitCode, "exit-code", false, "exit with an error if secrets differ")
+
+	return cmd
+}
+
+func (o *SecretsDiffOptions) Complete(cmd *cobra.Command) error {
+	o.AppName = cmd.Flags().Args()[0]
+
+	if o.FilePath == "" {
+		o.FilePath = fmt.Sprintf("%s/%s/%s.json", o.Config.EnvDir, "secrets", o.AppName)
+	}
+
+	if o.Backend == "" {
+		o.Backend = secrets.AppBackend(o.Config, o.AppName)
+	}
+
+	if o.SecretsPath == "" {
+		o.SecretsPath = secrets.DefaultPath(o.Backend, o.Config, o.AppName)
+	}
+
+	return nil
+}
+
+func (o *SecretsDiffOptions) Validate() error {
+	if len(o.Config.Env) == 0 {
+		return fmt.Errorf("env must be specified")
+	}
+
+	return nil
+}
+
+func (o *SecretsDiffOptions) Run() error {
+	backend, err := secrets.New(o.Backend, o.Config, secrets.Options{
+		App:            o.AppName,
+		IncludeStrings: o.IncludeStrings,
+		Passphrase:     os.Getenv(secrets.PassphraseEnv),
+	})
+	if err != nil {
+		return fmt.Errorf("can't diff secrets: %w", err)
+	}
+
+	changes, err := o.diff(backend)
+	if err != nil {
+		return fmt.Errorf("can't diff secrets: %w", err)
+	}
+
+	if len(changes) == 0 {
+		pterm.Success.Printfln("Secrets in %s are in sync with %s://%s", o.FilePath, o.Backend, o.SecretsPath)
+		return nil
+	}
+
+	data := pterm.TableData{{"KEY", "CHANGE", "LOCAL", "REMOTE"}}
+	for _, c := range changes {
+		local, remote := c.Local, c.Remote
+		if !o.ShowValues {
+			local, remote = secrets.Mask(local), secrets.Mask(remote)
+		}
+
+		change := c.Type
+		switch c.Type {
+		case secrets.Added:
+			change = pterm.Green(c.Type)
+		case secrets.Removed:
+			change = pterm.Red(c.Type)
+		case secrets.Changed:
+			change = pterm.Yellow(c.Type)
+		}
+
+		data = append(data, []string{c.Key, change, local, remote})
+	}
+
+	pterm.DefaultSection.Printfln("Difference between %s and %s://%s", o.FilePath, o.Backend, o.SecretsPath)
+	_ = pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+
+	if o.ExitCode {
+		return fmt.Errorf("secrets of %s differ: %d change(s)", o.AppName, len(changes))
+	}
+
+	return nil
+}
+
+func (o *SecretsDiffOptions) diff(backend secrets.Backend) ([]secrets.Change, error) {
+	local, err := getKeyValuePairs(o.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := backend.Pull(o.SecretsPath)
+	if secrets.IsNotFound(err) {
+		remote = map[string]string{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return secrets.Diff(local, remote), nil
+}
@@ -5,12 +5,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"strings"
 	"text/template"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/secrets"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -72,7 +70,7 @@ func NewCmdSecretsPull(project *config.Project) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&o.Backend, "backend", "ssm", "backend type (default=ssm)")
+	cmd.Flags().StringVar(&o.Backend, "backend", "", "backend type: ssm, secrets-manager or local (default is secrets_backend of the app or ssm)")
 	cmd.Flags().StringVar(&o.FilePath, "file", "", "file with secrets")
 	cmd.Flags().StringVar(&o.SecretsPath, "path", "", "path where to store secrets (/<env>/<app> by default)")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
@@ -89,8 +87,12 @@ func (o *SecretsPullOptions) Complete(cmd *cobra.Command) error {
 		o.FilePath = fmt.Sprintf("%s/%s/%s.json", o.Config.EnvDir, "secrets", o.AppName)
 	}
 
+	if o.Backend == "" {
+		o.Backend = secrets.AppBackend(o.Config, o.AppName)
+	}
+
 	if o.SecretsPath == "" {
-		o.SecretsPath = fmt.Sprintf("/%s/%s", o.Config.Env, o.AppName)
+		o.SecretsPath = secrets.DefaultPath(o.Backend, o.Config, o.AppName)
 	}
 
 	return nil
@@ -106,6 +108,10 @@ func (o *SecretsPullOptions) Validate() error {
 
 func (o *SecretsPullOptions) Run() error {
 	if o.Explain {
+		if o.Backend != secrets.SSM {
+			return fmt.Errorf("explain is supported only for %s backend", secrets.SSM)
+		}
+
 		err := o.Config.Generate(explainSecretsPullTmpl, template.FuncMap{
 			"svc": func() string {
 				return o.AppName
@@ -118,14 +124,20 @@ func (o *SecretsPullOptions) Run() error {
 		return nil
 	}
 
+	backend, err := secrets.New(o.Backend, o.Config, secrets.Options{
+		App:            o.AppName,
+		IncludeStrings: o.IncludeStrings,
+		Passphrase:     os.Getenv(secrets.PassphraseEnv),
+	})
+	if err != nil {
+		return fmt.Errorf("can't pull secrets: %w", err)
+	}
+
 	s, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Pulling secrets for %s...", o.AppName))
-	if o.Backend == "ssm" {
-		err := o.pull(s)
-		if err != nil {
-			return fmt.Errorf("can't pull secrets: %w", err)
-		}
-	} else {
-		return fmt.Errorf("backend with type %s not found or not supported", o.Backend)
+
+	err = o.pull(s, backend)
+	if err != nil {
+		return fmt.Errorf("can't pull secrets: %w", err)
 	}
 
 	s.Success("Pulling secrets complete!")
@@ -133,63 +145,14 @@ func (o *SecretsPullOptions) Run() error {
 	return nil
 }
 
-func (o *SecretsPullOptions) pull(s *pterm.SpinnerPrinter) error {
+func (o *SecretsPullOptions) pull(s *pterm.SpinnerPrinter, backend secrets.Backend) error {
 	s.UpdateText(fmt.Sprintf("Pulling secrets from %s://%s...", o.Backend, o.SecretsPath))
 
-	values := make(map[string]interface{})
-	typeValues := []string{"SecureString"}
-
-	if o.IncludeStrings {
-		typeValues = []string{"SecureString", "String"}
-	}
-
-	params, err := o.Config.AWSClient.SSMClient.GetParametersByPath(&ssm.GetParametersByPathInput{
-		Path:           aws.String(o.SecretsPath),
-		Recursive:      aws.Bool(true),
-		WithDecryption: aws.Bool(true),
-		ParameterFilters: []*ssm.ParameterStringFilter{
-			{
-				Key:    aws.String("Type"),
-				Values: aws.StringSlice(typeValues),
-			},
-		},
-	})
+	values, err := backend.Pull(o.SecretsPath)
 	if err != nil {
 		return err
 	}
 
-	for _, param := range params.Parameters {
-		p := strings.Split(*param.Name, "/")
-		values[p[len(p)-1]] = *param.Value
-	}
-
-	for {
-		if params.NextToken == nil {
-			break
-		}
-
-		params, err = o.Config.AWSClient.SSMClient.GetParametersByPath(&ssm.GetParametersByPathInput{
-			Path:           aws.String(o.SecretsPath),
-			Recursive:      aws.Bool(true),
-			WithDecryption: aws.Bool(true),
-			NextToken:      params.NextToken,
-			ParameterFilters: []*ssm.ParameterStringFilter{
-				{
-					Key:    aws.String("Type"),
-					Values: aws.StringSlice([]string{"SecureString"}),
-				},
-			},
-		})
-		if err != nil {
-			return err
-		}
-
-		for _, param := range params.Parameters {
-			p := strings.Split(*param.Name, "/")
-			values[p[len(p)-1]] = *param.Value
-		}
-	}
-
 	b, err := json.MarshalIndent(values, "", "")
 	if err != nil {
 		return err
@@ -6,12 +6,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/secrets"
 	"github.com/hazelops/ize/pkg/templates"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
@@ -24,6 +23,7 @@ type SecretsPushOptions struct {
 	FilePath    string
 	SecretsPath string
 	Force       bool
+	Prune       bool
 	Explain     bool
 }
 
@@ -45,6 +45,12 @@ var secretsPushExample = templates.Examples(`
     
     # This will push secrets for "squibby" app from a "example-service.json" file to the AWS SSM storage with force option (values will be overwritten if exist)
 	ize secrets push squibby --backend ssm --file example-service.json --force
+
+    # This will push secrets for "squibby" app to AWS Secrets Manager as a single JSON secret
+	ize secrets push squibby --backend secrets-manager
+
+    # This will push secrets for "squibby" app and remove remote secrets that are absent in the file
+	ize secrets push squibby --force --prune
 `)
 
 func NewSecretsPushFlags(project *config.Project) *SecretsPushOptions {
@@ -85,11 +91,12 @@ func NewCmdSecretsPush(project *config.Project) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&o.Backend, "backend", "ssm", "backend type (default=ssm)")
+	cmd.Flags().StringVar(&o.Backend, "backend", "", "backend type: ssm, secrets-manager or local (default is secrets_backend of the app or ssm)")
 	cmd.Flags().StringVar(&o.FilePath, "file", "", "file with secrets")
 	cmd.Flags().StringVar(&o.SecretsPath, "path", "", "path where to store secrets (/<env>/<app> by default)")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
 	cmd.Flags().BoolVar(&o.Force, "force", false, "allow values overwrite")
+	cmd.Flags().BoolVar(&o.Prune, "prune", false, "remove remote secrets that are absent in the file")
 
 	return cmd
 }
@@ -101,8 +108,12 @@ func (o *SecretsPushOptions) Complete(cmd *cobra.Command) error {
 		o.FilePath = fmt.Sprintf("%s/%s/%s.json", o.Config.EnvDir, "secrets", o.AppName)
 	}
 
+	if o.Backend == "" {
+		o.Backend = secrets.AppBackend(o.Config, o.AppName)
+	}
+
 	if o.SecretsPath == "" {
-		o.SecretsPath = fmt.Sprintf("/%s/%s", o.Config.Env, o.AppName)
+		o.SecretsPath = secrets.DefaultPath(o.Backend, o.Config, o.AppName)
 	}
 
 	return nil
@@ -118,6 +129,10 @@ func (o *SecretsPushOptions) Validate() error {
 
 func (o *SecretsPushOptions) Run() error {
 	if o.Explain {
+		if o.Backend != secrets.SSM {
+			return fmt.Errorf("explain is supported only for %s backend", secrets.SSM)
+		}
+
 		err := o.Config.Generate(explainSecretsPushTmpl, template.FuncMap{
 			"svc": func() string {
 				return o.AppName
@@ -130,14 +145,21 @@ func (o *SecretsPushOptions) Run() error {
 		return nil
 	}
 
+	// secrets are pulled only by --prune, which removes String parameters under the path too
+	backend, err := secrets.New(o.Backend, o.Config, secrets.Options{
+		App:            o.AppName,
+		IncludeStrings: true,
+		Passphrase:     os.Getenv(secrets.PassphraseEnv),
+	})
+	if err != nil {
+		return fmt.Errorf("can't push secrets: %w", err)
+	}
+
 	s, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Pushing secrets for %s...", o.AppName))
-	if o.Backend == "ssm" {
-		err := o.push(s)
-		if err != nil {
-			return fmt.Errorf("can't push secrets: %w", err)
-		}
-	} else {
-		return fmt.Errorf("backend with type %s not found or not supported", o.Backend)
+
+	err = o.push(s, backend)
+	if err != nil {
+		return fmt.Errorf("can't push secrets: %w", err)
 	}
 
 	s.Success("Pushing secrets complete!")
@@ -145,7 +167,7 @@ func (o *SecretsPushOptions) Run() error {
 	return nil
 }
 
-func (o *SecretsPushOptions) push(s *pterm.SpinnerPrinter) error {
+func (o *SecretsPushOptions) push(s *pterm.SpinnerPrinter, backend secrets.Backend) error {
 	s.UpdateText("Reading secrets from file...")
 	values, err := getKeyValuePairs(o.FilePath)
 	if err != nil {
@@ -154,45 +176,40 @@ func (o *SecretsPushOptions) push(s *pterm.SpinnerPrinter) error {
 
 	s.UpdateText(fmt.Sprintf("Pushing secrets to %s://%s...", o.Backend, o.SecretsPath))
 
-	for key, value := range values {
-		name := fmt.Sprintf("%s/%s", o.SecretsPath, key)
+	err = backend.Push(o.SecretsPath, values, o.Force)
+	if err != nil {
+		return err
+	}
 
-		_, err := o.Config.AWSClient.SSMClient.PutParameter(&ssm.PutParameterInput{
-			Name:      &name,
-			Value:     aws.String(value),
-			Type:      aws.String(ssm.ParameterTypeSecureString),
-			Overwrite: &o.Force,
-		})
+	if !o.Prune {
+		return nil
+	}
 
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "ParameterAlreadyExists":
-				return fmt.Errorf("secret already exists, you can use --force to overwrite it")
-			default:
-				return err
-			}
-		}
+	s.UpdateText(fmt.Sprintf("Pruning secrets in %s://%s...", o.Backend, o.SecretsPath))
 
-		_, err = o.Config.AWSClient.SSMClient.AddTagsToResource(&ssm.AddTagsToResourceInput{
-			ResourceId:   &name,
-			ResourceType: aws.String("Parameter"),
-			Tags: []*ssm.Tag{
-				{
-					Key:   aws.String("Application"),
-					Value: &o.AppName,
-				},
-				{
-					Key:   aws.String("EnvVarName"),
-					Value: &key,
-				},
-			},
-		})
+	remote, err := backend.Pull(o.SecretsPath)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			return err
+	var keys []string
+	for _, c := range secrets.Diff(values, remote) {
+		if c.Type == secrets.Removed {
+			keys = append(keys, c.Key)
 		}
 	}
 
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err = backend.Delete(o.SecretsPath, keys)
+	if err != nil {
+		return fmt.Errorf("can't prune %s: %w", strings.Join(keys, ", "), err)
+	}
+
+	pterm.Info.Printfln("Pruned secrets: %s", strings.Join(keys, ", "))
+
 	return nil
 }
 
@@ -3,11 +3,12 @@ package commands
 import (
 	"context"
 	"fmt"
+	"os"
 	"text/template"
 	"time"
 
-	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/secrets"
 	"github.com/hazelops/ize/pkg/templates"
 	"github.com/hazelops/ize/pkg/terminal"
 	"github.com/pterm/pterm"
@@ -70,7 +71,7 @@ func NewCmdSecretsRemove(project *config.Project) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&o.Backend, "backend", "ssm", "backend type")
+	cmd.Flags().StringVar(&o.Backend, "backend", "", "backend type: ssm, secrets-manager or local (default is secrets_backend of the app or ssm)")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
 	cmd.Flags().StringVar(&o.SecretsPath, "path", "", "path to secrets")
 
@@ -80,8 +81,12 @@ func NewCmdSecretsRemove(project *config.Project) *cobra.Command {
 func (o *SecretsRemoveOptions) Complete(cmd *cobra.Command) error {
 	o.AppName = cmd.Flags().Args()[0]
 
+	if o.Backend == "" {
+		o.Backend = secrets.AppBackend(o.Config, o.AppName)
+	}
+
 	if o.SecretsPath == "" {
-		o.SecretsPath = fmt.Sprintf("/%s/%s", o.Config.Env, o.AppName)
+		o.SecretsPath = secrets.DefaultPath(o.Backend, o.Config, o.AppName)
 	}
 
 	o.ui = terminal.ConsoleUI(context.Background(), o.Config.PlainText)
@@ -99,6 +104,10 @@ func (o *SecretsRemoveOptions) Validate() error {
 
 func (o *SecretsRemoveOptions) Run() error {
 	if o.Explain {
+		if o.Backend != secrets.SSM {
+			return fmt.Errorf("explain is supported only for %s backend", secrets.SSM)
+		}
+
 		err := o.Config.Generate(explainSecretsRmTmpl, template.FuncMap{
 			"svc": func() string {
 				return o.AppName
@@ -111,15 +120,20 @@ func (o *SecretsRemoveOptions) Run() error {
 		return nil
 	}
 
+	backend, err := secrets.New(o.Backend, o.Config, secrets.Options{
+		App:        o.AppName,
+		Passphrase: os.Getenv(secrets.PassphraseEnv),
+	})
+	if err != nil {
+		return fmt.Errorf("can't remove secrets: %w", err)
+	}
+
 	s, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Removing secrets for %s...", o.AppName))
-	if o.Backend == "ssm" {
-		err := o.rm(s)
-		if err != nil {
-			pterm.DefaultSection.Sprintfln("Secrets have been removed from %s", o.SecretsPath)
-			return err
-		}
-	} else {
-		return fmt.Errorf("backend %s is not found or not supported", o.Backend)
+
+	err = o.rm(s, backend)
+	if err != nil {
+		pterm.DefaultSection.Sprintfln("Secrets have been removed from %s", o.SecretsPath)
+		return err
 	}
 
 	s.Success("Removing secrets complete!")
@@ -127,7 +141,7 @@ func (o *SecretsRemoveOptions) Run() error {
 	return nil
 }
 
-func (o *SecretsRemoveOptions) rm(s *pterm.SpinnerPrinter) error {
+func (o *SecretsRemoveOptions) rm(s *pterm.SpinnerPrinter, backend secrets.Backend) error {
 	if o.SecretsPath == "" {
 		s.UpdateText("Path was not set...")
 		time.Sleep(2 * time.Second)
@@ -136,37 +150,5 @@ func (o *SecretsRemoveOptions) rm(s *pterm.SpinnerPrinter) error {
 
 	s.UpdateText(fmt.Sprintf("Removing secrets from %s://%s...", o.Backend, o.SecretsPath))
 
-	out, err := o.Config.AWSClient.SSMClient.GetParametersByPath(&ssm.GetParametersByPathInput{
-		Path: &o.SecretsPath,
-	})
-	if err != nil {
-		return err
-	}
-
-	s.UpdateText("Getting secrets...")
-
-	if len(out.Parameters) == 0 {
-		s.UpdateText("No values found...")
-		time.Sleep(2 * time.Second)
-		s.UpdateText("Removing secrets...")
-		time.Sleep(1 * time.Second)
-		return nil
-	}
-
-	var names []*string
-
-	for _, p := range out.Parameters {
-		names = append(names, p.Name)
-	}
-
-	_, err = o.Config.AWSClient.SSMClient.DeleteParameters(&ssm.DeleteParametersInput{
-		Names: names,
-	})
-	if err != nil {
-		return err
-	}
-
-	s.UpdateText("Removing secrets...")
-
-	return nil
+	return backend.Remove(o.SecretsPath)
 }
@@ -263,6 +263,29 @@ func TestSecretsPush(t *testing.T) {
 			wantErr:       false,
 			mockSSMClient: mockSSM,
 		},
+		{
+			name:           "success (prune)",
+			args:           []string{"secrets", "push", "squibby", "--force", "--prune"},
+			env:            map[string]string{"ENV": "test", "AWS_PROFILE": "test"},
+			withConfigFile: true,
+			wantErr:        false,
+			mockSSMClient: func(m *mocks.MockSSMAPI) {
+				mockSSM(m)
+				m.EXPECT().GetParametersByPath(gomock.Any()).Return(&ssm.GetParametersByPathOutput{
+					Parameters: []*ssm.Parameter{
+						{Name: aws.String("/test/squibby/service__key__one"), Value: aws.String("value one")},
+						{Name: aws.String("/test/squibby/service__key__two"), Value: aws.String("test value two")},
+						{Name: aws.String("/test/squibby/service__key__old"), Value: aws.String("old value")},
+					},
+				}, nil).Times(1)
+				m.EXPECT().DeleteParameters(gomock.Any()).DoAndReturn(func(input *ssm.DeleteParametersInput) (*ssm.DeleteParametersOutput, error) {
+					if len(input.Names) != 1 || *input.Names[0] != "/test/squibby/service__key__old" {
+						t.Errorf("DeleteParameters() names = %v", aws.StringValueSlice(input.Names))
+					}
+					return &ssm.DeleteParametersOutput{}, nil
+				}).Times(1)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -513,3 +536,139 @@ func TestSecretsRm(t *testing.T) {
 		})
 	}
 }
+
+func TestSecretsDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		wantErr       bool
+		env           map[string]string
+		mockSSMClient func(m *mocks.MockSSMAPI)
+	}{
+		{
+			name:    "in sync",
+			args:    []string{"secrets", "diff", "squibby", "--exit-code"},
+			env:     map[string]string{"ENV": "test", "AWS_PROFILE": "test"},
+			wantErr: false,
+			mockSSMClient: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParametersByPath(gomock.Any()).Return(&ssm.GetParametersByPathOutput{
+					Parameters: []*ssm.Parameter{
+						{Name: aws.String("/test/squibby/service__key__one"), Value: aws.String("value one")},
+						{Name: aws.String("/test/squibby/service__key__two"), Value: aws.String("test value two")},
+					},
+				}, nil).Times(1)
+			},
+		},
+		{
+			name:    "differ",
+			args:    []string{"secrets", "diff", "squibby"},
+			env:     map[string]string{"ENV": "test", "AWS_PROFILE": "test"},
+			wantErr: false,
+			mockSSMClient: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParametersByPath(gomock.Any()).Return(&ssm.GetParametersByPathOutput{
+					Parameters: []*ssm.Parameter{
+						{Name: aws.String("/test/squibby/service__key__one"), Value: aws.String("old value")},
+						{Name: aws.String("/test/squibby/service__key__old"), Value: aws.String("old value")},
+					},
+				}, nil).Times(1)
+			},
+		},
+		{
+			name:    "differ with exit code",
+			args:    []string{"secrets", "diff", "squibby", "--exit-code"},
+			env:     map[string]string{"ENV": "test", "AWS_PROFILE": "test"},
+			wantErr: true,
+			mockSSMClient: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParametersByPath(gomock.Any()).Return(&ssm.GetParametersByPathOutput{}, nil).Times(1)
+			},
+		},
+		{
+			name:    "failed",
+			args:    []string{"secrets", "diff", "squibby"},
+			env:     map[string]string{"ENV": "test", "AWS_PROFILE": "test"},
+			wantErr: true,
+			mockSSMClient: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParametersByPath(gomock.Any()).Return(nil, awserr.New("error", "", nil)).Times(1)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer resetEnv(os.Environ())
+			viper.Reset()
+			os.Unsetenv("IZE_CONFIG_FILE")
+			// Set env
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			temp, err := os.MkdirTemp("", "test")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			err = os.Chdir(temp)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			err = os.MkdirAll(filepath.Join(temp, ".ize", "env", "test", "secrets"), 0777)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			err = os.WriteFile(filepath.Join(temp, ".ize", "env", "test", "secrets", "squibby.json"), []byte("{\n  \"service__key__one\": \"value one\",\n  \"service__key__two\": \"test value two\"\n}"), 0666)
+			if err != nil {
+				t.Error(err)
+			}
+
+			setConfigFile(filepath.Join(temp, "ize.toml"), buildToml, t)
+
+			t.Setenv("HOME", temp)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockSSMAPI := mocks.NewMockSSMAPI(ctrl)
+			tt.mockSSMClient(mockSSMAPI)
+
+			cfg := new(config.Project)
+			cmd := newRootCmd(cfg)
+
+			cmd.SetArgs(tt.args)
+			cmd.PersistentFlags().ParseErrorsWhitelist.UnknownFlags = true
+			err = cmd.PersistentFlags().Parse(tt.args)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			cmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+				if len(f.Value.String()) != 0 {
+					_ = viper.BindPFlag(strings.ReplaceAll(f.Name, "-", "_"), cmd.PersistentFlags().Lookup(f.Name))
+				}
+			})
+
+			config.InitConfig()
+
+			err = cfg.GetTestConfig()
+			if err != nil {
+				t.Errorf("get config error = %v, wantErr %v", err, tt.wantErr)
+				os.Exit(1)
+			}
+
+			cfg.AWSClient = config.NewAWSClient(
+				config.WithSSMClient(mockSSMAPI),
+			)
+
+			cfg.Session = getSession(false)
+
+			err = cmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ize secrets diff error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+		})
+	}
+}
@@ -2,50 +2,49 @@ package commands
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/service/ecs"
-	"github.com/aws/aws-sdk-go/service/ssm"
-	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager/ecs"
 	"github.com/hazelops/ize/internal/requirements"
 	"github.com/hazelops/ize/pkg/templates"
 	"github.com/pterm/pterm"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"os"
-	"os/signal"
-	"strings"
-	"syscall"
+	"golang.org/x/exp/slices"
 )
 
 type StartOptions struct {
-	Config     *config.Project
-	AppName    string
-	EcsCluster string
-}
-
-type NetworkConfiguration struct {
-	SecurityGroups struct {
-		Value string `json:"value"`
-	} `json:"security_groups"`
-	Subnets struct {
-		Value [][]string `json:"value"`
-	} `json:"subnets"`
-	VpcPrivateSubnets struct {
-		Value []string `json:"value"`
-	} `json:"vpc_private_subnets"`
-	VpcPublicSubnets struct {
-		Value []string `json:"value"`
-	} `json:"vpc_public_subnets"`
+	Config           *config.Project
+	AppName          string
+	EcsCluster       string
+	Command          []string
+	Env              []string
+	Environment      map[string]string
+	Cpu              string
+	Memory           string
+	LaunchType       string
+	CapacityProvider string
 }
 
 var startExample = templates.Examples(`
-	# Connect to a container in the ECS via AWS SSM and run command.
+	# Start ECS task of the app and stream its logs
 	ize start goblin
+
+	# Run one-off command in the app container, ize exits with the exit code of the container
+	ize start goblin -- rake db:migrate
+
+	# Run command with additional environment and resources
+	ize start goblin --env RAILS_ENV=staging --cpu 1024 --memory 2048 -- rake db:seed
+
+	# Run task using capacity provider
+	ize start goblin --capacity-provider FARGATE_SPOT -- ./report.sh
 `)
 
 func NewStartFlags(project *config.Project) *StartOptions {
@@ -58,10 +57,10 @@ func NewCmdStart(project *config.Project) *cobra.Command {
 	o := NewStartFlags(project)
 
 	cmd := &cobra.Command{
-		Use:               "start [app-name]",
+		Use:               "start [flags] <app name> [-- command args...]",
 		Example:           startExample,
 		Short:             "Start ECS task",
-		Long:              "Start ECS task and stream logs until it dies or canceled.\nIt uses app name as an argument.",
+		Long:              "Start ECS task and stream logs until it dies or canceled.\nIt uses app name as an argument, arguments after -- override the command of the app container.\nize exits with the exit code of the app container.",
 		Args:              cobra.MinimumNArgs(1),
 		ValidArgsFunction: config.GetApps,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -86,6 +85,11 @@ func NewCmdStart(project *config.Project) *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&o.EcsCluster, "ecs-cluster", "", "set ECS cluster name")
+	cmd.Flags().StringArrayVar(&o.Env, "env", nil, "set environment variable of the app container (KEY=VALUE), can be repeated")
+	cmd.Flags().StringVar(&o.Cpu, "cpu", "", "override task CPU units (e.g. 512 or \"1 vCPU\")")
+	cmd.Flags().StringVar(&o.Memory, "memory", "", "override task memory (e.g. 2048 or \"2 GB\")")
+	cmd.Flags().StringVar(&o.LaunchType, "launch-type", "", "set launch type of the task (FARGATE, EC2 or EXTERNAL), FARGATE by default")
+	cmd.Flags().StringVar(&o.CapacityProvider, "capacity-provider", "", "run the task using capacity provider instead of launch type")
 
 	return cmd
 }
@@ -99,7 +103,25 @@ func (o *StartOptions) Complete(cmd *cobra.Command) error {
 		o.EcsCluster = fmt.Sprintf("%s-%s", o.Config.Env, o.Config.Namespace)
 	}
 
-	o.AppName = cmd.Flags().Args()[0]
+	args := cmd.Flags().Args()
+	if dash := cmd.ArgsLenAtDash(); dash != -1 {
+		o.Command = args[dash:]
+		args = args[:dash]
+	}
+
+	if len(args) != 0 {
+		o.AppName = args[0]
+	}
+
+	o.Environment = map[string]string{}
+	for _, kv := range o.Env {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || len(k) == 0 {
+			return fmt.Errorf("can't complete: invalid environment variable %q, must be KEY=VALUE", kv)
+		}
+
+		o.Environment[k] = v
+	}
 
 	return nil
 }
@@ -109,144 +131,70 @@ func (o *StartOptions) Validate() error {
 		return fmt.Errorf("can't validate: app name must be specified")
 	}
 
-	return nil
-}
-
-func getNetworkConfiguration(svc ssmiface.SSMAPI, env string) (NetworkConfiguration, error) {
-	resp, err := svc.GetParameter(&ssm.GetParameterInput{
-		Name:           aws.String(fmt.Sprintf("/%s/terraform-output", env)),
-		WithDecryption: aws.Bool(true),
-	})
-	if err != nil {
-		return NetworkConfiguration{}, fmt.Errorf("can't get terraform output: %w", err)
+	if len(o.LaunchType) != 0 && len(o.CapacityProvider) != 0 {
+		return fmt.Errorf("can't validate: --launch-type and --capacity-provider can't be used together")
 	}
 
-	var value []byte
-
-	value, err = base64.StdEncoding.DecodeString(*resp.Parameter.Value)
-	if err != nil {
-		return NetworkConfiguration{}, fmt.Errorf("can't get terraform output: %w", err)
+	if len(o.LaunchType) != 0 && !slices.Contains(awsecs.LaunchType_Values(), o.LaunchType) {
+		return fmt.Errorf("can't validate: invalid launch type %s, must be one of %s", o.LaunchType, strings.Join(awsecs.LaunchType_Values(), ", "))
 	}
 
-	var output NetworkConfiguration
-
-	err = json.Unmarshal(value, &output)
-	if err != nil {
-		return NetworkConfiguration{}, fmt.Errorf("can't get network configuration: %w", err)
-	}
-
-	return output, nil
+	return nil
 }
 
 func (o *StartOptions) Run() error {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGHUP, syscall.SIGTERM)
+	defer stop()
 
 	appName := fmt.Sprintf("%s-%s", o.Config.Env, o.AppName)
-	logGroup := fmt.Sprintf("%s-%s", o.Config.Env, o.AppName)
 
 	logrus.Debugf("app name: %s, cluster name: %s", appName, o.EcsCluster)
 	logrus.Debugf("region: %s, profile: %s", o.Config.AwsProfile, o.Config.AwsRegion)
 
-	configuration, err := getNetworkConfiguration(o.Config.AWSClient.SSMClient, o.Config.Env)
+	configuration, err := ecs.GetNetworkConfiguration(o.Config.AWSClient.SSMClient, o.Config.Env)
 	if err != nil {
 		return err
 	}
 
 	logrus.Debugf("network configuration: %+v", configuration)
 
-	if len(configuration.VpcPrivateSubnets.Value) == 0 {
-		return fmt.Errorf("output private_subnets is missing. Please add it to your Terraform")
+	pterm.DefaultSection.Println("Logs:")
+
+	res, err := ecs.RunTask(ctx, o.Config, ecs.Task{
+		Cluster:          o.EcsCluster,
+		TaskDefinition:   appName,
+		Container:        o.AppName,
+		Command:          o.Command,
+		Environment:      o.Environment,
+		Cpu:              o.Cpu,
+		Memory:           o.Memory,
+		LaunchType:       o.LaunchType,
+		CapacityProvider: o.CapacityProvider,
+		LogGroup:         appName,
+		Subnets:          configuration.VpcPrivateSubnets.Value,
+		SecurityGroups:   configuration.SecurityGroupIDs(),
+	}, os.Stdout)
+	if errors.Is(err, context.Canceled) {
+		fmt.Print("\r")
+		pterm.Success.Printfln("Stop task %s by interrupt", appName)
+		return nil
 	}
-
-	if len(configuration.SecurityGroups.Value) == 0 {
-		return fmt.Errorf("output security_groups is missing. Please add it to your Terraform")
+	if err != nil {
+		return err
 	}
 
-	out, err := o.Config.AWSClient.ECSClient.RunTaskWithContext(ctx, &ecs.RunTaskInput{
-		TaskDefinition: &appName,
-		StartedBy:      aws.String("IZE"),
-		Cluster:        &o.EcsCluster,
-		NetworkConfiguration: &ecs.NetworkConfiguration{AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
-			Subnets: aws.StringSlice(configuration.VpcPrivateSubnets.Value),
-		}},
-		LaunchType: aws.String(ecs.LaunchTypeFargate),
-	})
-	if aerr, ok := err.(awserr.Error); ok {
-		switch aerr.Code() {
-		case "ClusterNotFoundException":
-			return fmt.Errorf("ECS cluster %s not found", o.EcsCluster)
-		default:
-			return err
-		}
+	if res.ExitCode == nil {
+		return fmt.Errorf("%s was stopped without exit code: %s", appName, res.StoppedReason)
 	}
 
-	taskID := getTaskID(*out.Tasks[0].TaskArn)
-
-	c := make(chan os.Signal)
-	ch := make(chan bool)
-	errorChannel := make(chan error)
-	signal.Notify(c, syscall.SIGINT, syscall.SIGHUP, syscall.SIGTERM)
-
-	go func() {
-		s, _ := pterm.DefaultSpinner.WithRemoveWhenDone().Start(fmt.Sprintf("Please wait until task %s running...", appName))
-
-		err := o.Config.AWSClient.ECSClient.WaitUntilTasksRunningWithContext(ctx, &ecs.DescribeTasksInput{
-			Cluster: &o.EcsCluster,
-			Tasks:   aws.StringSlice([]string{*out.Tasks[0].TaskArn}),
-		})
-		if err != nil {
-			errorChannel <- err
+	if code := *res.ExitCode; code != 0 {
+		return &ExitCodeError{
+			Code: int(code),
+			Err:  fmt.Errorf("%s exited with code %d: %s", appName, code, res.StoppedReason),
 		}
-
-		s.Success()
-		pterm.DefaultSection.Println("Logs:")
-
-		var token *string
-		go GetLogs(o.Config.AWSClient.CloudWatchLogsClient, logGroup, fmt.Sprintf("main/%s/%s", o.AppName, taskID), token)
-		err = o.Config.AWSClient.ECSClient.WaitUntilTasksStoppedWithContext(ctx, &ecs.DescribeTasksInput{
-			Cluster: &o.EcsCluster,
-			Tasks:   aws.StringSlice([]string{*out.Tasks[0].TaskArn}),
-		})
-		if err != nil {
-			errorChannel <- err
-		}
-		ch <- true
-	}()
-
-	select {
-	case <-c:
-		fmt.Print("\r")
-		_, err := o.Config.AWSClient.ECSClient.StopTask(&ecs.StopTaskInput{
-			Cluster: &o.EcsCluster,
-			Reason:  aws.String("Task stopped by IZE"),
-			Task:    out.Tasks[0].TaskArn,
-		})
-		if err != nil {
-			return err
-		}
-		pterm.Success.Printfln("Stop task %s by interrupt", appName)
-	case <-ch:
-		tasks, err := o.Config.AWSClient.ECSClient.DescribeTasks(&ecs.DescribeTasksInput{
-			Cluster: &o.EcsCluster,
-			Tasks:   aws.StringSlice([]string{taskID}),
-		})
-		if err != nil {
-			return err
-		}
-
-		sr := *tasks.Tasks[0].StoppedReason
-		st := *tasks.Tasks[0].StopCode
-		logrus.Debugf("stop code: %s", st)
-		pterm.Success.Printfln("%s was stopped with reason: %s\n", appName, sr)
-		return nil
-	case err := <-errorChannel:
-		return err
 	}
 
-	return nil
-}
+	pterm.Success.Printfln("%s was stopped with reason: %s\n", appName, res.StoppedReason)
 
-func getTaskID(taskArn string) string {
-	split := strings.Split(taskArn, "/")
-	return split[len(split)-1]
+	return nil
 }
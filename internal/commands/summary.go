@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hazelops/ize/internal/manager"
+	"github.com/hazelops/ize/pkg/terminal"
+)
+
+// renderSummary outputs the status of every app and stack processed by a walk
+func renderSummary(ui terminal.UI, report *manager.Report) {
+	results := report.Results()
+	if len(results) == 0 {
+		return
+	}
+
+	t := terminal.NewTable("Name", "Status", "Duration", "Details")
+
+	for _, res := range results {
+		color := ""
+		switch res.Status {
+		case manager.ResultSucceeded:
+			color = terminal.Green
+		case manager.ResultFailed:
+			color = terminal.Red
+		case manager.ResultSkipped:
+			color = terminal.Yellow
+		}
+
+		duration, details := "", ""
+		if res.Status != manager.ResultSkipped {
+			duration = res.Duration.Round(time.Second).String()
+		}
+		if res.Err != nil {
+			details = res.Err.Error()
+		}
+
+		t.Rich([]string{res.Name, res.Status, duration, details}, []string{"", color, "", ""})
+	}
+
+	ui.Output("Summary:", terminal.WithHeaderStyle())
+	ui.Table(t)
+}
+
+// graphNames returns sorted names of the apps or stacks
+func graphNames(apps ...map[string]*interface{}) []string {
+	var names []string
+	for _, a := range apps {
+		for name := range a {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
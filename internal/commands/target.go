@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+// execTarget selects the task and the container exec and console connect to
+type execTarget struct {
+	Cluster   string
+	Service   string
+	AppName   string
+	Task      string
+	TaskIndex int
+	Container string
+	// NonInteractive disables selection prompts
+	NonInteractive bool
+}
+
+// resolve sets Task and Container. The task is the one set by --task or --task-index,
+// otherwise it's selected interactively when stdin is a terminal, or the oldest running task is used.
+// The container is the one set by --container, selected interactively or the app container.
+func (t *execTarget) resolve(project *config.Project) error {
+	if len(t.Task) != 0 {
+		if len(t.Container) == 0 {
+			t.Container = t.AppName
+		}
+
+		return nil
+	}
+
+	tasks, err := ecs.RunningTasks(project.AWSClient.ECSClient, t.Cluster, t.Service)
+	if err != nil {
+		return err
+	}
+
+	logrus.Debugf("running tasks: %v", tasks)
+
+	if len(tasks) == 0 {
+		return fmt.Errorf("running task not found")
+	}
+
+	interactive := !t.NonInteractive && term.IsTerminal(int(os.Stdin.Fd()))
+
+	task := tasks[0]
+
+	switch {
+	case t.TaskIndex >= 0:
+		if t.TaskIndex >= len(tasks) {
+			return fmt.Errorf("task index %d is out of range, %s has %d running task(s)", t.TaskIndex, t.Service, len(tasks))
+		}
+		task = tasks[t.TaskIndex]
+	case interactive && len(tasks) > 1:
+		var options []string
+		for _, rt := range tasks {
+			options = append(options, rt.String())
+		}
+
+		var i int
+		err = survey.AskOne(&survey.Select{
+			Message: "Task:",
+			Options: options,
+		}, &i)
+		if err != nil {
+			return fmt.Errorf("can't select task: %w", err)
+		}
+		task = tasks[i]
+	}
+
+	t.Task = task.Arn
+
+	switch {
+	case len(t.Container) != 0:
+		if !task.HasContainer(t.Container) {
+			return fmt.Errorf("task %s has no container %s, containers: %s", task.ID, t.Container, strings.Join(task.Containers, ", "))
+		}
+	case interactive && len(task.Containers) > 1:
+		var container string
+		prompt := &survey.Select{
+			Message: "Container:",
+			Options: task.Containers,
+		}
+		if task.HasContainer(t.AppName) {
+			prompt.Default = t.AppName
+		}
+
+		if err = survey.AskOne(prompt, &container); err != nil {
+			return fmt.Errorf("can't select container: %w", err)
+		}
+		t.Container = container
+	case task.HasContainer(t.AppName) || len(task.Containers) == 0:
+		t.Container = t.AppName
+	default:
+		t.Container = task.Containers[0]
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/golang/mock/gomock"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/mocks"
+)
+
+func TestExecTarget_resolve(t *testing.T) {
+	now := time.Now()
+
+	describe := &ecs.DescribeTasksOutput{
+		Tasks: []*ecs.Task{
+			{
+				TaskArn:    aws.String("arn:aws:ecs:us-east-1:0:task/dev-testnut/new"),
+				StartedAt:  aws.Time(now),
+				Containers: []*ecs.Container{{Name: aws.String("goblin")}, {Name: aws.String("nginx")}},
+			},
+			{
+				TaskArn:    aws.String("arn:aws:ecs:us-east-1:0:task/dev-testnut/old"),
+				StartedAt:  aws.Time(now.Add(-time.Hour)),
+				Containers: []*ecs.Container{{Name: aws.String("goblin")}, {Name: aws.String("nginx")}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		target        execTarget
+		describe      *ecs.DescribeTasksOutput
+		wantTask      string
+		wantContainer string
+		wantErr       bool
+	}{
+		{
+			name:          "oldest task and app container by default",
+			target:        execTarget{TaskIndex: -1},
+			describe:      describe,
+			wantTask:      "arn:aws:ecs:us-east-1:0:task/dev-testnut/old",
+			wantContainer: "goblin",
+		},
+		{
+			name:          "task index and container",
+			target:        execTarget{TaskIndex: 1, Container: "nginx"},
+			describe:      describe,
+			wantTask:      "arn:aws:ecs:us-east-1:0:task/dev-testnut/new",
+			wantContainer: "nginx",
+		},
+		{
+			name:     "task index out of range",
+			target:   execTarget{TaskIndex: 2},
+			describe: describe,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown container",
+			target:   execTarget{TaskIndex: -1, Container: "redis"},
+			describe: describe,
+			wantErr:  true,
+		},
+		{
+			name:     "no running tasks",
+			target:   execTarget{TaskIndex: -1},
+			describe: nil,
+			wantErr:  true,
+		},
+		{
+			name:   "sidecar only",
+			target: execTarget{TaskIndex: -1},
+			describe: &ecs.DescribeTasksOutput{Tasks: []*ecs.Task{{
+				TaskArn:    aws.String("arn:aws:ecs:us-east-1:0:task/dev-testnut/sidecar"),
+				Containers: []*ecs.Container{{Name: aws.String("datadog")}},
+			}}},
+			wantTask:      "arn:aws:ecs:us-east-1:0:task/dev-testnut/sidecar",
+			wantContainer: "datadog",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockECS := mocks.NewMockECSAPI(ctrl)
+
+			lto := &ecs.ListTasksOutput{}
+			if tt.describe != nil {
+				for _, task := range tt.describe.Tasks {
+					lto.TaskArns = append(lto.TaskArns, task.TaskArn)
+				}
+				mockECS.EXPECT().DescribeTasks(gomock.Any()).Return(tt.describe, nil).Times(1)
+			}
+			mockECS.EXPECT().ListTasks(gomock.Any()).Return(lto, nil).Times(1)
+
+			target := tt.target
+			target.Cluster = "dev-testnut"
+			target.Service = "dev-goblin"
+			target.AppName = "goblin"
+
+			err := target.resolve(&config.Project{AWSClient: config.NewAWSClient(config.WithECSClient(mockECS))})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if target.Task != tt.wantTask || target.Container != tt.wantContainer {
+				t.Errorf("resolve() = %s %s, want %s %s", target.Task, target.Container, tt.wantTask, tt.wantContainer)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ func NewCmdTunnel(project *config.Project) *cobra.Command {
 		NewCmdTunnelUp(project),
 		NewCmdTunnelDown(project),
 		NewCmdTunnelStatus(project),
+		NewCmdTunnelDaemon(project),
 	)
 
 	return cmd
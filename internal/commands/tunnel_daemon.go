@@ -0,0 +1,903 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/pkg/ssmsession"
+	"github.com/pterm/pterm"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	sshSessionDocument = "AWS-StartSSHSession"
+	bastionUser        = "ubuntu"
+
+	tunnelStarting     = "starting"
+	tunnelUp           = "up"
+	tunnelReconnecting = "reconnecting"
+	tunnelFailed       = "failed"
+
+	// tunnelDefaultProfile is the tunnel set directly in [infra.tunnel] or obtained from terraform output
+	tunnelDefaultProfile = "default"
+
+	forwardHealthy   = "healthy"
+	forwardUnhealthy = "unhealthy"
+)
+
+var (
+	// tunnelTimeout is how long ize tunnel up waits for the tunnel daemon to connect to the bastion host
+	tunnelTimeout = 60 * time.Second
+	// tunnelHealthInterval is how often the connection to the bastion host and the forwards are checked
+	tunnelHealthInterval = 15 * time.Second
+	// tunnelHealthTimeout is how long a keepalive or a forward check may take
+	tunnelHealthTimeout = 10 * time.Second
+	// tunnelMinBackoff and tunnelMaxBackoff limit the delay between reconnect attempts in watch mode
+	tunnelMinBackoff = time.Second
+	tunnelMaxBackoff = time.Minute
+)
+
+// tunnelForward forwards the local port to the remote host and port through the bastion host
+type tunnelForward struct {
+	Host       string `json:"host"`
+	RemotePort int    `json:"remote_port"`
+	LocalPort  int    `json:"local_port"`
+	// Health is the result of the last check, empty before the first one
+	Health       string    `json:"health,omitempty"`
+	HealthySince time.Time `json:"healthy_since"`
+	CheckedAt    time.Time `json:"checked_at"`
+	Error        string    `json:"error,omitempty"`
+}
+
+func (f tunnelForward) String() string {
+	return fmt.Sprintf("%s:%d ➡ localhost:%d", f.Host, f.RemotePort, f.LocalPort)
+}
+
+// tunnelState is the tunnel profile saved in <env dir>/tunnel-<profile>.json. ize tunnel up writes the settings of the tunnel,
+// the daemon serving it adds its pid and status and removes the file when it exits.
+type tunnelState struct {
+	Profile        string          `json:"profile"`
+	BastionHostID  string          `json:"bastion_instance_id"`
+	Forwards       []tunnelForward `json:"forwards"`
+	PrivateKeyFile string          `json:"ssh_private_key"`
+	PublicKeyFile  string          `json:"ssh_public_key"`
+	// UseEC2Metadata is set when the public key is sent with EC2 Instance Connect, which keeps it only for 60 seconds
+	UseEC2Metadata        bool `json:"use_ec2_metadata,omitempty"`
+	StrictHostKeyChecking bool `json:"strict_host_key_checking"`
+	// Watch makes the tunnel reconnect when the connection is lost instead of exiting
+	Watch       bool      `json:"watch,omitempty"`
+	PID         int       `json:"pid,omitempty"`
+	SessionID   string    `json:"session_id,omitempty"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Reconnects  int       `json:"reconnects,omitempty"`
+}
+
+// up reports whether the process serving the tunnel is running and connected to the bastion host or reconnecting
+func (s *tunnelState) up() bool {
+	return (s.Status == tunnelUp || s.Status == tunnelReconnecting) && daemonAlive(s.PID)
+}
+
+// daemonAlive reports whether the process with the pid is a running ize process. Saved pids of other processes
+// are never trusted or signaled, since they may be reused after the daemon has exited, e.g. after a reboot.
+func daemonAlive(pid int) bool {
+	if !processAlive(pid) {
+		return false
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		logrus.Debugf("can't get ize executable: %s", err)
+		return false
+	}
+
+	name, err := processName(pid)
+	if err != nil {
+		logrus.Debugf("can't get name of process %d: %s", pid, err)
+		return false
+	}
+
+	return name == strings.TrimSuffix(filepath.Base(exe), ".exe")
+}
+
+func NewCmdTunnelDaemon(project *config.Project) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "daemon <profile>",
+		Short:  "Serve tunnel in the background",
+		Long:   "Serve tunnel profile saved by ize tunnel up in the background until ize tunnel down",
+		Args:   cobra.ExactArgs(1),
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			return runTunnelDaemon(cmd.Context(), project, args[0])
+		},
+	}
+
+	return cmd
+}
+
+// runTunnelDaemon serves the saved tunnel profile until it's terminated
+func runTunnelDaemon(ctx context.Context, project *config.Project, profile string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	state, err := readTunnelState(project.EnvDir, profile)
+	if err != nil {
+		return err
+	}
+
+	if state == nil {
+		return fmt.Errorf("can't run tunnel: state of tunnel %s not found in %s", profile, project.EnvDir)
+	}
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+
+	return serveTunnel(ctx, project, state, logger, func(ok bool, msg string) {
+		logger.Println(msg)
+	})
+}
+
+// serveTunnel connects to the bastion host and serves forwards of the tunnel until ctx is done.
+// The state is saved on every change and removed when the tunnel is closed. If the tunnel can't be started,
+// the state is saved with the error, so that ize tunnel up can report it.
+func serveTunnel(ctx context.Context, project *config.Project, state *tunnelState, logger *log.Logger, notify func(ok bool, msg string)) error {
+	state.PID = os.Getpid()
+	if err := writeTunnelState(project.EnvDir, state); err != nil {
+		return err
+	}
+
+	t, err := newSSHTunnel(project, state, logger)
+	if err == nil {
+		t.notify = notify
+		t.save = func() {
+			if err := writeTunnelState(project.EnvDir, state); err != nil {
+				logger.Println(err)
+			}
+		}
+		err = t.start()
+	}
+	if err != nil {
+		state.Status = tunnelFailed
+		state.Error = err.Error()
+		_ = writeTunnelState(project.EnvDir, state)
+		return err
+	}
+
+	state.Status = tunnelUp
+	state.SessionID = t.sessionID
+	state.StartedAt = time.Now()
+	state.ConnectedAt = state.StartedAt
+	if err = writeTunnelState(project.EnvDir, state); err != nil {
+		t.close()
+		return err
+	}
+
+	notify(true, fmt.Sprintf("%s to %s is up", tunnelTitle(state.Profile), state.BastionHostID))
+
+	err = t.run(ctx, state.Watch)
+	t.close()
+
+	if rmErr := removeTunnelState(project.EnvDir, state.Profile); rmErr != nil {
+		logger.Println(rmErr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	notify(true, fmt.Sprintf("%s to %s is down", tunnelTitle(state.Profile), state.BastionHostID))
+
+	return nil
+}
+
+// sshTunnel forwards local ports through an SSH connection to the bastion host
+type sshTunnel struct {
+	state  *tunnelState
+	config *ssh.ClientConfig
+	// dial connects to sshd of the bastion host and returns the id of the SSM session
+	dial func() (net.Conn, string, error)
+	// terminate terminates the SSM session
+	terminate func(sessionID string)
+	// notify reports state transitions of the tunnel and its forwards
+	notify func(ok bool, msg string)
+	// save persists changes of the state
+	save func()
+	// refreshKey sends the public key to the bastion host again before reconnecting
+	refreshKey func() error
+	log        *log.Logger
+
+	mu        sync.Mutex
+	client    *ssh.Client
+	sessionID string
+	listeners []net.Listener
+}
+
+// newSSHTunnel returns the tunnel connecting to the bastion host through an AWS-StartSSHSession SSM session
+func newSSHTunnel(project *config.Project, state *tunnelState, logger *log.Logger) (*sshTunnel, error) {
+	auth, err := tunnelAuth(state.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeyCallback, err := tunnelHostKeyCallback(state.StrictHostKeyChecking)
+	if err != nil {
+		return nil, err
+	}
+
+	ssmCmd := ssmsession.NewSSMPluginCommand(project.AwsRegion)
+
+	return &sshTunnel{
+		state: state,
+		config: &ssh.ClientConfig{
+			User:            bastionUser,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+		},
+		dial: func() (net.Conn, string, error) {
+			input := &ssm.StartSessionInput{
+				Target:       aws.String(state.BastionHostID),
+				DocumentName: aws.String(sshSessionDocument),
+				Parameters: map[string][]*string{
+					"portNumber": aws.StringSlice([]string{"22"}),
+				},
+			}
+
+			out, err := project.AWSClient.SSMClient.StartSession(input)
+			if err != nil {
+				return nil, "", fmt.Errorf("can't start session: %w", err)
+			}
+
+			conn, err := ssmCmd.Dial(out, input, logger.Writer())
+			if err != nil {
+				return nil, "", err
+			}
+
+			return conn, aws.StringValue(out.SessionId), nil
+		},
+		terminate: func(sessionID string) {
+			_, err := project.AWSClient.SSMClient.TerminateSession(&ssm.TerminateSessionInput{
+				SessionId: aws.String(sessionID),
+			})
+			if err != nil {
+				logger.Printf("can't terminate session %s: %s", sessionID, err)
+			}
+		},
+		refreshKey: func() error {
+			if !state.UseEC2Metadata {
+				return nil
+			}
+
+			pk, err := getPublicKey(state.PublicKeyFile)
+			if err != nil {
+				return fmt.Errorf("can't get public key: %w", err)
+			}
+
+			return sendSSHPublicKey(state.BastionHostID, pk, project.Session)
+		},
+		log: logger,
+	}, nil
+}
+
+// start connects to the bastion host and listens on the local ports
+func (t *sshTunnel) start() error {
+	if err := t.connect(); err != nil {
+		return err
+	}
+
+	for _, f := range t.state.Forwards {
+		l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", f.LocalPort))
+		if err != nil {
+			t.close()
+			return fmt.Errorf("can't listen on local port %d: %w", f.LocalPort, err)
+		}
+
+		t.listeners = append(t.listeners, l)
+
+		go t.serve(l, f)
+	}
+
+	return nil
+}
+
+// connect opens the SSH connection to the bastion host
+func (t *sshTunnel) connect() error {
+	conn, sessionID, err := t.dial()
+	if err != nil {
+		return fmt.Errorf("can't connect to %s: %w", t.state.BastionHostID, err)
+	}
+
+	// the connection has no deadlines, so the handshake is interrupted by closing it
+	timer := time.AfterFunc(tunnelTimeout, func() {
+		_ = conn.Close()
+	})
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, net.JoinHostPort(t.state.BastionHostID, "22"), t.config)
+	timer.Stop()
+	if err != nil {
+		_ = conn.Close()
+		if t.terminate != nil && len(sessionID) != 0 {
+			t.terminate(sessionID)
+		}
+		return fmt.Errorf("can't connect to %s: %w", t.state.BastionHostID, err)
+	}
+
+	t.mu.Lock()
+	t.client = ssh.NewClient(c, chans, reqs)
+	t.sessionID = sessionID
+	t.mu.Unlock()
+
+	return nil
+}
+
+// serve forwards connections accepted by the listener until it's closed
+func (t *sshTunnel) serve(l net.Listener, f tunnelForward) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go t.forward(conn, f)
+	}
+}
+
+// forward copies data between the local connection and the remote host until both sides are done
+func (t *sshTunnel) forward(local net.Conn, f tunnelForward) {
+	defer local.Close()
+
+	client := t.currentClient()
+	if client == nil {
+		return
+	}
+
+	remote, err := client.Dial("tcp", net.JoinHostPort(f.Host, strconv.Itoa(f.RemotePort)))
+	if err != nil {
+		t.log.Printf("can't forward %s: %s", f, err)
+		return
+	}
+	defer remote.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	pipe := func(dst, src net.Conn) {
+		defer wg.Done()
+		_, _ = io.Copy(dst, src)
+		if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+			_ = cw.CloseWrite()
+		}
+	}
+
+	go pipe(remote, local)
+	go pipe(local, remote)
+
+	wg.Wait()
+}
+
+// run checks the connection and the forwards every tunnelHealthInterval until ctx is done.
+// A lost connection is re-established with backoff in watch mode, otherwise run returns an error.
+func (t *sshTunnel) run(ctx context.Context, watch bool) error {
+	lost := t.waitConnection()
+	t.checkForwards()
+
+	ticker := time.NewTicker(tunnelHealthInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case err = <-lost:
+			err = fmt.Errorf("connection to %s is lost: %v", t.state.BastionHostID, err)
+		case <-ticker.C:
+			if err = t.keepAlive(); err == nil {
+				t.checkForwards()
+				continue
+			}
+			err = fmt.Errorf("connection to %s is lost: %w", t.state.BastionHostID, err)
+		}
+
+		if !watch {
+			return err
+		}
+
+		t.state.Status = tunnelReconnecting
+		t.state.Error = err.Error()
+		for i := range t.state.Forwards {
+			t.state.Forwards[i].Health = ""
+			t.state.Forwards[i].HealthySince = time.Time{}
+		}
+		t.persist()
+		t.report(false, fmt.Sprintf("Tunnel is down: %s, reconnecting", err))
+
+		if err = t.reconnect(ctx); err != nil {
+			return nil
+		}
+
+		lost = t.waitConnection()
+		t.checkForwards()
+	}
+}
+
+// waitConnection returns the channel receiving the error the current connection is closed with
+func (t *sshTunnel) waitConnection() <-chan error {
+	client := t.currentClient()
+
+	lost := make(chan error, 1)
+	go func() {
+		lost <- client.Wait()
+	}()
+
+	return lost
+}
+
+// keepAlive checks that the bastion host responds. A connection broken by sleep may hang, so the check has a timeout.
+func (t *sshTunnel) keepAlive() error {
+	client := t.currentClient()
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("keepalive failed: %w", err)
+		}
+		return nil
+	case <-time.After(tunnelHealthTimeout):
+		return fmt.Errorf("keepalive timed out after %s", tunnelHealthTimeout)
+	}
+}
+
+// reconnect re-establishes the connection with exponential backoff until it succeeds or ctx is done
+func (t *sshTunnel) reconnect(ctx context.Context) error {
+	t.disconnect()
+
+	backoff := tunnelMinBackoff
+
+	for attempt := 1; ; attempt++ {
+		var err error
+		if t.refreshKey != nil {
+			err = t.refreshKey()
+		}
+		if err == nil {
+			err = t.connect()
+		}
+		if err == nil {
+			t.state.Status = tunnelUp
+			t.state.Error = ""
+			t.state.SessionID = t.sessionID
+			t.state.ConnectedAt = time.Now()
+			t.state.Reconnects++
+			t.persist()
+			t.report(true, fmt.Sprintf("Reconnected to %s", t.state.BastionHostID))
+			return nil
+		}
+
+		t.report(false, fmt.Sprintf("Reconnect attempt %d failed: %s, retrying in %s", attempt, err, backoff))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > tunnelMaxBackoff {
+			backoff = tunnelMaxBackoff
+		}
+	}
+}
+
+// checkForwards checks every forward, reports health changes and saves the results
+func (t *sshTunnel) checkForwards() {
+	for i := range t.state.Forwards {
+		f := &t.state.Forwards[i]
+
+		err := t.checkForward(*f)
+		now := time.Now()
+		f.CheckedAt = now
+
+		switch {
+		case err == nil:
+			if f.Health != forwardHealthy {
+				f.HealthySince = now
+				t.report(true, fmt.Sprintf("%s is healthy", f))
+			}
+			f.Health = forwardHealthy
+			f.Error = ""
+		default:
+			if f.Health != forwardUnhealthy {
+				t.report(false, fmt.Sprintf("%s is unhealthy: %s", f, err))
+			}
+			f.Health = forwardUnhealthy
+			f.HealthySince = time.Time{}
+			f.Error = err.Error()
+		}
+	}
+
+	t.persist()
+}
+
+// checkForward dials the local port and the remote host through the bastion host
+func (t *sshTunnel) checkForward(f tunnelForward) error {
+	local, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", f.LocalPort), tunnelHealthTimeout)
+	if err != nil {
+		return fmt.Errorf("local port %d doesn't accept connections: %w", f.LocalPort, err)
+	}
+	_ = local.Close()
+
+	client := t.currentClient()
+	if client == nil {
+		return fmt.Errorf("not connected to %s", t.state.BastionHostID)
+	}
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		conn, err := client.Dial("tcp", net.JoinHostPort(f.Host, strconv.Itoa(f.RemotePort)))
+		done <- result{conn, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return fmt.Errorf("can't connect to %s:%d: %w", f.Host, f.RemotePort, r.err)
+		}
+		return r.conn.Close()
+	case <-time.After(tunnelHealthTimeout):
+		go func() {
+			if r := <-done; r.conn != nil {
+				_ = r.conn.Close()
+			}
+		}()
+		return fmt.Errorf("connection to %s:%d timed out after %s", f.Host, f.RemotePort, tunnelHealthTimeout)
+	}
+}
+
+func (t *sshTunnel) currentClient() *ssh.Client {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.client
+}
+
+func (t *sshTunnel) report(ok bool, msg string) {
+	if t.notify != nil {
+		t.notify(ok, msg)
+	}
+}
+
+func (t *sshTunnel) persist() {
+	if t.save != nil {
+		t.save()
+	}
+}
+
+// close stops listening, closes the connection and terminates the SSM session
+func (t *sshTunnel) close() {
+	for _, l := range t.listeners {
+		_ = l.Close()
+	}
+	t.listeners = nil
+
+	t.disconnect()
+}
+
+// disconnect closes the connection and terminates the SSM session, the local ports keep listening
+func (t *sshTunnel) disconnect() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client != nil {
+		_ = t.client.Close()
+		t.client = nil
+	}
+
+	if t.terminate != nil && len(t.sessionID) != 0 {
+		t.terminate(t.sessionID)
+	}
+	t.sessionID = ""
+}
+
+// tunnelAuth returns the private key and ssh-agent auth methods. The agent is used when the key can't be read, e.g. it has a passphrase.
+func tunnelAuth(privateKeyFile string) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+
+	b, keyErr := os.ReadFile(privateKeyFile)
+	if keyErr == nil {
+		var signer ssh.Signer
+		signer, keyErr = ssh.ParsePrivateKey(b)
+		if keyErr == nil {
+			methods = append(methods, ssh.PublicKeys(signer))
+		}
+	}
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); len(sock) != 0 {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("can't read private key %s: %w", privateKeyFile, keyErr)
+	}
+
+	return methods, nil
+}
+
+// tunnelHostKeyCallback checks host keys against ~/.ssh/known_hosts with strict host key checking and accepts any host key otherwise
+func tunnelHostKeyCallback(strict bool) (ssh.HostKeyCallback, error) {
+	if !strict {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("can't read known hosts: %w", err)
+	}
+
+	callback, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("can't read known hosts: %w", err)
+	}
+
+	return callback, nil
+}
+
+// startTunnelDaemon starts ize tunnel daemon of the profile in the background and waits until the tunnel is up
+func startTunnelDaemon(project *config.Project, profile string) (*tunnelState, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("can't start tunnel daemon: %w", err)
+	}
+
+	logPath := filepath.Join(project.EnvDir, fmt.Sprintf("tunnel-%s.log", profile))
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("can't open tunnel log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "tunnel", "daemon", profile)
+	cmd.Env = append(os.Environ(), tunnelDaemonEnv(project)...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachProcess(cmd)
+
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("can't start tunnel daemon: %w", err)
+	}
+
+	state, err := waitForTunnel(cmd, project.EnvDir, profile, tunnelTimeout)
+	if err != nil {
+		_ = terminateProcess(cmd.Process.Pid)
+		return nil, fmt.Errorf("%w, see %s", err, logPath)
+	}
+
+	return state, nil
+}
+
+// tunnelDaemonEnv returns the environment selecting the same project and AWS settings in the daemon
+func tunnelDaemonEnv(project *config.Project) []string {
+	env := []string{
+		"ENV=" + project.Env,
+		"AWS_REGION=" + project.AwsRegion,
+		"NAMESPACE=" + project.Namespace,
+	}
+
+	if len(project.AwsProfile) != 0 {
+		env = append(env, "AWS_PROFILE="+project.AwsProfile)
+	}
+
+	if f := viper.GetString("config_file"); len(f) != 0 {
+		env = append(env, "IZE_CONFIG_FILE="+f)
+	}
+
+	return env
+}
+
+// waitForTunnel waits until the daemon reports that the tunnel is up, fails or exits
+func waitForTunnel(cmd *exec.Cmd, dir, profile string, timeout time.Duration) (*tunnelState, error) {
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for {
+		state, err := readTunnelState(dir, profile)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case state == nil:
+			return nil, fmt.Errorf("tunnel state was removed")
+		case state.Status == tunnelUp:
+			return state, nil
+		case state.Status == tunnelFailed:
+			return nil, fmt.Errorf("%s", state.Error)
+		}
+
+		select {
+		case err := <-exited:
+			// the daemon saves the reason it failed before exiting
+			if state, _ := readTunnelState(dir, profile); state != nil && state.Status == tunnelFailed {
+				return nil, fmt.Errorf("%s", state.Error)
+			}
+			return nil, fmt.Errorf("tunnel daemon exited: %v", err)
+		case <-ctx.Done():
+			return nil, fmt.Errorf("tunnel isn't up after %s", timeout)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// parseTunnelForwards parses forward hosts in host:port:localport format
+func parseTunnelForwards(forwardHost []string) ([]tunnelForward, error) {
+	var forwards []tunnelForward
+
+	for _, h := range forwardHost {
+		ss := strings.Split(h, ":")
+		if len(ss) != 3 {
+			return nil, fmt.Errorf("invalid format for forward host %s (should be host:port:localport)", h)
+		}
+
+		remotePort, err := parsePort(ss[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid forward host %s: %w", h, err)
+		}
+
+		localPort, err := parsePort(ss[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid forward host %s: %w", h, err)
+		}
+
+		forwards = append(forwards, tunnelForward{
+			Host:       ss[0],
+			RemotePort: remotePort,
+			LocalPort:  localPort,
+		})
+	}
+
+	return forwards, nil
+}
+
+func printTunnelForwards(forwards []tunnelForward) {
+	for _, f := range forwards {
+		pterm.Println(f)
+	}
+}
+
+// tunnelTitle returns Tunnel for the default profile and Tunnel <profile> for named ones
+func tunnelTitle(profile string) string {
+	if len(profile) == 0 || profile == tunnelDefaultProfile {
+		return "Tunnel"
+	}
+
+	return fmt.Sprintf("Tunnel %s", profile)
+}
+
+func tunnelStatePath(dir, profile string) string {
+	return filepath.Join(dir, fmt.Sprintf("tunnel-%s.json", profile))
+}
+
+// readTunnelState returns the saved tunnel profile or nil
+func readTunnelState(dir, profile string) (*tunnelState, error) {
+	b, err := os.ReadFile(tunnelStatePath(dir, profile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read tunnel state: %w", err)
+	}
+
+	state := &tunnelState{}
+	if err = json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("can't read tunnel state: %w", err)
+	}
+
+	if len(state.Profile) == 0 {
+		state.Profile = profile
+	}
+
+	return state, nil
+}
+
+// readTunnelStates returns saved tunnels of all profiles sorted by profile
+func readTunnelStates(dir string) ([]*tunnelState, error) {
+	paths, err := filepath.Glob(tunnelStatePath(dir, "*"))
+	if err != nil {
+		return nil, fmt.Errorf("can't read tunnel state: %w", err)
+	}
+
+	sort.Strings(paths)
+
+	var states []*tunnelState
+
+	for _, p := range paths {
+		profile := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(p), "tunnel-"), ".json")
+
+		state, err := readTunnelState(dir, profile)
+		if err != nil {
+			return nil, err
+		}
+
+		if state != nil {
+			states = append(states, state)
+		}
+	}
+
+	return states, nil
+}
+
+// writeTunnelState saves the state atomically, so that readers never see a partially written file
+func writeTunnelState(dir string, state *tunnelState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't write tunnel state: %w", err)
+	}
+
+	path := tunnelStatePath(dir, state.Profile)
+
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, b, 0600); err != nil {
+		return fmt.Errorf("can't write tunnel state: %w", err)
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("can't write tunnel state: %w", err)
+	}
+
+	return nil
+}
+
+func removeTunnelState(dir, profile string) error {
+	err := os.Remove(tunnelStatePath(dir, profile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("can't remove tunnel state: %w", err)
+	}
+
+	return nil
+}
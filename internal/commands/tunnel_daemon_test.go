@@ -0,0 +1,493 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hazelops/ize/internal/config"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseTunnelForwards(t *testing.T) {
+	tests := []struct {
+		name        string
+		forwardHost []string
+		want        []tunnelForward
+		wantErr     bool
+	}{
+		{name: "success", forwardHost: []string{"db.internal:5432:15432", "10.0.0.1:6379:16379"}, want: []tunnelForward{
+			{Host: "db.internal", RemotePort: 5432, LocalPort: 15432},
+			{Host: "10.0.0.1", RemotePort: 6379, LocalPort: 16379},
+		}},
+		{name: "no local port", forwardHost: []string{"db.internal:5432"}, wantErr: true},
+		{name: "invalid remote port", forwardHost: []string{"db.internal:postgres:15432"}, wantErr: true},
+		{name: "invalid local port", forwardHost: []string{"db.internal:5432:0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTunnelForwards(tt.forwardHost)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTunnelForwards() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTunnelForwards() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTunnel(t *testing.T) {
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Skip("true is not available")
+	}
+
+	tests := []struct {
+		name          string
+		state         *tunnelState
+		want          bool
+		wantErr       bool
+		wantStateFile bool
+	}{
+		{name: "no tunnel"},
+		{name: "up", state: &tunnelState{Profile: tunnelDefaultProfile, PID: os.Getpid(), Status: tunnelUp}, want: true, wantStateFile: true},
+		{name: "starting", state: &tunnelState{Profile: tunnelDefaultProfile, PID: os.Getpid(), Status: tunnelStarting}, wantErr: true, wantStateFile: true},
+		{name: "stale", state: &tunnelState{Profile: tunnelDefaultProfile, PID: exited.Process.Pid, Status: tunnelUp}},
+		{name: "other profile", state: &tunnelState{Profile: "db", PID: os.Getpid(), Status: tunnelUp}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			if tt.state != nil {
+				if err := writeTunnelState(dir, tt.state); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := checkTunnel(dir, tunnelDefaultProfile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkTunnel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("checkTunnel() = %v, want %v", got, tt.want)
+			}
+
+			_, err = os.Stat(tunnelStatePath(dir, tunnelDefaultProfile))
+			if os.IsNotExist(err) == tt.wantStateFile {
+				t.Errorf("checkTunnel(): state file exists = %v, want %v", !os.IsNotExist(err), tt.wantStateFile)
+			}
+		})
+	}
+}
+
+func TestTunnelProfiles(t *testing.T) {
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Skip("true is not available")
+	}
+
+	// the pid of a daemon which has exited is reused by another program
+	reused := exec.Command("sleep", "60")
+	if err := reused.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = reused.Process.Kill()
+		_ = reused.Wait()
+	}()
+
+	dir := t.TempDir()
+
+	states := []*tunnelState{
+		{Profile: "db", PID: os.Getpid(), Status: tunnelUp, Forwards: []tunnelForward{{Host: "db.local", RemotePort: 5432, LocalPort: 35432}}},
+		{Profile: "cache", PID: os.Getpid(), Status: tunnelReconnecting, Forwards: []tunnelForward{{Host: "cache.local", RemotePort: 6379, LocalPort: 36379}}},
+		{Profile: "stale", PID: exited.Process.Pid, Status: tunnelUp, Forwards: []tunnelForward{{Host: "old.local", RemotePort: 80, LocalPort: 30080}}},
+		{Profile: "reused", PID: reused.Process.Pid, Status: tunnelUp, Forwards: []tunnelForward{{Host: "web.local", RemotePort: 80, LocalPort: 30081}}},
+	}
+	for _, s := range states {
+		if err := writeTunnelState(dir, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ports, err := tunnelLocalPorts(dir, "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[int]string{36379: "cache"}; !reflect.DeepEqual(ports, want) {
+		t.Errorf("tunnelLocalPorts() = %v, want %v", ports, want)
+	}
+
+	active, err := activeTunnels(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, s := range active {
+		got = append(got, s.Profile)
+	}
+
+	if want := []string{"cache", "db"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("activeTunnels() = %v, want %v", got, want)
+	}
+
+	for _, profile := range []string{"stale", "reused"} {
+		if _, err = os.Stat(tunnelStatePath(dir, profile)); !os.IsNotExist(err) {
+			t.Errorf("activeTunnels(): %s state file exists", profile)
+		}
+	}
+
+	if err = writeTunnelState(dir, states[3]); err != nil {
+		t.Fatal(err)
+	}
+
+	o := NewTunnelDownOptions(&config.Project{Env: "testnut", EnvDir: dir})
+	o.Profile = "reused"
+
+	if err = o.Run(); err == nil {
+		t.Errorf("Run() error = nil, want error for tunnel which is not active")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if !processAlive(reused.Process.Pid) {
+		t.Errorf("ize tunnel down terminated process of another program")
+	}
+}
+
+func TestSSHTunnel(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	localPort, err := getFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var serverConn net.Conn
+	var terminated []string
+
+	tunnel := &sshTunnel{
+		state: &tunnelState{
+			BastionHostID: "i-xxxxxxxxxxxxxxxxx",
+			Forwards:      []tunnelForward{{Host: "127.0.0.1", RemotePort: echo.Addr().(*net.TCPAddr).Port, LocalPort: localPort}},
+		},
+		config: &ssh.ClientConfig{
+			User:            bastionUser,
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		},
+		dial: func() (net.Conn, string, error) {
+			client, server, err := tcpConnPair()
+			if err != nil {
+				return nil, "", err
+			}
+			serverConn = server
+			go serveTestSSH(t, server)
+			return client, "session-id", nil
+		},
+		terminate: func(sessionID string) {
+			terminated = append(terminated, sessionID)
+		},
+		log: log.New(io.Discard, "", 0),
+	}
+
+	if err = tunnel.start(); err != nil {
+		t.Fatalf("start() error = %v", err)
+	}
+
+	conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(localPort))
+	if err != nil {
+		t.Fatalf("can't connect to local port: %v", err)
+	}
+
+	if _, err = conn.Write([]byte("ping\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "ping\n" {
+		t.Errorf("forwarded connection read %q, %v, want %q", line, err, "ping\n")
+	}
+	_ = conn.Close()
+
+	lost := make(chan error, 1)
+	go func() {
+		lost <- tunnel.run(context.Background(), false)
+	}()
+
+	_ = serverConn.Close()
+
+	select {
+	case err = <-lost:
+		if err == nil {
+			t.Error("run() error = nil, want lost connection")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run() didn't return after the connection was lost")
+	}
+
+	tunnel.close()
+
+	if !reflect.DeepEqual(terminated, []string{"session-id"}) {
+		t.Errorf("terminated sessions = %v, want [session-id]", terminated)
+	}
+
+	if _, err = net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(localPort)); err == nil {
+		t.Error("local port accepts connections after close()")
+	}
+}
+
+func TestSSHTunnel_watch(t *testing.T) {
+	defer func(interval, backoff time.Duration) {
+		tunnelHealthInterval, tunnelMinBackoff = interval, backoff
+	}(tunnelHealthInterval, tunnelMinBackoff)
+	tunnelHealthInterval, tunnelMinBackoff = 50*time.Millisecond, 10*time.Millisecond
+
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	closedPort, err := getFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var localPorts []int
+	for i := 0; i < 2; i++ {
+		p, err := getFreePort()
+		if err != nil {
+			t.Fatal(err)
+		}
+		localPorts = append(localPorts, p)
+	}
+
+	var serverConns []net.Conn
+	dials := 0
+	reconnected := make(chan struct{}, 1)
+
+	tunnel := &sshTunnel{
+		state: &tunnelState{
+			BastionHostID: "i-xxxxxxxxxxxxxxxxx",
+			Forwards: []tunnelForward{
+				{Host: "127.0.0.1", RemotePort: echo.Addr().(*net.TCPAddr).Port, LocalPort: localPorts[0]},
+				{Host: "127.0.0.1", RemotePort: closedPort, LocalPort: localPorts[1]},
+			},
+		},
+		config: &ssh.ClientConfig{
+			User:            bastionUser,
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		},
+		dial: func() (net.Conn, string, error) {
+			dials++
+			// the first reconnect attempt fails
+			if dials == 2 {
+				return nil, "", io.ErrUnexpectedEOF
+			}
+
+			client, server, err := tcpConnPair()
+			if err != nil {
+				return nil, "", err
+			}
+			serverConns = append(serverConns, server)
+			go serveTestSSH(t, server)
+			return client, "session-" + strconv.Itoa(dials), nil
+		},
+		notify: func(ok bool, msg string) {
+			if ok && msg == "Reconnected to i-xxxxxxxxxxxxxxxxx" {
+				reconnected <- struct{}{}
+			}
+		},
+		log: log.New(io.Discard, "", 0),
+	}
+
+	if err = tunnel.start(); err != nil {
+		t.Fatalf("start() error = %v", err)
+	}
+	defer tunnel.close()
+
+	tunnel.checkForwards()
+
+	if got := tunnel.state.Forwards[0].Health; got != forwardHealthy {
+		t.Errorf("health of %s = %s, want %s", tunnel.state.Forwards[0], got, forwardHealthy)
+	}
+
+	if got := tunnel.state.Forwards[1].Health; got != forwardUnhealthy {
+		t.Errorf("health of %s = %s, want %s", tunnel.state.Forwards[1], got, forwardUnhealthy)
+	}
+
+	first := serverConns[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- tunnel.run(ctx, true)
+	}()
+
+	_ = first.Close()
+
+	select {
+	case <-reconnected:
+	case err = <-done:
+		t.Fatalf("run() returned %v, want reconnect", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("tunnel didn't reconnect after the connection was lost")
+	}
+
+	cancel()
+	if err = <-done; err != nil {
+		t.Errorf("run() error = %v, want nil", err)
+	}
+
+	if tunnel.state.Status != tunnelUp || tunnel.state.Reconnects != 1 || tunnel.state.SessionID != "session-3" {
+		t.Errorf("state after reconnect: status %s, reconnects %d, session %s, want up, 1, session-3", tunnel.state.Status, tunnel.state.Reconnects, tunnel.state.SessionID)
+	}
+
+	conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(localPorts[0]))
+	if err != nil {
+		t.Fatalf("can't connect to local port after reconnect: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("ping\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "ping\n" {
+		t.Errorf("forwarded connection read %q, %v after reconnect, want %q", line, err, "ping\n")
+	}
+}
+
+// tcpConnPair returns both ends of a loopback TCP connection, unlike net.Pipe they are buffered
+// and both SSH peers can send their versions at once
+func tcpConnPair() (net.Conn, net.Conn, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer l.Close()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	server, err := l.Accept()
+	if err != nil {
+		_ = client.Close()
+		return nil, nil, err
+	}
+
+	return client, server, nil
+}
+
+// serveTestSSH serves direct-tcpip channels of the SSH connection like sshd of the bastion host
+func serveTestSSH(t *testing.T, conn net.Conn) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for nc := range chans {
+		if nc.ChannelType() != "direct-tcpip" {
+			_ = nc.Reject(ssh.UnknownChannelType, nc.ChannelType())
+			continue
+		}
+
+		var target struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(nc.ExtraData(), &target); err != nil {
+			_ = nc.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			_ = nc.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		ch, chReqs, err := nc.Accept()
+		if err != nil {
+			_ = remote.Close()
+			continue
+		}
+		go ssh.DiscardRequests(chReqs)
+
+		go func() {
+			_, _ = io.Copy(remote, ch)
+			_ = remote.Close()
+		}()
+		go func() {
+			_, _ = io.Copy(ch, remote)
+			_ = ch.Close()
+		}()
+	}
+}
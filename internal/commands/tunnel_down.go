@@ -1,11 +1,9 @@
 package commands
 
 import (
-	"bytes"
 	"fmt"
-	"io/fs"
-	"os/exec"
-	"syscall"
+	"strings"
+	"time"
 
 	"github.com/hazelops/ize/internal/config"
 	"github.com/pterm/pterm"
@@ -18,8 +16,13 @@ var explainTunnelDownTmpl = `
 (cd {{.EnvDir}} && $(aws ssm get-parameter --name "/{{.Env}}/terraform-output" --with-decryption | jq -r '.Parameter.Value' | base64 -d | jq -r '.cmd.value.tunnel.down'))
 `
 
+// tunnelStopTimeout is how long ize tunnel down waits for the tunnel daemon to exit
+var tunnelStopTimeout = 10 * time.Second
+
 type TunnelDownOptions struct {
 	Config  *config.Project
+	Profile string
+	All     bool
 	Explain bool
 }
 
@@ -33,9 +36,11 @@ func NewCmdTunnelDown(project *config.Project) *cobra.Command {
 	o := NewTunnelDownOptions(project)
 
 	cmd := &cobra.Command{
-		Use:   "down",
-		Short: "Close tunnel",
-		Long:  "Close tunnel",
+		Use:               "down [flags] [profile]",
+		Short:             "Close tunnel",
+		Long:              "Close tunnel of the profile, the default one if it isn't specified",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: tunnelProfiles(project),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
 
@@ -48,7 +53,7 @@ func NewCmdTunnelDown(project *config.Project) *cobra.Command {
 				return nil
 			}
 
-			err := o.Complete()
+			err := o.Complete(args)
 			if err != nil {
 				return err
 			}
@@ -67,12 +72,18 @@ func NewCmdTunnelDown(project *config.Project) *cobra.Command {
 		},
 	}
 
+	cmd.Flags().BoolVar(&o.All, "all", false, "close tunnels of all profiles")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
 
 	return cmd
 }
 
-func (o *TunnelDownOptions) Complete() error {
+func (o *TunnelDownOptions) Complete(args []string) error {
+	o.Profile = tunnelDefaultProfile
+	if len(args) != 0 {
+		o.Profile = args[0]
+	}
+
 	return nil
 }
 
@@ -81,39 +92,109 @@ func (o *TunnelDownOptions) Validate() error {
 		return fmt.Errorf("env must be specified")
 	}
 
+	if o.All && o.Profile != tunnelDefaultProfile {
+		return fmt.Errorf("--all can't be used with a tunnel profile")
+	}
+
 	return nil
 }
 
 func (o *TunnelDownOptions) Run() error {
-	c := exec.Command(
-		"ssh", "-S", "bastion.sock", "-O", "exit", "",
-	)
+	if !o.All {
+		return o.down(o.Profile)
+	}
+
+	states, err := readTunnelStates(o.Config.EnvDir)
+	if err != nil {
+		return fmt.Errorf("unable to bring tunnels down: %w", err)
+	}
+
+	if len(states) == 0 {
+		return fmt.Errorf("unable to bring tunnels down: no tunnel is active")
+	}
 
-	if o.Config.LogLevel == "debug" {
-		c.Args = append(c.Args, "-vvv")
+	for _, state := range states {
+		if err := o.down(state.Profile); err != nil {
+			return err
+		}
 	}
 
-	out := &bytes.Buffer{}
-	c.Stdout = out
-	c.Stderr = out
-	c.Dir = o.Config.EnvDir
+	return nil
+}
 
-	err := c.Run()
+func (o *TunnelDownOptions) down(profile string) error {
+	state, err := readTunnelState(o.Config.EnvDir, profile)
 	if err != nil {
-		patherr, ok := err.(*fs.PathError)
-		if ok {
-			return fmt.Errorf("unable to access folder '%s': %w", c.Dir, patherr.Err)
+		return fmt.Errorf("unable to bring the tunnel down: %w", err)
+	}
+
+	if state == nil || !daemonAlive(state.PID) {
+		if err := removeTunnelState(o.Config.EnvDir, profile); err != nil {
+			return err
 		}
-		exiterr := err.(*exec.ExitError)
-		status := exiterr.Sys().(syscall.WaitStatus)
-		if status.ExitStatus() != 255 {
-			logrus.Debug(out.String())
-			return fmt.Errorf("unable to bring the tunnel down: %w", err)
+
+		if o.All {
+			pterm.Success.Printfln("%s is down!", tunnelTitle(profile))
+			return nil
+		}
+
+		return fmt.Errorf("unable to bring the tunnel down: %s is not active%s", strings.ToLower(tunnelTitle(profile)), o.activeProfiles())
+	}
+
+	if err = stopTunnelDaemon(state.PID, tunnelStopTimeout); err != nil {
+		return fmt.Errorf("unable to bring the tunnel down: %w", err)
+	}
+
+	// the daemon removes the state on exit, but it can't if it was killed
+	if err = removeTunnelState(o.Config.EnvDir, profile); err != nil {
+		return err
+	}
+
+	pterm.Success.Printfln("%s is down!", tunnelTitle(profile))
+
+	return nil
+}
+
+// activeProfiles returns a hint with profiles of running tunnels
+func (o *TunnelDownOptions) activeProfiles() string {
+	states, err := readTunnelStates(o.Config.EnvDir)
+	if err != nil {
+		return ""
+	}
+
+	var profiles []string
+	for _, state := range states {
+		if daemonAlive(state.PID) {
+			profiles = append(profiles, state.Profile)
 		}
-		return fmt.Errorf("unable to bring the tunnel down: tunnel is not active")
 	}
 
-	pterm.Success.Println("Tunnel is down!")
+	if len(profiles) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(", active tunnels: %s", strings.Join(profiles, ", "))
+}
+
+// stopTunnelDaemon terminates the daemon and waits for it to close the connection and exit
+func stopTunnelDaemon(pid int, timeout time.Duration) error {
+	logrus.Debugf("terminating tunnel daemon (pid %d)", pid)
+
+	if !daemonAlive(pid) {
+		return fmt.Errorf("process %d is not ize tunnel daemon, it's not terminated", pid)
+	}
+
+	if err := terminateProcess(pid); err != nil {
+		return fmt.Errorf("can't terminate tunnel daemon (pid %d): %w", pid, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for daemonAlive(pid) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("tunnel daemon (pid %d) is still running after %s", pid, timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
 
 	return nil
 }
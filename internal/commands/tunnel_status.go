@@ -3,6 +3,8 @@ package commands
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/hazelops/ize/internal/config"
 	"github.com/hazelops/ize/pkg/terminal"
@@ -16,6 +18,7 @@ var explainTunnelStatusTmpl = `
 
 type TunnelStatusOptions struct {
 	Config  *config.Project
+	Profile string
 	UI      terminal.UI
 	Explain bool
 }
@@ -30,9 +33,11 @@ func NewCmdTunnelStatus(project *config.Project) *cobra.Command {
 	o := NewTunnelStatusOptions(project)
 
 	cmd := &cobra.Command{
-		Use:   "status",
-		Short: "Tunnel status",
-		Long:  "Tunnel running status",
+		Use:               "status [flags] [profile]",
+		Short:             "Tunnel status",
+		Long:              "Tunnel running status of the profile or of all active profiles if it isn't specified",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: tunnelProfiles(project),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
 
@@ -45,7 +50,7 @@ func NewCmdTunnelStatus(project *config.Project) *cobra.Command {
 				return nil
 			}
 
-			err := o.Complete()
+			err := o.Complete(args)
 			if err != nil {
 				return err
 			}
@@ -69,7 +74,11 @@ func NewCmdTunnelStatus(project *config.Project) *cobra.Command {
 	return cmd
 }
 
-func (o *TunnelStatusOptions) Complete() error {
+func (o *TunnelStatusOptions) Complete(args []string) error {
+	if len(args) != 0 {
+		o.Profile = args[0]
+	}
+
 	o.UI = terminal.ConsoleUI(context.Background(), o.Config.PlainText)
 
 	return nil
@@ -84,18 +93,92 @@ func (o *TunnelStatusOptions) Validate() error {
 }
 
 func (o *TunnelStatusOptions) Run() error {
+	var states []*tunnelState
+
+	if len(o.Profile) != 0 {
+		state, err := activeTunnel(o.Config.EnvDir, o.Profile)
+		if err != nil {
+			return fmt.Errorf("can't get tunnel status: %w", err)
+		}
+
+		if state == nil {
+			return fmt.Errorf("can't get tunnel status: %s is down", strings.ToLower(tunnelTitle(o.Profile)))
+		}
+
+		states = append(states, state)
+	} else {
+		var err error
+		states, err = activeTunnels(o.Config.EnvDir)
+		if err != nil {
+			return fmt.Errorf("can't get tunnel status: %w", err)
+		}
+
+		if len(states) == 0 {
+			return fmt.Errorf("can't get tunnel status: tunnel is down")
+		}
+	}
+
+	for _, state := range states {
+		o.printStatus(state)
+	}
+
+	return nil
+}
+
+func (o *TunnelStatusOptions) printStatus(state *tunnelState) {
 	ui := o.UI
-	sg := ui.StepGroup()
-	defer sg.Wait()
+	now := time.Now()
+	title := tunnelTitle(state.Profile)
 
-	isUp, err := checkTunnel(o.Config.EnvDir)
-	if err != nil {
-		return fmt.Errorf("can't get tunnel status: %w", err)
+	if state.Status == tunnelReconnecting {
+		ui.Output("%s is reconnecting: %s", title, state.Error, terminal.WithWarningStyle())
+	} else {
+		ui.Output("%s is up. Forwarding config:", title, terminal.WithSuccessStyle())
 	}
 
-	if !isUp {
-		return fmt.Errorf("can't get tunnel status: tunnel is down")
+	mode := "background"
+	if state.Watch {
+		mode = "watch"
 	}
 
-	return nil
+	ui.Output("Bastion: %s, pid: %d, mode: %s, started %s ago, connected for %s, reconnects: %d",
+		state.BastionHostID, state.PID, mode, formatUptime(state.StartedAt, now), formatUptime(state.ConnectedAt, now), state.Reconnects)
+
+	t := terminal.NewTable("Forward", "Health", "Uptime", "Checked", "Error")
+
+	for _, f := range state.Forwards {
+		health, color := f.Health, terminal.Green
+		switch {
+		case state.Status == tunnelReconnecting:
+			health, color = tunnelReconnecting, terminal.Yellow
+		case f.Health == forwardUnhealthy:
+			color = terminal.Red
+		case len(f.Health) == 0:
+			health, color = "unknown", terminal.Yellow
+		}
+
+		checked := "-"
+		if !f.CheckedAt.IsZero() {
+			checked = formatUptime(f.CheckedAt, now) + " ago"
+		}
+
+		t.Rich([]string{
+			f.String(),
+			health,
+			formatUptime(f.HealthySince, now),
+			checked,
+			f.Error,
+		}, []string{"", color, "", "", ""})
+	}
+
+	ui.Table(t)
+}
+
+// formatUptime returns the time passed since t rounded to seconds or - if t is zero
+func formatUptime(t time.Time, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return now.Sub(t).Round(time.Second).String()
 }
@@ -1,7 +1,7 @@
 package commands
 
 import (
-	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -11,11 +11,12 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
-	"text/template"
+	"syscall"
 
 	"github.com/Masterminds/semver"
 	"github.com/aws/aws-sdk-go/aws"
@@ -25,6 +26,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 	"github.com/hazelops/ize/internal/config"
 	"github.com/hazelops/ize/internal/requirements"
+	"github.com/hazelops/ize/pkg/templates"
 	"github.com/hazelops/ize/pkg/term"
 	"github.com/pterm/pterm"
 	"github.com/sirupsen/logrus"
@@ -33,15 +35,6 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 )
 
-const sshConfig = `# SSH over Session Manager
-host i-* mi-*
-ServerAliveInterval 180
-ProxyCommand sh -c "aws ssm start-session --target %h --document-name AWS-StartSSHSession --parameters 'portNumber=%p'"
-
-{{range $k :=  .}}LocalForward {{$k}}
-{{end}}
-`
-
 var explainTunnelUpTmpl = `
 # Set variables
 SSH_CONFIG={{.EnvDir}}/ssh.config
@@ -62,15 +55,32 @@ aws ssm send-command --instance-ids $BASTION_INSTANCE_ID --document-name AWS-Run
 
 type TunnelUpOptions struct {
 	Config                *config.Project
+	Profile               string
 	PrivateKeyFile        string
 	PublicKeyFile         string
 	BastionHostID         string
 	ForwardHost           []string
 	StrictHostKeyChecking bool
 	Metadata              bool
+	Watch                 bool
 	Explain               bool
 }
 
+var tunnelUpExample = templates.Examples(`
+	# Open the tunnel set in [infra.tunnel] or in terraform output
+	ize tunnel up
+
+	# Open tunnels of profiles set as [infra.tunnel.db] and [infra.tunnel.cache] side by side
+	ize tunnel up db
+	ize tunnel up cache
+
+	# Keep the tunnel in the foreground and reconnect when the connection is lost
+	ize tunnel up db --watch
+`)
+
+// tunnelProfileName is the format of tunnel profile names, they are used in state file names
+var tunnelProfileName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
 func NewTunnelUpFlags(project *config.Project) *TunnelUpOptions {
 	return &TunnelUpOptions{
 		Config: project,
@@ -81,9 +91,12 @@ func NewCmdTunnelUp(project *config.Project) *cobra.Command {
 	o := NewTunnelUpFlags(project)
 
 	cmd := &cobra.Command{
-		Use:   "up",
-		Short: "Open tunnel with sending ssh key",
-		Long:  "Open tunnel with sending ssh key to remote server",
+		Use:               "up [flags] [profile]",
+		Short:             "Open tunnel with sending ssh key",
+		Long:              "Open tunnel with sending ssh key to remote server. Named tunnel profiles set as [infra.tunnel.<name>] run side by side.",
+		Example:           tunnelUpExample,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: tunnelProfiles(project),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
 
@@ -96,7 +109,7 @@ func NewCmdTunnelUp(project *config.Project) *cobra.Command {
 				return nil
 			}
 
-			err := o.Complete()
+			err := o.Complete(args)
 			if err != nil {
 				return err
 			}
@@ -121,17 +134,27 @@ func NewCmdTunnelUp(project *config.Project) *cobra.Command {
 	cmd.Flags().StringVar(&o.PrivateKeyFile, "ssh-private-key", "", "set ssh key private path")
 	cmd.PersistentFlags().BoolVar(&o.StrictHostKeyChecking, "strict-host-key-checking", false, "set strict host key checking")
 	cmd.PersistentFlags().BoolVar(&o.Metadata, "use-ec2-metadata", false, "send ssh key to EC2 metadata (work only for Ubuntu versions > 20.0)")
+	cmd.Flags().BoolVar(&o.Watch, "watch", false, "keep the tunnel in the foreground, check forwards periodically and reconnect when the connection is lost")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
 
 	return cmd
 }
 
-func (o *TunnelUpOptions) Complete() error {
+func (o *TunnelUpOptions) Complete(args []string) error {
+	o.Profile = tunnelDefaultProfile
+	if len(args) != 0 {
+		o.Profile = args[0]
+	}
+
+	if !tunnelProfileName.MatchString(o.Profile) {
+		return fmt.Errorf("can't load options for a command: invalid tunnel profile name %s", o.Profile)
+	}
+
 	if err := requirements.CheckRequirements(requirements.WithSSMPlugin()); err != nil {
 		return err
 	}
 
-	isUp, err := checkTunnel(o.Config.EnvDir)
+	isUp, err := checkTunnel(o.Config.EnvDir, o.Profile)
 	if err != nil {
 		return fmt.Errorf("can't run tunnel up: %w", err)
 	}
@@ -139,12 +162,17 @@ func (o *TunnelUpOptions) Complete() error {
 		os.Exit(0)
 	}
 
-	if o.PrivateKeyFile == "" && o.Config.Tunnel != nil {
-		o.PrivateKeyFile = o.Config.Tunnel.SSHPrivateKey
+	tunnel, err := o.tunnelConfig()
+	if err != nil {
+		return fmt.Errorf("can't load options for a command: %w", err)
+	}
+
+	if o.PrivateKeyFile == "" && tunnel != nil {
+		o.PrivateKeyFile = tunnel.SSHPrivateKey
 	}
 
-	if o.PublicKeyFile == "" && o.Config.Tunnel != nil {
-		o.PublicKeyFile = o.Config.Tunnel.SSHPublicKey
+	if o.PublicKeyFile == "" && tunnel != nil {
+		o.PublicKeyFile = tunnel.SSHPublicKey
 	}
 
 	if o.PrivateKeyFile == "" {
@@ -166,16 +194,20 @@ func (o *TunnelUpOptions) Complete() error {
 	}
 
 	if len(o.BastionHostID) == 0 && len(o.ForwardHost) == 0 {
-		if o.Config.Tunnel != nil {
-			o.ForwardHost = o.Config.Tunnel.ForwardHost
-			o.BastionHostID = o.Config.Tunnel.BastionInstanceID
+		if tunnel != nil {
+			o.ForwardHost = tunnel.ForwardHost
+			o.BastionHostID = tunnel.BastionInstanceID
 		}
 	}
 
-	if len(o.BastionHostID) == 0 && len(o.ForwardHost) == 0 {
-		wr := new(SSMWrapper)
-		wr.Api = ssm.New(o.Config.Session)
-		bastionHostID, forwardHost, err := writeSSHConfigFromSSM(wr, o.Config.Env, o.Config.EnvDir)
+	wr := new(SSMWrapper)
+	wr.Api = ssm.New(o.Config.Session)
+
+	switch {
+	case len(o.ForwardHost) == 0 && o.Profile != tunnelDefaultProfile:
+		return fmt.Errorf("can't load options for a command: forward_host of tunnel profile %s must be specified", o.Profile)
+	case len(o.ForwardHost) == 0:
+		bastionHostID, forwardHost, err := forwardHostsFromSSM(wr, o.Config.Env)
 		if err != nil {
 			return err
 		}
@@ -183,25 +215,67 @@ func (o *TunnelUpOptions) Complete() error {
 		o.BastionHostID = bastionHostID
 		o.ForwardHost = forwardHost
 		pterm.Success.Println("Tunnel forwarding configuration obtained from SSM")
-	} else {
-		err := writeSSHConfigFromConfig(o.ForwardHost, o.Config.EnvDir)
+	default:
+		// neither the profile nor [infra.tunnel] set the bastion host, the one of the environment is used
+		if len(o.BastionHostID) == 0 {
+			to, err := getTerraformOutput(wr, o.Config.Env)
+			if err != nil {
+				return fmt.Errorf("can't get bastion instance id of tunnel profile %s: %w", o.Profile, err)
+			}
+
+			o.BastionHostID = to.BastionInstanceID.Value
+			if len(o.BastionHostID) == 0 {
+				return fmt.Errorf("can't load options for a command: bastion_instance_id of tunnel profile %s must be specified", o.Profile)
+			}
+		}
+
+		forwardHost, err := parseForwardHosts(o.ForwardHost)
 		if err != nil {
 			return err
 		}
+
+		o.ForwardHost = forwardHost
 		pterm.Success.Println("Tunnel forwarding configuration obtained from the config file")
 	}
 
 	return nil
 }
 
+// tunnelConfig returns the configuration of the profile. The default profile is [infra.tunnel] itself, it may be not set.
+func (o *TunnelUpOptions) tunnelConfig() (*config.Tunnel, error) {
+	if profile, ok := o.Config.Tunnel.Profile(o.Profile); ok {
+		return profile, nil
+	}
+
+	if o.Profile == tunnelDefaultProfile {
+		return o.Config.Tunnel, nil
+	}
+
+	names := o.Config.Tunnel.ProfileNames()
+	if len(names) == 0 {
+		return nil, fmt.Errorf("tunnel profile %s not found, tunnel profiles are set as [infra.tunnel.<name>]", o.Profile)
+	}
+
+	return nil, fmt.Errorf("tunnel profile %s not found, available profiles: %s", o.Profile, strings.Join(names, ", "))
+}
+
 func (o *TunnelUpOptions) Validate() error {
 	if len(o.Config.Env) == 0 {
 		return fmt.Errorf("env must be specified")
 	}
 
+	used, err := tunnelLocalPorts(o.Config.EnvDir, o.Profile)
+	if err != nil {
+		return err
+	}
+
 	for _, h := range o.ForwardHost {
 		p, _ := strconv.Atoi(strings.Split(h, ":")[2])
-		if err := checkPort(p, o.Config.EnvDir); err != nil {
+		if profile, ok := used[p]; ok {
+			return fmt.Errorf("tunnel forwarding config validation failed: local port %d is used by tunnel %s", p, profile)
+		}
+
+		if err := checkPort(p); err != nil {
 			return fmt.Errorf("tunnel forwarding config validation failed: %w", err)
 		}
 	}
@@ -237,13 +311,17 @@ func (o *TunnelUpOptions) Run() error {
 		}
 	}
 
-	forwardConfig, err := o.upTunnel()
+	if o.Watch {
+		return o.watchTunnel()
+	}
+
+	forwards, err := o.upTunnel()
 	if err != nil {
 		return err
 	}
 
-	pterm.Success.Println("Tunnel is up! Forwarded ports:")
-	pterm.Println(forwardConfig)
+	pterm.Success.Printfln("%s is up! Forwarded ports:", tunnelTitle(o.Profile))
+	printTunnelForwards(forwards)
 
 	return nil
 }
@@ -282,64 +360,76 @@ func (o *TunnelUpOptions) checkOsVersion() error {
 	return nil
 }
 
-func (o *TunnelUpOptions) upTunnel() (string, error) {
-	sshConfigPath := fmt.Sprintf("%s/ssh.config", o.Config.EnvDir)
-	logrus.Debugf("ssh config path: %s", sshConfigPath)
+// upTunnel saves the tunnel and starts the daemon serving it in the background
+func (o *TunnelUpOptions) upTunnel() ([]tunnelForward, error) {
+	forwards, err := parseTunnelForwards(o.ForwardHost)
+	if err != nil {
+		return nil, fmt.Errorf("can't run tunnel: %w", err)
+	}
 
-	if err := setAWSCredentials(o.Config.Session); err != nil {
-		return "", fmt.Errorf("can't run tunnel: %w", err)
+	err = writeTunnelState(o.Config.EnvDir, o.tunnelState(forwards))
+	if err != nil {
+		return nil, fmt.Errorf("can't run tunnel: %w", err)
 	}
 
-	args := o.getSSHCommandArgs(sshConfigPath)
+	s, _ := pterm.DefaultSpinner.WithRemoveWhenDone().Start("Starting tunnel...")
 
-	err := o.runSSH(args)
+	state, err := startTunnelDaemon(o.Config, o.Profile)
 	if err != nil {
-		return "", err
+		s.Fail()
+		_ = removeTunnelState(o.Config.EnvDir, o.Profile)
+		return nil, fmt.Errorf("can't run tunnel: %w", err)
 	}
 
-	var forwardConfig string
-	for _, h := range o.ForwardHost {
-		ss := strings.Split(h, ":")
-		forwardConfig += fmt.Sprintf("%s:%s ➡ localhost:%s\n", ss[0], ss[1], ss[2])
-	}
-	return forwardConfig, nil
+	s.Success()
+
+	return state.Forwards, nil
 }
 
-func (o *TunnelUpOptions) runSSH(args []string) error {
-	c := exec.Command("ssh", args...)
+// watchTunnel serves the tunnel in the foreground until it's interrupted. Forwards are checked every
+// tunnelHealthInterval and the connection is re-established with backoff when it's lost.
+func (o *TunnelUpOptions) watchTunnel() error {
+	forwards, err := parseTunnelForwards(o.ForwardHost)
+	if err != nil {
+		return fmt.Errorf("can't run tunnel: %w", err)
+	}
 
-	c.Dir = o.Config.EnvDir
-	os.Setenv("AWS_REGION", o.Config.AwsRegion)
+	state := o.tunnelState(forwards)
+	state.Watch = true
 
-	runner := term.New(term.WithStdin(os.Stdin))
-	_, _, code, err := runner.Run(c)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger := log.New(logrus.StandardLogger().WriterLevel(logrus.DebugLevel), "", 0)
+
+	pterm.Info.Printfln("Watching %s to %s, press Ctrl+C to close it", strings.ToLower(tunnelTitle(o.Profile)), o.BastionHostID)
+
+	err = serveTunnel(ctx, o.Config, state, logger, func(ok bool, msg string) {
+		if ok {
+			pterm.Success.Println(msg)
+		} else {
+			pterm.Warning.Println(msg)
+		}
+	})
 	if err != nil {
-		return err
+		_ = removeTunnelState(o.Config.EnvDir, o.Profile)
+		return fmt.Errorf("can't run tunnel: %w", err)
 	}
 
-	if code != 0 {
-		return fmt.Errorf("exit status: %d", code)
-	}
 	return nil
 }
 
-func (o *TunnelUpOptions) getSSHCommandArgs(sshConfigPath string) []string {
-	args := []string{"-M", "-t", "-S", "bastion.sock", "-fN"}
-	if !o.StrictHostKeyChecking {
-		args = append(args, "-o", "StrictHostKeyChecking=no")
+func (o *TunnelUpOptions) tunnelState(forwards []tunnelForward) *tunnelState {
+	return &tunnelState{
+		Profile:               o.Profile,
+		BastionHostID:         o.BastionHostID,
+		Forwards:              forwards,
+		PrivateKeyFile:        o.PrivateKeyFile,
+		PublicKeyFile:         o.PublicKeyFile,
+		UseEC2Metadata:        o.Metadata,
+		StrictHostKeyChecking: o.StrictHostKeyChecking,
+		Status:                tunnelStarting,
 	}
-	args = append(args, fmt.Sprintf("ubuntu@%s", o.BastionHostID))
-	args = append(args, "-F", sshConfigPath)
-
-	if _, err := os.Stat(o.PrivateKeyFile); !os.IsNotExist(err) {
-		args = append(args, "-i", o.PrivateKeyFile)
-	}
-
-	if o.Config.LogLevel == "debug" {
-		args = append(args, "-vvv")
-	}
-
-	return args
 }
 
 type SSMWrapper struct {
@@ -449,7 +539,7 @@ func getPublicKey(path string) (string, error) {
 }
 
 func getHosts(config string) [][]string {
-	// This regexp reads ssh.conf configuration, so we can display it nicely in the UI
+	// This regexp reads LocalForward lines of the ssh_forward_config terraform output
 	re, err := regexp.Compile(`LocalForward\s(?P<localPort>\d+)\s(?P<remoteHost>.+):(?P<remotePort>\d+)`)
 	if err != nil {
 		log.Fatal(fmt.Errorf("can't get forward config: %w", err))
@@ -463,20 +553,6 @@ func getHosts(config string) [][]string {
 	return hosts
 }
 
-func getSSHConfig(path string) (string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return "", fmt.Errorf("can't get ssh config: %w", err)
-	}
-
-	b, err := io.ReadAll(f)
-	if err != nil {
-		return "", fmt.Errorf("can't get ssh config: %w", err)
-	}
-
-	return string(b), nil
-}
-
 func getFreePort() (int, error) {
 	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
 	if err != nil {
@@ -496,7 +572,7 @@ func getFreePort() (int, error) {
 	return l.Addr().(*net.TCPAddr).Port, nil
 }
 
-func checkPort(port int, dir string) error {
+func checkPort(port int) error {
 	addr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("127.0.0.1:%d", port))
 	if err != nil {
 		return fmt.Errorf("can't check address %s: %w", fmt.Sprintf("127.0.0.1:%d", port), err)
@@ -522,10 +598,6 @@ func checkPort(port int, dir string) error {
 				return fmt.Errorf("can't find process: %w", err)
 			}
 
-			_, err = os.Stat(filepath.Join(dir, "bastion.sock"))
-			if processName == "ssh" && os.IsNotExist(err) {
-				return fmt.Errorf("it could be another ize tunnel, but we can't find a socket. Something went wrong. We suggest terminating it and starting it up again")
-			}
 			isContinue := false
 			if terminal.IsTerminal(int(os.Stdout.Fd())) {
 				isContinue, err = pterm.DefaultInteractiveConfirm.WithDefaultText("Would you like to terminate it?").Show()
@@ -559,150 +631,148 @@ func checkPort(port int, dir string) error {
 	return nil
 }
 
-func writeSSHConfigFromSSM(wr *SSMWrapper, env string, dir string) (string, []string, error) {
-	var bastionHostID string
-	var forwardHost []string
-
+// forwardHostsFromSSM returns the bastion instance and forward hosts of the ssh_forward_config terraform output
+func forwardHostsFromSSM(wr *SSMWrapper, env string) (string, []string, error) {
 	to, err := getTerraformOutput(wr, env)
 	if err != nil {
-		return "", []string{}, fmt.Errorf("can't write SSH config: %w", err)
+		return "", []string{}, fmt.Errorf("can't get forwarding config: %w", err)
 	}
 
-	sshConfigPath := fmt.Sprintf("%s/ssh.config", dir)
+	forwardConfig := strings.Join(to.SSHForwardConfig.Value, "\n")
 
-	f, err := os.Create(sshConfigPath)
-	if err != nil {
-		return "", []string{}, fmt.Errorf("can't write SSH config: %w", err)
-	}
-
-	sshConfig := strings.Join(to.SSHForwardConfig.Value, "\n")
-	_, err = io.WriteString(f, sshConfig)
-	if err != nil {
-		return "", []string{}, fmt.Errorf("can't write SSH config: %w", err)
-	}
-	if err = f.Close(); err != nil {
-		return "", []string{}, fmt.Errorf("can't write SSH config: %w", err)
-	}
-
-	hosts := getHosts(sshConfig)
+	hosts := getHosts(forwardConfig)
 	if len(hosts) == 0 {
-		errMsg := "can't write SSH config: forwarding config is not valid"
+		errMsg := "can't get forwarding config: forwarding config is not valid"
 		if logrus.GetLevel() == logrus.DebugLevel {
-			errMsg += fmt.Sprintf(". Config in SSM: \n%s", sshConfig)
+			errMsg += fmt.Sprintf(". Config in SSM: \n%s", forwardConfig)
 		}
 		return "", []string{}, fmt.Errorf(errMsg)
 	}
 
-	bastionHostID = to.BastionInstanceID.Value
-
+	var forwardHost []string
 	for _, h := range hosts {
 		forwardHost = append(forwardHost, fmt.Sprintf("%s:%s:%s", h[2], h[3], h[1]))
 	}
 
-	return bastionHostID, forwardHost, nil
+	return to.BastionInstanceID.Value, forwardHost, nil
 }
 
-func writeSSHConfigFromConfig(forwardHost []string, dir string) error {
-	sshConfigPath := fmt.Sprintf("%s/ssh.config", dir)
-	f, err := os.Create(sshConfigPath)
-	if err != nil {
-		return fmt.Errorf("can't run tunnel up: %w", err)
-	}
+// parseForwardHosts checks forward hosts set as host:port[:local port] and selects free local ports if they're omitted
+func parseForwardHosts(forwardHost []string) ([]string, error) {
+	var hosts []string
 
-	var tmplData []string
-	for k, v := range forwardHost {
+	for _, v := range forwardHost {
 		ss := strings.Split(v, ":")
 		if len(ss) < 2 || len(ss) > 3 {
-			return fmt.Errorf("can't load options for a command: invalid format for forward host (should be host:port:localport)")
+			return nil, fmt.Errorf("can't load options for a command: invalid format for forward host (should be host:port:localport)")
 		}
+
 		if len(ss) == 2 {
 			p, err := getFreePort()
 			if err != nil {
-				return fmt.Errorf("can't load options for a command: %w", err)
+				return nil, fmt.Errorf("can't load options for a command: %w", err)
 			}
-			forwardHost[k] = forwardHost[k] + ":" + strconv.Itoa(p)
-			ss = append(ss, strconv.Itoa(p))
+			v = v + ":" + strconv.Itoa(p)
 		} else if len(ss[2]) == 0 {
-			return fmt.Errorf("can't load options for a command: invalid format for forward host (should be host:port:localport)")
+			return nil, fmt.Errorf("can't load options for a command: invalid format for forward host (should be host:port:localport)")
 		}
-		tmplData = append(tmplData, fmt.Sprintf("%s %s:%s", ss[2], ss[0], ss[1]))
+
+		hosts = append(hosts, v)
 	}
-	t := template.New("sshConfig")
-	t, err = t.Parse(sshConfig)
+
+	return hosts, nil
+}
+
+// activeTunnel returns the state of the tunnel profile if the process serving it is up. A stale state is removed.
+func activeTunnel(dir, profile string) (*tunnelState, error) {
+	state, err := readTunnelState(dir, profile)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = t.Execute(f, tmplData)
-	if err != nil {
-		return err
+
+	if state == nil {
+		return nil, nil
 	}
-	if err = f.Close(); err != nil {
-		return fmt.Errorf("can't run tunnel up: %w", err)
+
+	if !state.up() {
+		if daemonAlive(state.PID) {
+			return nil, fmt.Errorf("%s is %s (pid %d), try again later or run ize tunnel down", strings.ToLower(tunnelTitle(profile)), state.Status, state.PID)
+		}
+
+		pterm.Warning.Printfln("%s state file seems to be stale. We have deleted it", tunnelTitle(profile))
+		return nil, removeTunnelState(dir, profile)
 	}
 
-	return nil
+	return state, nil
 }
 
-func checkTunnel(dir string) (bool, error) {
-	pathToSocket := filepath.Join(dir, "bastion.sock")
-	if _, err := os.Stat(pathToSocket); !os.IsNotExist(err) {
-		pterm.Info.Printfln("A socket file from another tunnel has been detected: %s", pathToSocket)
-		c := exec.Command(
-			"ssh", "-S", "bastion.sock", "-O", "check", "",
-		)
-		out := &bytes.Buffer{}
-		c.Stdout = out
-		c.Stderr = out
-		c.Dir = dir
-
-		err := c.Run()
-		if err == nil {
-			sshConfigPath := fmt.Sprintf("%s/ssh.config", dir)
-			sshConfig, err := getSSHConfig(sshConfigPath)
-			if err != nil {
-				return false, fmt.Errorf("can't check tunnel: %w", err)
-			}
+// activeTunnels returns states of all tunnel profiles which are up
+func activeTunnels(dir string) ([]*tunnelState, error) {
+	states, err := readTunnelStates(dir)
+	if err != nil {
+		return nil, err
+	}
 
-			pterm.Success.Println("Tunnel is up. Forwarding config:")
-			hosts := getHosts(sshConfig)
-			var forwardConfig string
-			for _, h := range hosts {
-				forwardConfig += fmt.Sprintf("%s:%s ➡ localhost:%s\n", h[2], h[3], h[1])
-			}
-			pterm.Println(forwardConfig)
+	var active []*tunnelState
 
-			return true, nil
-		} else {
-			pterm.Warning.Println("Tunnel socket file seems to be not useable. We have deleted it")
-			err := os.Remove(pathToSocket)
-			if err != nil {
-				return false, err
-			}
-			return false, nil
+	for _, s := range states {
+		state, err := activeTunnel(dir, s.Profile)
+		if err != nil {
+			return nil, err
+		}
+
+		if state != nil {
+			active = append(active, state)
 		}
 	}
 
-	return false, nil
+	return active, nil
 }
 
-func setAWSCredentials(sess *session.Session) error {
-	v, err := sess.Config.Credentials.Get()
+// tunnelLocalPorts returns local ports of running tunnels of other profiles
+func tunnelLocalPorts(dir, profile string) (map[int]string, error) {
+	states, err := readTunnelStates(dir)
 	if err != nil {
-		return fmt.Errorf("can't set AWS credentials: %w", err)
+		return nil, err
 	}
 
-	err = os.Setenv("AWS_SECRET_ACCESS_KEY", v.SecretAccessKey)
-	if err != nil {
-		return err
+	ports := map[int]string{}
+
+	for _, state := range states {
+		if state.Profile == profile || !daemonAlive(state.PID) {
+			continue
+		}
+
+		for _, f := range state.Forwards {
+			ports[f.LocalPort] = state.Profile
+		}
 	}
-	err = os.Setenv("AWS_ACCESS_KEY_ID", v.AccessKeyID)
-	if err != nil {
-		return err
+
+	return ports, nil
+}
+
+// tunnelProfiles completes names of the tunnel profiles
+func tunnelProfiles(project *config.Project) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		return project.Tunnel.ProfileNames(), cobra.ShellCompDirectiveNoFileComp
 	}
-	err = os.Setenv("AWS_SESSION_TOKEN", v.SessionToken)
-	if err != nil {
-		return err
+}
+
+// checkTunnel reports whether the tunnel profile is up and prints its forwards
+func checkTunnel(dir, profile string) (bool, error) {
+	state, err := activeTunnel(dir, profile)
+	if err != nil || state == nil {
+		return false, err
 	}
 
-	return nil
+	logrus.Debugf("tunnel %s pid: %d, up since %s", profile, state.PID, state.StartedAt)
+
+	pterm.Success.Printfln("%s is up. Forwarding config:", tunnelTitle(profile))
+	printTunnelForwards(state.Forwards)
+
+	return true, nil
 }
@@ -1,12 +1,12 @@
 package commands
 
 import (
-	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -15,94 +15,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
-	"github.com/hazelops/ize/internal/config"
 )
 
-func TestUpOptions_getSSHCommandArgs(t *testing.T) {
-	temp, err := os.MkdirTemp("", "test")
-	if err != nil {
-		t.Error(err)
-		return
-	}
-	type fields struct {
-		Config                *config.Project
-		PrivateKeyFile        string
-		PublicKeyFile         string
-		BastionHostID         string
-		ForwardHost           []string
-		StrictHostKeyChecking bool
-	}
-	type args struct {
-		sshConfigPath string
-	}
-	tests := []struct {
-		name   string
-		fields fields
-		args   args
-		want   []string
-	}{
-		{name: "success",
-			fields: fields{
-				Config:                &config.Project{},
-				PrivateKeyFile:        "",
-				PublicKeyFile:         "",
-				BastionHostID:         "i-xxxxxxxxxxx",
-				ForwardHost:           nil,
-				StrictHostKeyChecking: true,
-			},
-			args: args{sshConfigPath: "./test/ssh.config"},
-			want: []string{"-M", "-t", "-S", "bastion.sock", "-fN", "ubuntu@i-xxxxxxxxxxx", "-F", "./test/ssh.config"},
-		},
-		{name: "success with StrictHostKeyChecking",
-			fields: fields{
-				Config:                &config.Project{},
-				PrivateKeyFile:        "",
-				PublicKeyFile:         "",
-				BastionHostID:         "i-xxxxxxxxxxx",
-				ForwardHost:           nil,
-				StrictHostKeyChecking: false,
-			},
-			args: args{sshConfigPath: "./test/ssh.config"},
-			want: []string{"-M", "-t", "-S", "bastion.sock", "-fN", "-o", "StrictHostKeyChecking=no", "ubuntu@i-xxxxxxxxxxx", "-F", "./test/ssh.config"},
-		},
-		{name: "success with private key file",
-			fields: fields{
-				Config:                &config.Project{},
-				PrivateKeyFile:        fmt.Sprintf("%s/.ssh/id_rsa", temp),
-				PublicKeyFile:         fmt.Sprintf("%s/.ssh/id_rsa.pub", temp),
-				BastionHostID:         "i-XXXXXXXXXXXXXXXXX",
-				ForwardHost:           nil,
-				StrictHostKeyChecking: true,
-			},
-			args: args{sshConfigPath: "./test/ssh.config"},
-			want: []string{"-M", "-t", "-S", "bastion.sock", "-fN", "ubuntu@i-XXXXXXXXXXXXXXXXX", "-F", "./test/ssh.config", "-i", fmt.Sprintf("%s/.ssh/id_rsa", temp)},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			o := &TunnelUpOptions{
-				Config:                tt.fields.Config,
-				PrivateKeyFile:        tt.fields.PrivateKeyFile,
-				PublicKeyFile:         tt.fields.PublicKeyFile,
-				BastionHostID:         tt.fields.BastionHostID,
-				ForwardHost:           tt.fields.ForwardHost,
-				StrictHostKeyChecking: tt.fields.StrictHostKeyChecking,
-			}
-			os.MkdirAll(filepath.Join(temp, ".ssh"), 0777)
-			if len(o.PrivateKeyFile) != 0 {
-				_, err := makeSSHKeyPair(o.PublicKeyFile, o.PrivateKeyFile)
-				if err != nil {
-					t.Error(err)
-					return
-				}
-			}
-			if got := o.getSSHCommandArgs(tt.args.sshConfigPath); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("getSSHCommandArgs() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 type mockSSM struct {
 	ssmiface.SSMAPI
 	response string
@@ -196,74 +110,70 @@ func Test_getTerraformOutput(t *testing.T) {
 	}
 }
 
-func Test_writeSSHConfigFromConfig(t *testing.T) {
-	tmp := os.TempDir()
-
+func Test_parseForwardHosts(t *testing.T) {
 	type args struct {
 		forwardHost []string
-		dir         string
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    string
+		want    []string
 		wantErr bool
 	}{
 		{
 			name: "success",
 			args: args{
 				forwardHost: []string{"11.11.11.11:22:36002", "11.11.11.11:23:36001"},
-				dir:         tmp,
 			},
-			want:    "# SSH over Session Manager\nhost i-* mi-*\nServerAliveInterval 180\nProxyCommand sh -c \"aws ssm start-session --target %h --document-name AWS-StartSSHSession --parameters 'portNumber=%p'\"\n\nLocalForward 36002 11.11.11.11:22\nLocalForward 36001 11.11.11.11:23\n\n",
+			want:    []string{"11.11.11.11:22:36002", "11.11.11.11:23:36001"},
 			wantErr: false,
 		},
 		{
 			name: "incorrect forward host 1",
 			args: args{
 				forwardHost: []string{"11.11.11.11", "11.11.11.11:23:36001"},
-				dir:         tmp,
 			},
-			want:    "",
+			want:    nil,
 			wantErr: true,
 		},
 		{
 			name: "incorrect forward host 2",
 			args: args{
 				forwardHost: []string{"11.11.11.11:22:", "11.11.11.11:23:36001"},
-				dir:         tmp,
 			},
-			want:    "",
+			want:    nil,
 			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := writeSSHConfigFromConfig(tt.args.forwardHost, tt.args.dir); (err != nil) != tt.wantErr {
-				t.Errorf("writeSSHConfigFromConfig() error = %v, wantErr %v", err, tt.wantErr)
-				t.SkipNow()
-			}
-
-			file, err := os.ReadFile(filepath.Join(tmp, "ssh.config"))
-			if err != nil {
-				t.Error("writeSSHConfigFromConfig(): ssh.config not found")
+			got, err := parseForwardHosts(tt.args.forwardHost)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseForwardHosts() error = %v, wantErr %v", err, tt.wantErr)
+				return
 			}
-
-			fmt.Println(string(file))
-			if !reflect.DeepEqual(string(file), tt.want) {
-				t.Errorf("writeSSHConfigFromConfig() = %v, want %v", string(file), tt.want)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseForwardHosts() = %v, want %v", got, tt.want)
 			}
 		})
 	}
-}
 
-func Test_writeSSHConfigFromSSM(t *testing.T) {
-	tmp := os.TempDir()
+	t.Run("free local port", func(t *testing.T) {
+		got, err := parseForwardHosts([]string{"11.11.11.11:22"})
+		if err != nil {
+			t.Fatalf("parseForwardHosts() error = %v", err)
+		}
+		ss := strings.Split(got[0], ":")
+		if len(ss) != 3 || ss[0] != "11.11.11.11" || ss[1] != "22" || len(ss[2]) == 0 {
+			t.Errorf("parseForwardHosts() = %v, want 11.11.11.11:22:<free port>", got)
+		}
+	})
+}
 
+func Test_forwardHostsFromSSM(t *testing.T) {
 	type args struct {
 		wr  *SSMWrapper
 		env string
-		dir string
 	}
 	tests := []struct {
 		name    string
@@ -281,7 +191,6 @@ func Test_writeSSHConfigFromSSM(t *testing.T) {
 					err:      nil,
 				}},
 				env: "test",
-				dir: tmp,
 			},
 			want:    "i-XXXXXXXXXXXXXXXXX",
 			want1:   []string{"test.test.test:80:32084"},
@@ -295,7 +204,6 @@ func Test_writeSSHConfigFromSSM(t *testing.T) {
 					err:    awserr.New(ssm.ErrCodeParameterNotFound, "", nil),
 				}},
 				env: "test",
-				dir: tmp,
 			},
 			want:    "",
 			want1:   []string{},
@@ -304,40 +212,16 @@ func Test_writeSSHConfigFromSSM(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, got1, err := writeSSHConfigFromSSM(tt.args.wr, tt.args.env, tt.args.dir)
+			got, got1, err := forwardHostsFromSSM(tt.args.wr, tt.args.env)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("writeSSHConfigFromSSM() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("forwardHostsFromSSM() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if got != tt.want {
-				t.Errorf("writeSSHConfigFromSSM() got = %v, want %v", got, tt.want)
+				t.Errorf("forwardHostsFromSSM() got = %v, want %v", got, tt.want)
 			}
 			if !reflect.DeepEqual(got1, tt.want1) {
-				t.Errorf("writeSSHConfigFromSSM() got1 = %v, want %v", got1, tt.want1)
-			}
-		})
-	}
-}
-
-func Test_setAWSCredentials(t *testing.T) {
-	type args struct {
-		sess *session.Session
-	}
-	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
-	}{
-		{name: "error", args: args{sess: getSession(true)}, wantErr: true},
-		{name: "success", args: args{sess: getSession(false)}, wantErr: false},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if err := setAWSCredentials(tt.args.sess); (err != nil) != tt.wantErr {
-				t.Errorf("setAWSCredentials() error = %v, wantErr %v", err, tt.wantErr)
-			}
-			if os.Getenv("AWS_SECRET_ACCESS_KEY") == "test" && os.Getenv("AWS_ACCESS_KEY_ID") == "test" {
-				return
+				t.Errorf("forwardHostsFromSSM() got1 = %v, want %v", got1, tt.want1)
 			}
 		})
 	}
@@ -361,94 +245,6 @@ aws_secret_access_key = test
 	return session.Must(session.NewSession(aws.NewConfig().WithCredentials(credentials.NewSharedCredentials(filepath.Join(tmp, "credentials"), "test"))))
 }
 
-//func TestUpOptions_runSSH(t *testing.T) {
-//	type fields struct {
-//		Config                *config.Project
-//		PrivateKeyFile        string
-//		PublicKeyFile         string
-//		BastionHostID         string
-//		ForwardHost           []string
-//		StrictHostKeyChecking bool
-//	}
-//	type args struct {
-//		args []string
-//	}
-//	tests := []struct {
-//		name         string
-//		fields       fields
-//		args         args
-//		wantErr      bool
-//		wantNotFound bool
-//	}{
-//		{
-//			name: "success",
-//			fields: fields{
-//				Config: &config.Project{EnvDir: func() string {
-//					temp, _ := os.MkdirTemp("", "test")
-//					return temp
-//				}()},
-//			},
-//			args: args{args: []string{"-M", "-t", "-S", "bastion.sock", "git@github.com", "-fN", "-o", "StrictHostKeyChecking=no"}},
-//		},
-//		{
-//			name: "not found",
-//			fields: fields{
-//				Config: &config.Project{EnvDir: func() string {
-//					temp, _ := os.MkdirTemp("", "test")
-//					return temp
-//				}()},
-//			},
-//			args:         args{args: []string{"-M", "-t", "-S", "bastion.sock", "git@github.com", "-fN", "-o", "StrictHostKeyChecking=no"}},
-//			wantErr:      false,
-//			wantNotFound: true,
-//		},
-//		{
-//			name: "incorrect host",
-//			fields: fields{
-//				Config: &config.Project{EnvDir: func() string {
-//					temp, _ := os.MkdirTemp("", "test")
-//					return temp
-//				}()},
-//			},
-//			args:         args{args: []string{"-M", "-t", "-S", "bastion.sock", "git@incorrecthost.com", "-fN", "-o", "StrictHostKeyChecking=no"}},
-//			wantErr:      true,
-//			wantNotFound: true,
-//		},
-//	}
-//	for _, tt := range tests {
-//		t.Run(tt.name, func(t *testing.T) {
-//			o := &TunnelUpOptions{
-//				Config:                tt.fields.Config,
-//				PrivateKeyFile:        tt.fields.PrivateKeyFile,
-//				PublicKeyFile:         tt.fields.PublicKeyFile,
-//				BastionHostID:         tt.fields.BastionHostID,
-//				ForwardHost:           tt.fields.ForwardHost,
-//				StrictHostKeyChecking: tt.fields.StrictHostKeyChecking,
-//			}
-//
-//			if err := o.runSSH(tt.args.args); (err != nil) != tt.wantErr {
-//				t.Errorf("runSSH() error = %v, wantErr %v", err, tt.wantErr)
-//				return
-//			}
-//
-//			if tt.wantNotFound {
-//				tt.fields.Config.EnvDir = func() string {
-//					temp, _ := os.MkdirTemp("", "not_found")
-//					return temp
-//				}()
-//			}
-//
-//			_, err := os.Stat(filepath.Join(tt.fields.Config.EnvDir, "bastion.sock"))
-//			if os.IsNotExist(err) != tt.wantNotFound {
-//				t.Error("bastion.sock not found")
-//				return
-//			}
-//
-//			return
-//		})
-//	}
-//}
-
 func Test_getPublicKey(t *testing.T) {
 	tmp, _ := os.MkdirTemp("", "test")
 
@@ -3,6 +3,8 @@ package commands
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/hazelops/ize/internal/config"
@@ -12,6 +14,7 @@ import (
 	"github.com/hazelops/ize/pkg/terminal"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
 )
 
 type UpOptions struct {
@@ -22,7 +25,20 @@ type UpOptions struct {
 	UseYarn          bool
 	AutoApprove      bool
 	Explain          bool
+	Plan             bool
+	Parallelism      int
+	Serial           bool
+	KeepGoing        bool
+	Targets          []string
+	WithDeps         bool
+	WithDependents   bool
 	UI               terminal.UI
+
+	// stacks and apps selected by targets
+	withInfra bool
+	selected  map[string]*interface{}
+	// stacks and apps selected besides the targets
+	expanded []string
 }
 
 type Apps map[string]*interface{}
@@ -41,6 +57,21 @@ var upExample = templates.Examples(`
 	# Deploy app (config file required)
 	ize up <app name>
 
+	# Show what would be changed by deploy all without applying anything
+	ize up --plan
+
+	# Deploy all, processing at most 3 apps at the same time
+	ize up --auto-approve --parallelism 3
+
+	# Deploy all, continuing with independent apps if some of them fail
+	ize up --auto-approve --keep-going
+
+	# Deploy an app together with all apps and stacks it depends on
+	ize up --target <app name> --with-deps --auto-approve
+
+	# Deploy a stack and everything that depends on it
+	ize up --target <stack name> --with-dependents --auto-approve
+
 	# Deploy app with explicitly specified config file
 	ize --config-file (or -c) /path/to/config up <app name>
 
@@ -67,7 +98,7 @@ func NewCmdUp(project *config.Project) *cobra.Command {
 		ValidArgsFunction: config.GetApps,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.SilenceUsage = true
-			if len(args) == 0 && !o.AutoApprove {
+			if len(args) == 0 && len(o.Targets) == 0 && !o.AutoApprove && !o.Plan {
 				pterm.Warning.Println("Please set flag --auto-approve")
 				return nil
 			}
@@ -95,7 +126,14 @@ func NewCmdUp(project *config.Project) *cobra.Command {
 	cmd.Flags().BoolVar(&o.UseYarn, "use-yarn", false, "execute sls commands using yarn")
 	cmd.Flags().BoolVar(&o.SkipGen, "skip-gen", false, "skip generating terraform files")
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
- 
+	cmd.Flags().BoolVar(&o.Plan, "plan", false, "show terraform plans and task definition changes without applying them")
+	cmd.Flags().IntVar(&o.Parallelism, "parallelism", 0, "maximum number of apps processed at the same time (default is max_parallel from ize.toml or no limit)")
+	cmd.Flags().BoolVar(&o.Serial, "serial", false, "process apps one at a time in dependency order")
+	cmd.Flags().BoolVar(&o.KeepGoing, "keep-going", false, "continue with independent apps when an app fails (apps depending on it are skipped)")
+	cmd.Flags().StringSliceVar(&o.Targets, "target", nil, "deploy only these apps and terraform stacks (can be repeated)")
+	cmd.Flags().BoolVar(&o.WithDeps, "with-deps", false, "also deploy everything the targets depend on, including the infra stack (requires --auto-approve)")
+	cmd.Flags().BoolVar(&o.WithDependents, "with-dependents", false, "also deploy everything that depends on the targets (requires --auto-approve)")
+
 	cmd.AddCommand(
 		NewCmdUpInfra(project),
 		NewCmdUpApps(project),
@@ -147,12 +185,90 @@ func (o *UpOptions) Complete(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if len(o.Config.K8s) != 0 {
+		if err := requirements.CheckRequirements(k8sRequirements(o.Config)...); err != nil {
+			return err
+		}
+	}
+
+	if o.Parallelism == 0 {
+		o.Parallelism = o.Config.MaxParallel
+	}
+
+	if o.AppName == "" {
+		err := o.selectTargets()
+		if err != nil {
+			return err
+		}
+	}
+
 	o.UI = terminal.ConsoleUI(context.Background(), o.Config.PlainText)
 
 	return nil
 }
 
+// selectTargets narrows down stacks and apps to the targets expanded with
+// their dependencies or dependents. Without targets everything is selected
+func (o *UpOptions) selectTargets() error {
+	_, o.withInfra = o.Config.Terraform["infra"]
+	o.selected = o.Config.GetStatesAndApps()
+
+	if len(o.Targets) == 0 {
+		return nil
+	}
+
+	var unknown []string
+	for _, t := range o.Targets {
+		if _, ok := o.selected[t]; !ok && !(t == "infra" && o.withInfra) {
+			unknown = append(unknown, t)
+		}
+	}
+
+	if len(unknown) != 0 {
+		return fmt.Errorf("can't select targets: %s not found in ize.toml", strings.Join(unknown, ", "))
+	}
+
+	// infra is deployed before other stacks and apps, so all of them depend on it
+	infraTarget := slices.Contains(o.Targets, "infra")
+	o.withInfra = o.withInfra && (infraTarget || o.WithDeps)
+
+	if !infraTarget || !o.WithDependents {
+		o.selected = manager.Select(o.selected, o.Targets, o.WithDeps, o.WithDependents)
+	}
+
+	o.expanded = nil
+	for name := range o.selected {
+		if !slices.Contains(o.Targets, name) {
+			o.expanded = append(o.expanded, name)
+		}
+	}
+
+	if o.withInfra && !infraTarget {
+		o.expanded = append(o.expanded, "infra")
+	}
+
+	sort.Strings(o.expanded)
+
+	return nil
+}
+
 func (o *UpOptions) Validate() error {
+	if o.Parallelism < 0 {
+		return fmt.Errorf("can't validate options: parallelism must not be negative")
+	}
+
+	if len(o.Targets) != 0 && o.AppName != "" {
+		return fmt.Errorf("can't validate options: --target can't be used with app name")
+	}
+
+	if (o.WithDeps || o.WithDependents) && len(o.Targets) == 0 {
+		return fmt.Errorf("can't validate options: --with-deps and --with-dependents require --target")
+	}
+
+	if len(o.expanded) != 0 && !o.AutoApprove && !o.Plan {
+		return fmt.Errorf("can't validate options: %s would be deployed besides the targets, set flag --auto-approve to continue", strings.Join(o.expanded, ", "))
+	}
+
 	if o.AppName == "" {
 		err := o.validateAll()
 		if err != nil {
@@ -170,8 +286,20 @@ func (o *UpOptions) Validate() error {
 
 func (o *UpOptions) Run() error {
 	ui := o.UI
+
+	if o.Plan {
+		if o.AppName == "" {
+			return planAll(ui, o)
+		}
+
+		return planOne(ui, o)
+	}
+
 	if o.AppName == "" {
-		err := deployAll(ui, o)
+		report := &manager.Report{}
+
+		err := deployAll(ui, o, report)
+		renderSummary(ui, report)
 		if err != nil {
 			return err
 		}
@@ -220,25 +348,29 @@ func (o *UpOptions) validateAll() error {
 	return nil
 }
 
-func deployAll(ui terminal.UI, o *UpOptions) error {
-	if _, ok := o.Config.Terraform["infra"]; ok {
-		err := deployInfra("infra", ui, o.Config, o.SkipGen)
+func deployAll(ui terminal.UI, o *UpOptions, report *manager.Report) error {
+	if o.withInfra {
+		err := report.Run("infra", func() error {
+			return deployInfra("infra", ui, o.Config, o.SkipGen)
+		})
 		if err != nil {
+			report.Skip(graphNames(o.selected)...)
 			return err
 		}
 	}
 
-	err := manager.InDependencyOrder(aws.BackgroundContext(), o.Config.GetStates(), func(c context.Context, name string) error {
-		return deployInfra(name, ui, o.Config, o.SkipGen)
-	})
-	if err != nil {
-		return err
-	}
+	ui.Output("Deploying stacks and apps...", terminal.WithHeaderStyle())
 
-	ui.Output("Deploying apps...", terminal.WithHeaderStyle())
+	// apps are deployed with the profile of infra. It's set before the walker starts,
+	// since apps and stacks are deployed concurrently and share the config
+	if infra, ok := o.Config.Terraform["infra"]; ok {
+		o.Config.AwsProfile = infra.AwsProfile
+	}
 
-	err = manager.InDependencyOrder(aws.BackgroundContext(), o.Config.GetApps(), func(c context.Context, name string) error {
-		o.Config.AwsProfile = o.Config.Terraform["infra"].AwsProfile
+	err := manager.InDependencyOrder(aws.BackgroundContext(), o.selected, func(c context.Context, name string) error {
+		if _, ok := o.Config.Terraform[name]; ok {
+			return deployInfra(name, ui, o.Config, o.SkipGen)
+		}
 
 		err := deployApp(name, ui, o.Config, false)
 		if err != nil {
@@ -246,7 +378,7 @@ func deployAll(ui terminal.UI, o *UpOptions) error {
 		}
 
 		return nil
-	})
+	}, manager.WithParallelism(o.Parallelism), manager.WithSerial(o.Serial), manager.WithKeepGoing(o.KeepGoing), manager.WithReport(report))
 	if err != nil {
 		return err
 	}
@@ -9,6 +9,7 @@ import (
 	"github.com/hazelops/ize/internal/manager"
 	"github.com/hazelops/ize/internal/manager/alias"
 	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/manager/k8s"
 	"github.com/hazelops/ize/internal/manager/serverless"
 	"github.com/hazelops/ize/internal/requirements"
 	"github.com/hazelops/ize/pkg/templates"
@@ -17,9 +18,12 @@ import (
 )
 
 type UpAppsOptions struct {
-	Config  *config.Project
-	UI      terminal.UI
-	Explain bool
+	Config      *config.Project
+	UI          terminal.UI
+	Explain     bool
+	Parallelism int
+	Serial      bool
+	KeepGoing   bool
 }
 
 var upAppsLongDesc = templates.LongDesc(`
@@ -74,6 +78,9 @@ func NewCmdUpApps(project *config.Project) *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&o.Explain, "explain", false, "bash alternative shown")
+	cmd.Flags().IntVar(&o.Parallelism, "parallelism", 0, "maximum number of apps processed at the same time (default is max_parallel from ize.toml or no limit)")
+	cmd.Flags().BoolVar(&o.Serial, "serial", false, "process apps one at a time in dependency order")
+	cmd.Flags().BoolVar(&o.KeepGoing, "keep-going", false, "continue with independent apps when an app fails (apps depending on it are skipped)")
 
 	return cmd
 }
@@ -89,12 +96,26 @@ func (o *UpAppsOptions) Complete() error {
 		}
 	}
 
+	if len(o.Config.K8s) != 0 {
+		if err := requirements.CheckRequirements(k8sRequirements(o.Config)...); err != nil {
+			return err
+		}
+	}
+
+	if o.Parallelism == 0 {
+		o.Parallelism = o.Config.MaxParallel
+	}
+
 	o.UI = terminal.ConsoleUI(context.Background(), o.Config.PlainText)
 
 	return nil
 }
 
 func (o *UpAppsOptions) Validate() error {
+	if o.Parallelism < 0 {
+		return fmt.Errorf("can't validate options: parallelism must not be negative")
+	}
+
 	return nil
 }
 
@@ -102,16 +123,22 @@ func (o *UpAppsOptions) Run() error {
 	ui := o.UI
 	ui.Output("Deploying apps...", terminal.WithHeaderStyle())
 
-	err := manager.InDependencyOrder(aws.BackgroundContext(), o.Config.GetApps(), func(c context.Context, name string) error {
-		o.Config.AwsProfile = o.Config.Terraform["infra"].AwsProfile
+	report := &manager.Report{}
 
+	// apps are deployed concurrently and share the config, so the profile is set once
+	if infra, ok := o.Config.Terraform["infra"]; ok {
+		o.Config.AwsProfile = infra.AwsProfile
+	}
+
+	err := manager.InDependencyOrder(aws.BackgroundContext(), o.Config.GetApps(), func(c context.Context, name string) error {
 		err := deployApp(name, ui, o.Config, false)
 		if err != nil {
 			return err
 		}
 
 		return nil
-	})
+	}, manager.WithParallelism(o.Parallelism), manager.WithSerial(o.Serial), manager.WithKeepGoing(o.KeepGoing), manager.WithReport(report))
+	renderSummary(ui, report)
 	if err != nil {
 		return err
 	}
@@ -137,6 +164,13 @@ func deployApp(name string, ui terminal.UI, cfg *config.Project, isExplain bool)
 			App:     app,
 		}
 	}
+	if app, ok := cfg.K8s[name]; ok {
+		app.Name = name
+		m = &k8s.Manager{
+			Project: cfg,
+			App:     app,
+		}
+	}
 	if app, ok := cfg.Alias[name]; ok {
 		app.Name = name
 		m = &alias.Manager{
@@ -185,3 +219,21 @@ func deployApp(name string, ui terminal.UI, cfg *config.Project, isExplain bool)
 	return nil
 }
 
+// k8sRequirements returns requirements of k8s apps. Manifests are applied through the Kubernetes API,
+// but helm charts are installed by the local helm when the native runtime is used.
+func k8sRequirements(project *config.Project) []requirements.Option {
+	var options []requirements.Option
+
+	if project.PreferRuntime != "native" {
+		return options
+	}
+
+	for _, app := range project.K8s {
+		if len(app.Chart) != 0 {
+			options = append(options, requirements.WithHelm())
+			break
+		}
+	}
+
+	return options
+}
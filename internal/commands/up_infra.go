@@ -21,13 +21,16 @@ import (
 )
 
 type UpInfraOptions struct {
-	Config     *config.Project
-	SkipGen    bool
-	AwsProfile string
-	AwsRegion  string
-	Version    string
-	UI         terminal.UI
-	Explain    bool
+	Config      *config.Project
+	SkipGen     bool
+	AwsProfile  string
+	AwsRegion   string
+	Version     string
+	UI          terminal.UI
+	Explain     bool
+	Parallelism int
+	Serial      bool
+	KeepGoing   bool
 }
 
 var upInfraLongDesc = templates.LongDesc(`
@@ -86,6 +89,9 @@ func NewCmdUpInfra(project *config.Project) *cobra.Command {
 	cmd.Flags().StringVar(&o.Version, "infra.terraform.version", "", "set terraform version")
 	cmd.Flags().StringVar(&o.AwsRegion, "infra.terraform.aws-region", "", "set aws region")
 	cmd.Flags().StringVar(&o.AwsProfile, "infra.terraform.aws-profile", "", "set aws profile")
+	cmd.Flags().IntVar(&o.Parallelism, "parallelism", 0, "maximum number of apps processed at the same time (default is max_parallel from ize.toml or no limit)")
+	cmd.Flags().BoolVar(&o.Serial, "serial", false, "process apps one at a time in dependency order")
+	cmd.Flags().BoolVar(&o.KeepGoing, "keep-going", false, "continue with independent apps when an app fails (apps depending on it are skipped)")
 
 	return cmd
 }
@@ -129,12 +135,20 @@ func (o *UpInfraOptions) Complete() error {
 		}
 	}
 
+	if o.Parallelism == 0 {
+		o.Parallelism = o.Config.MaxParallel
+	}
+
 	o.UI = terminal.ConsoleUI(context.Background(), o.Config.PlainText)
 
 	return nil
 }
 
 func (o *UpInfraOptions) Validate() error {
+	if o.Parallelism < 0 {
+		return fmt.Errorf("can't validate options: parallelism must not be negative")
+	}
+
 	if len(o.Config.Env) == 0 {
 		return fmt.Errorf("env must be specified")
 	}
@@ -209,17 +223,23 @@ terraform apply
 	}
 
 	ui := o.UI
+	report := &manager.Report{}
 
 	if _, ok := o.Config.Terraform["infra"]; ok {
-		err := deployInfra("infra", ui, o.Config, o.SkipGen)
+		err := report.Run("infra", func() error {
+			return deployInfra("infra", ui, o.Config, o.SkipGen)
+		})
 		if err != nil {
+			report.Skip(graphNames(o.Config.GetStates())...)
+			renderSummary(ui, report)
 			return err
 		}
 	}
 
 	err := manager.InDependencyOrder(aws.BackgroundContext(), o.Config.GetStates(), func(c context.Context, name string) error {
 		return deployInfra(name, ui, o.Config, o.SkipGen)
-	})
+	}, manager.WithParallelism(o.Parallelism), manager.WithSerial(o.Serial), manager.WithKeepGoing(o.KeepGoing), manager.WithReport(report))
+	renderSummary(ui, report)
 	if err != nil {
 		return err
 	}
@@ -235,36 +255,11 @@ func deployInfra(name string, ui terminal.UI, config *config.Project, skipGen bo
 		}
 	}
 
-	var tf terraform.Terraform
-
 	logrus.Infof("infra: %s", config.Terraform[name])
 
-	v, err := config.Session.Config.Credentials.Get()
+	tf, err := newInfraTerraform(name, []string{"init", "-input=true"}, config)
 	if err != nil {
-		return fmt.Errorf("can't get AWS credentials: %w", err)
-	}
-
-	env := []string{
-		fmt.Sprintf("ENV=%v", config.Env),
-		fmt.Sprintf("AWS_PROFILE=%v", config.Terraform[name].AwsProfile),
-		fmt.Sprintf("TF_LOG=%v", config.TFLog),
-		fmt.Sprintf("TF_LOG_PATH=%v", config.TFLogPath),
-		fmt.Sprintf("AWS_ACCESS_KEY_ID=%v", v.AccessKeyID),
-		fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%v", v.SecretAccessKey),
-		fmt.Sprintf("AWS_SESSION_TOKEN=%v", v.SessionToken),
-	}
-
-	switch config.PreferRuntime {
-	case "docker":
-		tf = terraform.NewDockerTerraform(name, []string{"init", "-input=true"}, env, nil, config)
-	case "native":
-		tf = terraform.NewLocalTerraform(name, []string{"init", "-input=true"}, env, nil, config)
-		err = tf.Prepare()
-		if err != nil {
-			return fmt.Errorf("can't deploy infra: %w", err)
-		}
-	default:
-		return fmt.Errorf("can't supported %s runtime", config.PreferRuntime)
+		return fmt.Errorf("can't deploy infra: %w", err)
 	}
 
 	ui.Output(fmt.Sprintf("[%s][%s] Running deploy infra...", config.Env, name), terminal.WithHeaderStyle())
@@ -339,3 +334,38 @@ func deployInfra(name string, ui terminal.UI, config *config.Project, skipGen bo
 
 	return nil
 }
+
+// newInfraTerraform returns terraform of the preferred runtime set up to run cmd in the name stack
+func newInfraTerraform(name string, cmd []string, config *config.Project) (terraform.Terraform, error) {
+	var tf terraform.Terraform
+
+	v, err := config.Session.Config.Credentials.Get()
+	if err != nil {
+		return nil, fmt.Errorf("can't get AWS credentials: %w", err)
+	}
+
+	env := []string{
+		fmt.Sprintf("ENV=%v", config.Env),
+		fmt.Sprintf("AWS_PROFILE=%v", config.Terraform[name].AwsProfile),
+		fmt.Sprintf("TF_LOG=%v", config.TFLog),
+		fmt.Sprintf("TF_LOG_PATH=%v", config.TFLogPath),
+		fmt.Sprintf("AWS_ACCESS_KEY_ID=%v", v.AccessKeyID),
+		fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%v", v.SecretAccessKey),
+		fmt.Sprintf("AWS_SESSION_TOKEN=%v", v.SessionToken),
+	}
+
+	switch config.PreferRuntime {
+	case "docker":
+		tf = terraform.NewDockerTerraform(name, cmd, env, nil, config)
+	case "native":
+		tf = terraform.NewLocalTerraform(name, cmd, env, nil, config)
+		err = tf.Prepare()
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("can't supported %s runtime", config.PreferRuntime)
+	}
+
+	return tf, nil
+}
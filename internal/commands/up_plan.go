@@ -0,0 +1,251 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/hazelops/ize/internal/manager"
+	"github.com/hazelops/ize/internal/manager/ecs"
+	"github.com/hazelops/ize/internal/terraform"
+	"github.com/hazelops/ize/pkg/terminal"
+)
+
+const (
+	planChanges   = "changes"
+	planNoChanges = "no changes"
+	planSkipped   = "skipped"
+	planError     = "error"
+)
+
+var planSummaryRe = regexp.MustCompile(`Plan: \d+ to add, \d+ to change, \d+ to destroy`)
+
+// planResult is a single row of the plan report
+type planResult struct {
+	name    string
+	kind    string
+	status  string
+	details string
+}
+
+// planReport collects plan results of stacks and apps planned concurrently
+type planReport struct {
+	mu      sync.Mutex
+	results []planResult
+}
+
+func (r *planReport) add(res planResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.results = append(r.results, res)
+}
+
+func (r *planReport) table() *terminal.Table {
+	t := terminal.NewTable("Name", "Type", "Status", "Details")
+
+	for _, res := range r.results {
+		color := ""
+		switch res.status {
+		case planChanges:
+			color = terminal.Yellow
+		case planNoChanges:
+			color = terminal.Green
+		case planError:
+			color = terminal.Red
+		}
+
+		t.Rich([]string{res.name, res.kind, res.status, res.details}, []string{"", "", color, ""})
+	}
+
+	return t
+}
+
+func (r *planReport) failed() []string {
+	var names []string
+
+	for _, res := range r.results {
+		if res.status == planError {
+			names = append(names, res.name)
+		}
+	}
+
+	return names
+}
+
+func planAll(ui terminal.UI, o *UpOptions) error {
+	report := &planReport{}
+
+	if o.withInfra {
+		report.add(planInfra("infra", ui, o.Config, o.SkipGen))
+	}
+
+	// apps are planned with the profile of infra, the config is shared by concurrent plans
+	if infra, ok := o.Config.Terraform["infra"]; ok {
+		o.Config.AwsProfile = infra.AwsProfile
+	}
+
+	err := manager.InDependencyOrder(aws.BackgroundContext(), o.selected, func(c context.Context, name string) error {
+		if _, ok := o.Config.Terraform[name]; ok {
+			report.add(planInfra(name, ui, o.Config, o.SkipGen))
+			return nil
+		}
+
+		report.add(planApp(name, ui, o.Config))
+		return nil
+	}, manager.WithParallelism(o.Parallelism), manager.WithSerial(o.Serial))
+	if err != nil {
+		return err
+	}
+
+	return renderPlan(ui, report)
+}
+
+func planOne(ui terminal.UI, o *UpOptions) error {
+	report := &planReport{}
+
+	if _, ok := o.Config.Terraform[o.AppName]; ok {
+		report.add(planInfra(o.AppName, ui, o.Config, o.SkipGen))
+	}
+
+	report.add(planApp(o.AppName, ui, o.Config))
+
+	return renderPlan(ui, report)
+}
+
+func renderPlan(ui terminal.UI, report *planReport) error {
+	ui.Output("Plan summary:", terminal.WithHeaderStyle())
+	ui.Table(report.table())
+
+	if failed := report.failed(); len(failed) != 0 {
+		return fmt.Errorf("can't plan: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// planInfra runs terraform plan with -detailed-exitcode in the name stack
+// and prints the plan output once it's done
+func planInfra(name string, ui terminal.UI, config *config.Project, skipGen bool) planResult {
+	res := planResult{name: name, kind: "terraform"}
+
+	if !skipGen {
+		err := GenerateTerraformFiles(name, "", config)
+		if err != nil {
+			res.status, res.details = planError, err.Error()
+			return res
+		}
+	}
+
+	tf, err := newInfraTerraform(name, []string{"init", "-input=true"}, config)
+	if err != nil {
+		res.status, res.details = planError, err.Error()
+		return res
+	}
+
+	ui.Output(fmt.Sprintf("[%s][%s] Running plan infra...", config.Env, name), terminal.WithHeaderStyle())
+
+	err = tf.RunUI(ui)
+	if err != nil {
+		res.status, res.details = planError, fmt.Sprintf("terraform init: %s", err)
+		return res
+	}
+
+	var output bytes.Buffer
+
+	tf.NewCmd([]string{"plan", "-detailed-exitcode", "-input=false"})
+	tf.SetOut(&output)
+
+	err = tf.RunUI(ui)
+
+	ui.Output(output.String())
+
+	var exitErr *terraform.ExitError
+	switch {
+	case err == nil:
+		res.status = planNoChanges
+	case errors.As(err, &exitErr) && exitErr.Code == 2:
+		res.status = planChanges
+		res.details = planSummaryRe.FindString(output.String())
+		if len(res.details) == 0 {
+			res.details = "changes to outputs"
+		}
+	default:
+		res.status, res.details = planError, fmt.Sprintf("terraform plan: %s", err)
+	}
+
+	return res
+}
+
+// planApp previews the app deployment. Only ECS apps support computing the diff
+func planApp(name string, ui terminal.UI, cfg *config.Project) planResult {
+	res := planResult{name: name, kind: "ecs"}
+
+	switch {
+	case cfg.Serverless[name] != nil:
+		res.kind = "serverless"
+	case cfg.K8s[name] != nil:
+		res.kind = "k8s"
+	case cfg.Alias[name] != nil:
+		res.kind = "alias"
+	}
+
+	if res.kind != "ecs" {
+		res.status, res.details = planSkipped, fmt.Sprintf("plan is not supported for %s apps", res.kind)
+		return res
+	}
+
+	app, ok := cfg.Ecs[name]
+	if !ok {
+		app = &config.Ecs{}
+	}
+	app.Name = name
+
+	m := &ecs.Manager{
+		Project: cfg,
+		App:     app,
+	}
+
+	diff, err := m.Plan()
+	if err != nil {
+		res.status, res.details = planError, err.Error()
+		return res
+	}
+
+	if diff.Skipped {
+		res.status, res.details = planSkipped, "skip_deploy is set"
+		return res
+	}
+
+	var changes []string
+	for _, i := range diff.Images {
+		if i.Before != i.After {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", i.Container, i.Before, i.After))
+		}
+	}
+
+	if !diff.Changed() {
+		res.status, res.details = planNoChanges, fmt.Sprintf("running %s", diff.TaskDefinition)
+		return res
+	}
+
+	if len(diff.Changes) != 0 {
+		ui.Output(fmt.Sprintf("[%s][%s] Task definition changes:", cfg.Env, name), terminal.WithHeaderStyle())
+		ui.Output(diff.Changes)
+
+		if len(changes) == 0 {
+			changes = append(changes, "task definition changed")
+		}
+	}
+
+	res.status = planChanges
+	res.details = fmt.Sprintf("%s: %s", diff.TaskDefinition, strings.Join(changes, "; "))
+
+	return res
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hazelops/ize/internal/config"
+)
+
+func TestUpOptions_selectTargets(t *testing.T) {
+	cfg := &config.Project{
+		Env:       "testnut",
+		Namespace: "nutcorp",
+		Terraform: map[string]*config.Terraform{
+			"infra":    {},
+			"vpc":      {},
+			"database": {DependsOn: []string{"vpc"}},
+		},
+		Ecs: map[string]*config.Ecs{
+			"goblin":  {DependsOn: []string{"vpc"}},
+			"squibby": {DependsOn: []string{"goblin"}},
+			"troll":   {DependsOn: []string{"squibby"}},
+		},
+		Alias: map[string]*config.Alias{
+			"wisp": {},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		targets        []string
+		withDeps       bool
+		withDependents bool
+		wantInfra      bool
+		want           []string
+		wantExpanded   []string
+		wantErr        bool
+	}{
+		{
+			name:      "no targets",
+			wantInfra: true,
+			want:      []string{"database", "goblin", "squibby", "troll", "vpc", "wisp"},
+		},
+		{
+			name:    "app without expansion",
+			targets: []string{"goblin"},
+			want:    []string{"goblin"},
+		},
+		{
+			name:         "app depending on a stack",
+			targets:      []string{"goblin"},
+			withDeps:     true,
+			wantInfra:    true,
+			want:         []string{"goblin", "vpc"},
+			wantExpanded: []string{"infra", "vpc"},
+		},
+		{
+			name:         "app depending on all stacks implicitly",
+			targets:      []string{"squibby"},
+			withDeps:     true,
+			wantInfra:    true,
+			want:         []string{"database", "goblin", "squibby", "vpc"},
+			wantExpanded: []string{"database", "goblin", "infra", "vpc"},
+		},
+		{
+			name:           "stack with dependents",
+			targets:        []string{"database"},
+			withDependents: true,
+			want:           []string{"database", "squibby", "troll", "wisp"},
+			wantExpanded:   []string{"squibby", "troll", "wisp"},
+		},
+		{
+			name:      "only infra",
+			targets:   []string{"infra"},
+			wantInfra: true,
+		},
+		{
+			name:           "infra with dependents",
+			targets:        []string{"infra"},
+			withDependents: true,
+			wantInfra:      true,
+			want:           []string{"database", "goblin", "squibby", "troll", "vpc", "wisp"},
+			wantExpanded:   []string{"database", "goblin", "squibby", "troll", "vpc", "wisp"},
+		},
+		{
+			name:    "unknown target",
+			targets: []string{"ogre"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &UpOptions{
+				Config:         cfg,
+				Targets:        tt.targets,
+				WithDeps:       tt.withDeps,
+				WithDependents: tt.withDependents,
+			}
+
+			err := o.selectTargets()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if o.withInfra != tt.wantInfra {
+				t.Errorf("selectTargets() withInfra = %v, want %v", o.withInfra, tt.wantInfra)
+			}
+
+			if got := graphNames(o.selected); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectTargets() = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(o.expanded, tt.wantExpanded) {
+				t.Errorf("selectTargets() expanded = %v, want %v", o.expanded, tt.wantExpanded)
+			}
+
+			if err = o.Validate(); (err != nil) != (len(tt.wantExpanded) != 0) {
+				t.Errorf("Validate() without --auto-approve error = %v, want error %v", err, len(tt.wantExpanded) != 0)
+			}
+
+			o.AutoApprove = true
+			if err = o.Validate(); err != nil {
+				t.Errorf("Validate() with --auto-approve error = %v", err)
+			}
+		})
+	}
+}
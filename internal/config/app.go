@@ -1,35 +1,57 @@
 package config
 
 type Ecs struct {
-	Name                   string   `mapstructure:",omitempty"`
-	Path                   string   `mapstructure:",omitempty"`
-	Image                  string   `mapstructure:",omitempty"`
-	Cluster                string   `mapstructure:",omitempty"`
-	TaskDefinitionRevision string   `mapstructure:"task_definition_revision"`
-	DockerRegistry         string   `mapstructure:"docker_registry,omitempty"`
-	Timeout                int      `mapstructure:",omitempty"`
-	Unsafe                 bool     `mapstructure:",omitempty"`
-	SkipDeploy             bool     `mapstructure:"skip_deploy,omitempty"`
-	Icon                   string   `mapstructure:"icon,omitempty"`
-	AwsProfile             string   `mapstructure:"aws_profile,omitempty"`
-	AwsRegion              string   `mapstructure:"aws_region,omitempty"`
-	DependsOn              []string `mapstructure:"depends_on,omitempty"`
+	Name                   string            `mapstructure:",omitempty"`
+	Path                   string            `mapstructure:",omitempty"`
+	Image                  string            `mapstructure:",omitempty"`
+	Cluster                string            `mapstructure:",omitempty"`
+	TaskDefinitionRevision string            `mapstructure:"task_definition_revision"`
+	TaskDefinitionFile     string            `mapstructure:"task_definition_file,omitempty"`
+	DockerRegistry         string            `mapstructure:"docker_registry,omitempty"`
+	Timeout                int               `mapstructure:",omitempty"`
+	Unsafe                 bool              `mapstructure:",omitempty"`
+	SkipDeploy             bool              `mapstructure:"skip_deploy,omitempty"`
+	Icon                   string            `mapstructure:"icon,omitempty"`
+	AwsProfile             string            `mapstructure:"aws_profile,omitempty"`
+	AwsRegion              string            `mapstructure:"aws_region,omitempty"`
+	DependsOn              []string          `mapstructure:"depends_on,omitempty"`
+	SecretsBackend         string            `mapstructure:"secrets_backend,omitempty"`
+	DeploymentStrategy     string            `mapstructure:"deployment_strategy,omitempty"`
+	BakeTime               int               `mapstructure:"bake_time,omitempty"`
+	CanaryPercent          int               `mapstructure:"canary_percent,omitempty"`
+	ListenerArn            string            `mapstructure:"listener_arn,omitempty"`
+	TargetGroupArns        []string          `mapstructure:"target_group_arns,omitempty"`
+	Containers             map[string]string `mapstructure:"containers,omitempty"`
+	CircuitBreaker         bool              `mapstructure:"circuit_breaker,omitempty"`
+	KeepRevisions          int               `mapstructure:"keep_revisions,omitempty"`
+	PreDeploy              *PreDeploy        `mapstructure:"pre_deploy,omitempty"`
+	Queries                map[string]string `mapstructure:"queries,omitempty"`
+}
+
+// PreDeploy is a one-off task run with the new task definition before the ECS app is deployed
+type PreDeploy struct {
+	Command   []string `mapstructure:"command"`
+	Container string   `mapstructure:"container,omitempty"`
 }
 
 type K8s struct {
-	Name                   string   `mapstructure:",omitempty"`
-	Path                   string   `mapstructure:",omitempty"`
-	Image                  string   `mapstructure:",omitempty"`
-	Cluster                string   `mapstructure:",omitempty"`
-	TaskDefinitionRevision string   `mapstructure:"task_definition_revision"`
-	DockerRegistry         string   `mapstructure:"docker_registry,omitempty"`
-	Timeout                int      `mapstructure:",omitempty"`
-	Unsafe                 bool     `mapstructure:",omitempty"`
-	SkipDeploy             bool     `mapstructure:"skip_deploy,omitempty"`
-	Icon                   string   `mapstructure:"icon,omitempty"`
-	AwsProfile             string   `mapstructure:"aws_profile,omitempty"`
-	AwsRegion              string   `mapstructure:"aws_region,omitempty"`
-	DependsOn              []string `mapstructure:"depends_on,omitempty"`
+	Name           string   `mapstructure:",omitempty"`
+	Path           string   `mapstructure:",omitempty"`
+	Image          string   `mapstructure:",omitempty"`
+	Manifests      string   `mapstructure:"manifests,omitempty"`
+	Chart          string   `mapstructure:"chart,omitempty"`
+	ValuesFile     string   `mapstructure:"values_file,omitempty"`
+	KubeContext    string   `mapstructure:"kube_context,omitempty"`
+	KubeNamespace  string   `mapstructure:"kube_namespace,omitempty"`
+	Kubeconfig     string   `mapstructure:"kubeconfig,omitempty"`
+	DockerRegistry string   `mapstructure:"docker_registry,omitempty"`
+	Timeout        int      `mapstructure:",omitempty"`
+	SkipDeploy     bool     `mapstructure:"skip_deploy,omitempty"`
+	Icon           string   `mapstructure:"icon,omitempty"`
+	AwsProfile     string   `mapstructure:"aws_profile,omitempty"`
+	AwsRegion      string   `mapstructure:"aws_region,omitempty"`
+	DependsOn      []string `mapstructure:"depends_on,omitempty"`
+	SecretsBackend string   `mapstructure:"secrets_backend,omitempty"`
 }
 
 type Serverless struct {
@@ -47,6 +69,7 @@ type Serverless struct {
 	AwsProfile              string   `mapstructure:"aws_profile,omitempty"`
 	AwsRegion               string   `mapstructure:"aws_region,omitempty"`
 	DependsOn               []string `mapstructure:"depends_on,omitempty"`
+	SecretsBackend          string   `mapstructure:"secrets_backend,omitempty"`
 }
 
 type Alias struct {
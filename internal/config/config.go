@@ -6,12 +6,15 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"text/template"
 
+	"github.com/agext/levenshtein"
 	"github.com/hazelops/ize/internal/schema"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sts"
@@ -93,6 +96,11 @@ func (p *Project) GetConfig() error {
 		return err
 	}
 
+	err = validateDependsOn(p)
+	if err != nil {
+		return err
+	}
+
 	sess, err := utils.GetSession(&utils.SessionConfig{
 		Region:  p.AwsRegion,
 		Profile: p.AwsProfile,
@@ -129,6 +137,24 @@ func (p *Project) GetConfig() error {
 	return nil
 }
 
+// GetRawConfig reads apps and terraform stacks of the config file without validating them
+// and without creating an AWS session, so the config can be inspected even if it's invalid
+func (p *Project) GetRawConfig() error {
+	err := ConvertApps()
+	if err != nil {
+		return err
+	}
+
+	err = ConvertInfra()
+	if err != nil {
+		return err
+	}
+
+	logrus.Debug("config file used:", viper.ConfigFileUsed())
+
+	return viper.Unmarshal(p)
+}
+
 func (p *Project) GetTestConfig() error {
 	switch viper.GetString("log_level") {
 	case "info":
@@ -330,6 +356,20 @@ func findDuplicates(cfg *Project) error {
 		existingKeys[k] = "ecs"
 	}
 
+	for k := range cfg.K8s {
+		if val, ok := existingKeys[k]; ok {
+			if duplicateKeys[k] == nil {
+				duplicateKeys[k] = map[string]string{}
+			}
+			duplicateKeys[k]["k8s"] = k
+			if _, ok := duplicateKeys[k][val]; !ok {
+				duplicateKeys[k][val] = k
+
+			}
+		}
+		existingKeys[k] = "k8s"
+	}
+
 	for k := range cfg.Serverless {
 		if val, ok := existingKeys[k]; ok {
 			if duplicateKeys[k] == nil {
@@ -375,6 +415,154 @@ func findDuplicates(cfg *Project) error {
 	return nil
 }
 
+// validateDependsOn checks that depends_on of apps and terraform stacks refers
+// to existing apps or stacks and suggests the closest name for typos. The infra stack
+// is deployed before everything else, so it can't be a part of depends_on. Cycles are
+// searched in the graph the apps are deployed by, including implicit dependencies
+// of apps on all stacks
+func validateDependsOn(cfg *Project) error {
+	sections := map[string]string{}
+	deps := map[string][]string{}
+
+	for name, tf := range cfg.Terraform {
+		sections[name], deps[name] = "terraform", tf.DependsOn
+	}
+	for name, app := range cfg.Ecs {
+		sections[name], deps[name] = "ecs", app.DependsOn
+	}
+	for name, app := range cfg.K8s {
+		sections[name], deps[name] = "k8s", app.DependsOn
+	}
+	for name, app := range cfg.Serverless {
+		sections[name], deps[name] = "serverless", app.DependsOn
+	}
+	for name, app := range cfg.Alias {
+		sections[name], deps[name] = "alias", app.DependsOn
+	}
+
+	var names []string
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errMsg := ""
+	for _, name := range names {
+		if name == "infra" && sections[name] == "terraform" && len(deps[name]) != 0 {
+			errMsg += "\n- [terraform.infra] can't depend on other stacks or apps, it's deployed before all of them"
+		}
+
+		for _, d := range deps[name] {
+			if d == "infra" && sections[d] == "terraform" {
+				errMsg += fmt.Sprintf("\n- [%s.%s] depends on \"infra\" which is always deployed first, remove it from depends_on", sections[name], name)
+				continue
+			}
+
+			if _, ok := sections[d]; ok {
+				continue
+			}
+
+			errMsg += fmt.Sprintf("\n- [%s.%s] depends on \"%s\" which is not defined", sections[name], name, d)
+			if suggestion := suggestName(d, names); len(suggestion) != 0 {
+				errMsg += fmt.Sprintf(" (did you mean \"%s\"?)", suggestion)
+			}
+		}
+	}
+
+	if len(errMsg) != 0 {
+		return fmt.Errorf("invalid depends_on references:%s", errMsg)
+	}
+
+	cycle := dependencyCycle(cfg.GetStatesAndApps())
+	if len(cycle) == 0 {
+		return nil
+	}
+
+	hint := ""
+	for i := 0; i < len(cycle)-1; i++ {
+		app, stack := cycle[i], cycle[i+1]
+		if sections[app] != "terraform" && sections[stack] == "terraform" && !slices.Contains(deps[app], stack) {
+			hint = fmt.Sprintf(" (%s doesn't depend on any stack, so it's deployed after all of them)", app)
+			break
+		}
+	}
+
+	return fmt.Errorf("dependency cycle: %s%s", strings.Join(cycle, " -> "), hint)
+}
+
+// dependencyCycle returns the first cycle found in the dependency graph as a path
+// where every node depends on the next one, or nil if there are no cycles
+func dependencyCycle(graph map[string]*interface{}) []string {
+	var names []string
+	for name := range graph {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := map[string]int{}
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+
+		deps, _ := (*graph[name]).(map[string]interface{})["depends_on"].([]string)
+		deps = append([]string{}, deps...)
+		sort.Strings(deps)
+
+		for _, d := range deps {
+			if graph[d] == nil {
+				continue
+			}
+
+			switch state[d] {
+			case visiting:
+				return append(append([]string{}, path[slices.Index(path, d):]...), d)
+			case 0:
+				if cycle := visit(d); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, name := range names {
+		if state[name] == 0 {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
+
+// suggestName returns the closest of the names or an empty string if none of them is close enough
+func suggestName(name string, names []string) string {
+	suggestion := ""
+	best := 0
+
+	for _, n := range names {
+		d := levenshtein.Distance(name, n, nil)
+		if d <= len(name)/3+1 && (len(suggestion) == 0 || d < best) {
+			suggestion, best = n, d
+		}
+	}
+
+	return suggestion
+}
+
 func setDefaultInfraDir(cwd string) {
 	viper.SetDefault("IZE_DIR", fmt.Sprintf("%v/.ize", cwd))
 	viper.SetDefault("ENV_DIR", fmt.Sprintf("%v/.ize/env/%v", cwd, viper.GetString("ENV")))
@@ -451,6 +639,7 @@ func readConfigFile(path string) (*Config, error) {
 
 func ConvertApps() error {
 	ecs := map[string]interface{}{}
+	k8s := map[string]interface{}{}
 	serverless := map[string]interface{}{}
 
 	apps := viper.GetStringMap("app")
@@ -466,6 +655,14 @@ func ConvertApps() error {
 			}
 
 			ecs[name] = structToMap(ecsApp)
+		case "k8s":
+			k8sApp := K8s{}
+			err := mapstructure.Decode(&body, &k8sApp)
+			if err != nil {
+				return err
+			}
+
+			k8s[name] = structToMap(k8sApp)
 		case "serverless":
 			slsApp := Serverless{}
 			err := mapstructure.Decode(&body, &slsApp)
@@ -482,6 +679,7 @@ func ConvertApps() error {
 
 	err := viper.MergeConfigMap(map[string]interface{}{
 		"ecs":        ecs,
+		"k8s":        k8s,
 		"serverless": serverless,
 	})
 	if err != nil {
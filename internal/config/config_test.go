@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
 )
 
@@ -62,3 +63,137 @@ func TestSetTag(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateDependsOn(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *Project
+		wantErr string
+	}{
+		{
+			name: "valid",
+			cfg: &Project{
+				Terraform: map[string]*Terraform{"vpc": {}},
+				Ecs: map[string]*Ecs{
+					"goblin":  {DependsOn: []string{"vpc"}},
+					"squibby": {DependsOn: []string{"goblin"}},
+				},
+			},
+		},
+		{
+			name: "typo",
+			cfg: &Project{
+				Ecs: map[string]*Ecs{
+					"goblin":  {},
+					"squibby": {DependsOn: []string{"gobiln"}},
+				},
+			},
+			wantErr: `[ecs.squibby] depends on "gobiln" which is not defined (did you mean "goblin"?)`,
+		},
+		{
+			name: "unknown",
+			cfg: &Project{
+				Terraform: map[string]*Terraform{"vpc": {DependsOn: []string{"network"}}},
+			},
+			wantErr: `[terraform.vpc] depends on "network" which is not defined`,
+		},
+		{
+			name: "stack depending on app which depends on another stack",
+			cfg: &Project{
+				Terraform: map[string]*Terraform{"vpc": {}, "database": {DependsOn: []string{"goblin"}}},
+				Ecs:       map[string]*Ecs{"goblin": {DependsOn: []string{"vpc"}}},
+			},
+		},
+		{
+			name: "stack depending on app without stack dependencies",
+			cfg: &Project{
+				Terraform: map[string]*Terraform{"vpc": {DependsOn: []string{"goblin"}}},
+				Ecs:       map[string]*Ecs{"goblin": {}},
+			},
+			wantErr: `dependency cycle: goblin -> vpc -> goblin (goblin doesn't depend on any stack, so it's deployed after all of them)`,
+		},
+		{
+			name: "explicit cycle",
+			cfg: &Project{
+				Ecs: map[string]*Ecs{
+					"goblin":  {DependsOn: []string{"squibby"}},
+					"squibby": {DependsOn: []string{"goblin"}},
+				},
+			},
+			wantErr: `dependency cycle: goblin -> squibby -> goblin`,
+		},
+		{
+			name: "infra",
+			cfg: &Project{
+				Terraform: map[string]*Terraform{"infra": {}, "vpc": {DependsOn: []string{"infra"}}},
+			},
+			wantErr: `[terraform.vpc] depends on "infra" which is always deployed first`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDependsOn(tt.cfg)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("validateDependsOn() error = %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateDependsOn() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTunnelProfile(t *testing.T) {
+	tunnel := &Tunnel{
+		BastionInstanceID: "i-0",
+		SSHPublicKey:      "id.pub",
+		SSHPrivateKey:     "id",
+		Profiles: map[string]*Tunnel{
+			"db":    {ForwardHost: []string{"db:5432:35432"}},
+			"cache": {BastionInstanceID: "i-1", SSHPrivateKey: "cache"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		tunnel         *Tunnel
+		profile        string
+		wantOk         bool
+		wantBastion    string
+		wantPrivateKey string
+		wantPublicKey  string
+	}{
+		{name: "inherited keys and bastion", tunnel: tunnel, profile: "db", wantOk: true, wantBastion: "i-0", wantPrivateKey: "id", wantPublicKey: "id.pub"},
+		{name: "own key and bastion", tunnel: tunnel, profile: "cache", wantOk: true, wantBastion: "i-1", wantPrivateKey: "cache", wantPublicKey: "id.pub"},
+		{name: "not found", tunnel: tunnel, profile: "queue"},
+		{name: "no tunnel", profile: "db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.tunnel.Profile(tt.profile)
+			if ok != tt.wantOk {
+				t.Fatalf("Profile() ok = %v, want %v", ok, tt.wantOk)
+			}
+
+			if !ok {
+				return
+			}
+
+			if got.BastionInstanceID != tt.wantBastion {
+				t.Errorf("Profile() bastion = %s, want %s", got.BastionInstanceID, tt.wantBastion)
+			}
+
+			if got.SSHPrivateKey != tt.wantPrivateKey || got.SSHPublicKey != tt.wantPublicKey {
+				t.Errorf("Profile() keys = %s, %s, want %s, %s", got.SSHPrivateKey, got.SSHPublicKey, tt.wantPrivateKey, tt.wantPublicKey)
+			}
+		})
+	}
+
+	if got := tunnel.ProfileNames(); strings.Join(got, ",") != "cache,db" {
+		t.Errorf("ProfileNames() = %v, want [cache db]", got)
+	}
+}
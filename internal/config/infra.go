@@ -1,5 +1,7 @@
 package config
 
+import "sort"
+
 type Infra struct {
 	Terraform Terraform `mapstructure:"infra.terraform,omitempty"`
 	Tunnel    Tunnel    `mapstructure:"infra.tunnel,omitempty"`
@@ -22,4 +24,51 @@ type Tunnel struct {
 	ForwardHost       []string `mapstructure:"forward_host,omitempty"`
 	SSHPublicKey      string   `mapstructure:"ssh_public_key,omitempty"`
 	SSHPrivateKey     string   `mapstructure:"ssh_private_key,omitempty"`
+	// Profiles are named tunnels set as [infra.tunnel.<name>], each with its own bastion host, forwards and keys
+	Profiles map[string]*Tunnel `mapstructure:",remain"`
+}
+
+// Profile returns the named tunnel profile. Keys which are not set in the profile are inherited from the tunnel.
+func (t *Tunnel) Profile(name string) (*Tunnel, bool) {
+	if t == nil {
+		return nil, false
+	}
+
+	p, ok := t.Profiles[name]
+	if !ok || p == nil {
+		return nil, false
+	}
+
+	profile := *p
+	profile.Profiles = nil
+
+	if len(profile.BastionInstanceID) == 0 {
+		profile.BastionInstanceID = t.BastionInstanceID
+	}
+
+	if len(profile.SSHPublicKey) == 0 {
+		profile.SSHPublicKey = t.SSHPublicKey
+	}
+
+	if len(profile.SSHPrivateKey) == 0 {
+		profile.SSHPrivateKey = t.SSHPrivateKey
+	}
+
+	return &profile, true
+}
+
+// ProfileNames returns sorted names of the tunnel profiles
+func (t *Tunnel) ProfileNames() []string {
+	if t == nil {
+		return nil
+	}
+
+	var names []string
+	for name := range t.Profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
 }
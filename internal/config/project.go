@@ -14,6 +14,8 @@ import (
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 	"github.com/aws/aws-sdk-go/service/sts"
@@ -32,6 +34,7 @@ type Project struct {
 	PreferRuntime    string `mapstructure:"prefer_runtime,omitempty"`
 	Tag              string `mapstructure:",omitempty"`
 	DockerRegistry   string `mapstructure:"docker_registry,omitempty"`
+	MaxParallel      int    `mapstructure:"max_parallel,omitempty"`
 
 	Home      string `mapstructure:",omitempty"`
 	RootDir   string `mapstructure:"root_dir,omitempty"`
@@ -47,6 +50,7 @@ type Project struct {
 	Tunnel     *Tunnel                `mapstructure:",omitempty"`
 	Terraform  map[string]*Terraform  `mapstructure:",omitempty"`
 	Ecs        map[string]*Ecs        `mapstructure:",omitempty"`
+	K8s        map[string]*K8s        `mapstructure:",omitempty"`
 	Serverless map[string]*Serverless `mapstructure:",omitempty"`
 	Alias      map[string]*Alias      `mapstructure:",omitempty"`
 }
@@ -60,6 +64,7 @@ type awsClient struct {
 	SSMClient            ssmiface.SSMAPI
 	ELBV2Client          elbv2iface.ELBV2API
 	ECRClient            ecriface.ECRAPI
+	SecretsManagerClient secretsmanageriface.SecretsManagerAPI
 }
 
 type Option func(*awsClient)
@@ -112,6 +117,12 @@ func WithECRClient(api ecriface.ECRAPI) Option {
 	}
 }
 
+func WithSecretsManagerClient(api secretsmanageriface.SecretsManagerAPI) Option {
+	return func(r *awsClient) {
+		r.SecretsManagerClient = api
+	}
+}
+
 func NewAWSClient(options ...Option) *awsClient {
 	r := awsClient{}
 	for _, opt := range options {
@@ -131,6 +142,7 @@ func (p *Project) SettingAWSClient(sess *session.Session) {
 		WithSSMClient(ssm.New(sess)),
 		WithELBV2Client(elbv2.New(sess)),
 		WithECRClient(ecr.New(sess)),
+		WithSecretsManagerClient(secretsmanager.New(sess)),
 	)
 }
 
@@ -145,6 +157,14 @@ func (p *Project) GetApps() map[string]*interface{} {
 		apps[name] = &v
 	}
 
+	for name, body := range p.K8s {
+		var v interface{}
+		v = map[string]interface{}{
+			"depends_on": body.DependsOn,
+		}
+		apps[name] = &v
+	}
+
 	for name, body := range p.Serverless {
 		var v interface{}
 		v = map[string]interface{}{
@@ -180,3 +200,41 @@ func (p *Project) GetStates() map[string]*interface{} {
 
 	return states
 }
+
+// GetStatesAndApps returns terraform stacks (except infra) and apps as a single dependency graph,
+// so an app can depend on a stack. Apps that don't depend on any stack implicitly depend on all
+// of them and are deployed once the whole infrastructure is up
+func (p *Project) GetStatesAndApps() map[string]*interface{} {
+	states := p.GetStates()
+	all := map[string]*interface{}{}
+
+	var stateNames []string
+	for name, state := range states {
+		stateNames = append(stateNames, name)
+		all[name] = state
+	}
+
+	for name, app := range p.GetApps() {
+		deps, _ := (*app).(map[string]interface{})["depends_on"].([]string)
+
+		onState := false
+		for _, d := range deps {
+			if _, ok := states[d]; ok {
+				onState = true
+				break
+			}
+		}
+
+		if !onState {
+			var v interface{}
+			v = map[string]interface{}{
+				"depends_on": append(append([]string{}, deps...), stateNames...),
+			}
+			app = &v
+		}
+
+		all[name] = app
+	}
+
+	return all
+}
@@ -3,6 +3,7 @@ package manager
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
@@ -12,6 +13,8 @@ import (
 // Graph represents project as app dependencies
 type Graph struct {
 	Vertices map[string]*Vertex
+	// Dangling are depends_on references to unknown apps by the app
+	Dangling map[string][]string
 	lock     sync.RWMutex
 }
 
@@ -50,14 +53,54 @@ var (
 	}
 )
 
+// Option configures how the graph is walked
+type Option func(*walkOptions)
+
+type walkOptions struct {
+	parallelism int
+	serial      bool
+	keepGoing   bool
+	report      *Report
+}
+
+// WithParallelism limits the number of apps the function is applied to at the same time.
+// Zero or a negative value means no limit
+func WithParallelism(n int) Option {
+	return func(o *walkOptions) {
+		o.parallelism = n
+	}
+}
+
+// WithSerial makes the walk apply the function to one app at a time in a stable topological order
+func WithSerial(serial bool) Option {
+	return func(o *walkOptions) {
+		o.serial = serial
+	}
+}
+
+// WithKeepGoing makes the walk continue with independent apps when the function fails.
+// Apps depending on the failed ones are skipped
+func WithKeepGoing(keepGoing bool) Option {
+	return func(o *walkOptions) {
+		o.keepGoing = keepGoing
+	}
+}
+
+// WithReport records the result of every app of the walk to the report
+func WithReport(report *Report) Option {
+	return func(o *walkOptions) {
+		o.report = report
+	}
+}
+
 // InDependencyOrder applies the function to the apps of the project taking in account the dependency order
-func InDependencyOrder(ctx context.Context, apps map[string]*interface{}, fn func(context.Context, string) error) error {
-	return visit(ctx, apps, upDirectionTraversalConfig, fn, AppStopped)
+func InDependencyOrder(ctx context.Context, apps map[string]*interface{}, fn func(context.Context, string) error, opts ...Option) error {
+	return visit(ctx, apps, upDirectionTraversalConfig, fn, AppStopped, opts...)
 }
 
 // InReverseDependencyOrder applies the function to the apps of the project in reverse order of dependencies
-func InReversDependencyOrder(ctx context.Context, apps map[string]*interface{}, fn func(context.Context, string) error) error {
-	return visit(ctx, apps, downDirectionTraversalConfig, fn, AppStarted)
+func InReversDependencyOrder(ctx context.Context, apps map[string]*interface{}, fn func(context.Context, string) error, opts ...Option) error {
+	return visit(ctx, apps, downDirectionTraversalConfig, fn, AppStarted, opts...)
 }
 
 // NewGraph returns the dependency graph of the apps
@@ -65,6 +108,7 @@ func NewGraph(apps map[string]*interface{}, initialStatus AppStatus) *Graph {
 	graph := &Graph{
 		lock:     sync.RWMutex{},
 		Vertices: map[string]*Vertex{},
+		Dangling: map[string][]string{},
 	}
 
 	for n := range apps {
@@ -79,6 +123,8 @@ func NewGraph(apps map[string]*interface{}, initialStatus AppStatus) *Graph {
 				for _, d := range d {
 					if _, ok := apps[d]; ok {
 						graph.AddEdge(n, d)
+					} else {
+						graph.Dangling[n] = append(graph.Dangling[n], d)
 					}
 				}
 			}
@@ -88,6 +134,51 @@ func NewGraph(apps map[string]*interface{}, initialStatus AppStatus) *Graph {
 	return graph
 }
 
+// Select returns the subset of apps with the targets. Transitive dependencies of the
+// targets are added with withDeps, apps that depend on the targets are added with withDependents.
+// Targets that aren't in apps are ignored
+func Select(apps map[string]*interface{}, targets []string, withDeps bool, withDependents bool) map[string]*interface{} {
+	g := NewGraph(apps, AppStopped)
+
+	selected := map[string]*interface{}{}
+
+	// Every direction has its own visited set, an app selected as a dependent
+	// still has to be expanded with its dependencies and vice versa
+	var walk func(v *Vertex, next func(*Vertex) []*Vertex, visited map[string]bool)
+	walk = func(v *Vertex, next func(*Vertex) []*Vertex, visited map[string]bool) {
+		for _, n := range next(v) {
+			if visited[n.Key] {
+				continue
+			}
+
+			visited[n.Key] = true
+			selected[n.Key] = apps[n.Key]
+			walk(n, next, visited)
+		}
+	}
+
+	deps, dependents := map[string]bool{}, map[string]bool{}
+
+	for _, t := range targets {
+		v, ok := g.Vertices[t]
+		if !ok {
+			continue
+		}
+
+		selected[t] = apps[t]
+
+		if withDeps {
+			walk(v, getChildren, deps)
+		}
+
+		if withDependents {
+			walk(v, getParents, dependents)
+		}
+	}
+
+	return selected
+}
+
 func (g *Graph) AddVertex(key string, app string, initialStatus AppStatus) {
 	g.lock.Lock()
 	defer g.lock.Unlock()
@@ -153,6 +244,79 @@ func (g *Graph) HasCycles() (bool, error) {
 	return false, nil
 }
 
+// Cycles returns all groups of vertices that depend on each other, every group
+// is a strongly connected component of the graph. Groups and their keys are sorted
+func (g *Graph) Cycles() [][]string {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	// Tarjan's algorithm
+	index := 0
+	indices := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var cycles [][]string
+
+	var connect func(v *Vertex)
+	connect = func(v *Vertex) {
+		indices[v.Key] = index
+		lowlink[v.Key] = index
+		index++
+		stack = append(stack, v.Key)
+		onStack[v.Key] = true
+
+		for _, c := range v.Children {
+			if _, ok := indices[c.Key]; !ok {
+				connect(c)
+				if lowlink[c.Key] < lowlink[v.Key] {
+					lowlink[v.Key] = lowlink[c.Key]
+				}
+			} else if onStack[c.Key] && indices[c.Key] < lowlink[v.Key] {
+				lowlink[v.Key] = indices[c.Key]
+			}
+		}
+
+		if lowlink[v.Key] != indices[v.Key] {
+			return
+		}
+
+		var component []string
+		for {
+			k := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[k] = false
+			component = append(component, k)
+			if k == v.Key {
+				break
+			}
+		}
+
+		if _, self := v.Children[v.Key]; len(component) > 1 || self {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	var keys []string
+	for k := range g.Vertices {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, ok := indices[k]; !ok {
+			connect(g.Vertices[k])
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+
+	return cycles
+}
+
 func (g *Graph) visit(key string, path []string, discovered []string, finished []string) ([]string, []string, error) {
 	discovered = append(discovered, key)
 
@@ -184,43 +348,218 @@ func remove(slice []string, item string) []string {
 	return s
 }
 
-func visit(ctx context.Context, apps map[string]*interface{}, traversalConfig graphTraversalConfig, fn func(context.Context, string) error, initialStatus AppStatus) error {
+func visit(ctx context.Context, apps map[string]*interface{}, traversalConfig graphTraversalConfig, fn func(context.Context, string) error, initialStatus AppStatus, opts ...Option) error {
+	o := walkOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.report == nil {
+		o.report = &Report{}
+	}
+
 	g := NewGraph(apps, initialStatus)
 	if b, err := g.HasCycles(); b {
 		return err
 	}
 
-	nodes := traversalConfig.extremityNodesFn(g)
+	w := &walker{
+		graph:           g,
+		traversalConfig: traversalConfig,
+		fn:              fn,
+		opts:            o,
+		started:         map[string]bool{},
+	}
+
+	var err error
+	if o.serial {
+		err = w.visitSerial(ctx)
+	} else {
+		err = w.visit(ctx)
+	}
+
+	// Everything that was never started has been blocked by a failure
+	var skipped []string
+	for key := range g.Vertices {
+		if !w.started[key] {
+			skipped = append(skipped, key)
+		}
+	}
+	o.report.Skip(skipped...)
 
-	eg, _ := errgroup.WithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(w.failed) != 0 {
+		sort.Strings(w.failed)
+		return fmt.Errorf("failed: %s", strings.Join(w.failed, ", "))
+	}
+
+	return nil
+}
+
+// walker holds the state of a single graph walk
+type walker struct {
+	graph           *Graph
+	traversalConfig graphTraversalConfig
+	fn              func(context.Context, string) error
+	opts            walkOptions
+
+	// sem bounds the number of running functions, goroutines waiting
+	// for their turn are cheap unlike builds and API calls
+	sem chan struct{}
+
+	lock    sync.Mutex
+	started map[string]bool
+	failed  []string
+	stopped bool
+}
+
+func (w *walker) visit(ctx context.Context) error {
+	if w.opts.parallelism > 0 {
+		w.sem = make(chan struct{}, w.opts.parallelism)
+	}
+
+	nodes := w.traversalConfig.extremityNodesFn(w.graph)
+
+	// the context is canceled once the walk is stopped by a failure
+	eg, ctx := errgroup.WithContext(ctx)
 	eg.Go(func() error {
-		return run(ctx, g, eg, nodes, traversalConfig, fn)
+		return w.run(ctx, eg, nodes)
 	})
 
 	return eg.Wait()
 }
 
-func run(ctx context.Context, graph *Graph, eg *errgroup.Group, nodes []*Vertex, traversalConfig graphTraversalConfig, fn func(context.Context, string) error) error {
+func (w *walker) run(ctx context.Context, eg *errgroup.Group, nodes []*Vertex) error {
 	for _, node := range nodes {
 		// Don't start this app yet if all of its children have
 		// not been started yet.
-		if len(traversalConfig.filterAdjacentByStatusFn(graph, node.Key, traversalConfig.adjacentAppStatusToSkip)) != 0 {
+		if len(w.traversalConfig.filterAdjacentByStatusFn(w.graph, node.Key, w.traversalConfig.adjacentAppStatusToSkip)) != 0 {
+			continue
+		}
+
+		// An app can become ready after any of its dependencies is done,
+		// make sure it is started only once.
+		if !w.start(node.Key) {
 			continue
 		}
 
 		node := node
 		eg.Go(func() error {
-			err := fn(ctx, node.App)
+			if w.sem != nil {
+				w.sem <- struct{}{}
+			}
+
+			// the walk could have been stopped while the app was waiting for its turn
+			if !w.proceed(node.Key) {
+				if w.sem != nil {
+					<-w.sem
+				}
+
+				return nil
+			}
+
+			err := w.apply(ctx, node)
+
+			if w.sem != nil {
+				<-w.sem
+			}
+
 			if err != nil {
 				return err
 			}
 
-			graph.UpdateStatus(node.Key, traversalConfig.targetAppStatus)
+			return w.run(ctx, eg, w.traversalConfig.adjacentNodesFn(node))
+		})
+	}
+
+	return nil
+}
+
+// visitSerial applies the function to one app at a time. Among the apps that are ready
+// the one with the smallest key goes first, so the order is the same on every run
+func (w *walker) visitSerial(ctx context.Context) error {
+	for {
+		var ready []*Vertex
+		for _, v := range w.graph.Vertices {
+			if w.started[v.Key] {
+				continue
+			}
+
+			if len(w.traversalConfig.filterAdjacentByStatusFn(w.graph, v.Key, w.traversalConfig.adjacentAppStatusToSkip)) == 0 {
+				ready = append(ready, v)
+			}
+		}
+
+		if len(ready) == 0 {
+			return nil
+		}
 
-			return run(ctx, graph, eg, traversalConfig.adjacentNodesFn(node), traversalConfig, fn)
+		sort.Slice(ready, func(i, j int) bool {
+			return ready[i].Key < ready[j].Key
 		})
+
+		w.start(ready[0].Key)
+
+		if err := w.apply(ctx, ready[0]); err != nil {
+			return err
+		}
+	}
+}
+
+// start marks the app as started unless it already is or the walk is stopped
+func (w *walker) start(key string) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.started[key] || w.stopped {
+		return false
 	}
 
+	w.started[key] = true
+
+	return true
+}
+
+// proceed reports whether the started app can run. If the walk has been stopped,
+// the app is unmarked as started, so it's reported as skipped
+func (w *walker) proceed(key string) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.stopped {
+		delete(w.started, key)
+		return false
+	}
+
+	return true
+}
+
+// apply runs the function for the app and updates its status. The error is
+// only returned if the walk has to be stopped
+func (w *walker) apply(ctx context.Context, node *Vertex) error {
+	err := w.opts.report.Run(node.App, func() error {
+		return w.fn(ctx, node.App)
+	})
+
+	if err != nil {
+		w.lock.Lock()
+		defer w.lock.Unlock()
+
+		if w.opts.keepGoing {
+			w.failed = append(w.failed, node.App)
+			return nil
+		}
+
+		w.stopped = true
+
+		return err
+	}
+
+	w.graph.UpdateStatus(node.Key, w.traversalConfig.targetAppStatus)
+
 	return nil
 }
 
@@ -0,0 +1,344 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testApps(deps map[string][]string) map[string]*interface{} {
+	apps := map[string]*interface{}{}
+	for name, d := range deps {
+		var v interface{} = map[string]interface{}{"depends_on": d}
+		apps[name] = &v
+	}
+
+	return apps
+}
+
+func TestInDependencyOrder_Serial(t *testing.T) {
+	apps := testApps(map[string][]string{
+		"squibby": {"goblin"},
+		"goblin":  {},
+		"wisp":    {},
+		"troll":   {"squibby", "wisp"},
+	})
+
+	tests := []struct {
+		name string
+		walk func(context.Context, map[string]*interface{}, func(context.Context, string) error, ...Option) error
+		want []string
+	}{
+		{
+			name: "dependency order",
+			walk: InDependencyOrder,
+			want: []string{"goblin", "squibby", "wisp", "troll"},
+		},
+		{
+			name: "reverse dependency order",
+			walk: InReversDependencyOrder,
+			want: []string{"troll", "squibby", "goblin", "wisp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := tt.walk(context.Background(), apps, func(ctx context.Context, name string) error {
+				got = append(got, name)
+				return nil
+			}, WithSerial(true))
+			if err != nil {
+				t.Fatalf("walk error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walk order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInDependencyOrder_Parallelism(t *testing.T) {
+	apps := testApps(map[string][]string{
+		"one":   {},
+		"two":   {},
+		"three": {},
+		"four":  {},
+		"five":  {},
+		"six":   {"one", "two", "three", "four", "five"},
+	})
+
+	tests := []struct {
+		name        string
+		parallelism int
+		wantMax     int
+	}{
+		{name: "limited", parallelism: 2, wantMax: 2},
+		{name: "one at a time", parallelism: 1, wantMax: 1},
+		{name: "unlimited", parallelism: 0, wantMax: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var running, max int
+			var done []string
+
+			err := InDependencyOrder(context.Background(), apps, func(ctx context.Context, name string) error {
+				mu.Lock()
+				running++
+				if running > max {
+					max = running
+				}
+				mu.Unlock()
+
+				time.Sleep(50 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				done = append(done, name)
+				mu.Unlock()
+
+				return nil
+			}, WithParallelism(tt.parallelism))
+			if err != nil {
+				t.Fatalf("InDependencyOrder() error = %v", err)
+			}
+
+			if max != tt.wantMax {
+				t.Errorf("max running = %d, want %d", max, tt.wantMax)
+			}
+
+			if len(done) != len(apps) || done[len(done)-1] != "six" {
+				t.Errorf("InDependencyOrder() order = %v", done)
+			}
+		})
+	}
+
+	t.Run("stop on failure", func(t *testing.T) {
+		var mu sync.Mutex
+		var done []string
+
+		// whichever app runs first fails, the ones waiting for their turn must not run
+		err := InDependencyOrder(context.Background(), apps, func(ctx context.Context, name string) error {
+			mu.Lock()
+			done = append(done, name)
+			first := len(done) == 1
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			if first {
+				return fmt.Errorf("can't deploy %s", name)
+			}
+
+			return nil
+		}, WithParallelism(1))
+		if err == nil {
+			t.Fatalf("InDependencyOrder() must fail")
+		}
+
+		if len(done) != 1 {
+			t.Errorf("apps run after failure: %v", done)
+		}
+	})
+}
+
+func TestInDependencyOrder_KeepGoing(t *testing.T) {
+	apps := testApps(map[string][]string{
+		"goblin":  {},
+		"squibby": {"goblin"},
+		"troll":   {"squibby"},
+		"wisp":    {},
+	})
+
+	tests := []struct {
+		name      string
+		keepGoing bool
+		serial    bool
+		want      map[string]string
+	}{
+		{
+			name:      "keep going",
+			keepGoing: true,
+			want: map[string]string{
+				"goblin":  ResultFailed,
+				"squibby": ResultSkipped,
+				"troll":   ResultSkipped,
+				"wisp":    ResultSucceeded,
+			},
+		},
+		{
+			name:      "keep going serial",
+			keepGoing: true,
+			serial:    true,
+			want: map[string]string{
+				"goblin":  ResultFailed,
+				"squibby": ResultSkipped,
+				"troll":   ResultSkipped,
+				"wisp":    ResultSucceeded,
+			},
+		},
+		{
+			name:   "stop on failure serial",
+			serial: true,
+			want: map[string]string{
+				"goblin":  ResultFailed,
+				"squibby": ResultSkipped,
+				"troll":   ResultSkipped,
+				"wisp":    ResultSkipped,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &Report{}
+
+			err := InDependencyOrder(context.Background(), apps, func(ctx context.Context, name string) error {
+				if name == "goblin" {
+					return fmt.Errorf("can't deploy %s", name)
+				}
+				return nil
+			}, WithKeepGoing(tt.keepGoing), WithSerial(tt.serial), WithReport(report))
+			if err == nil {
+				t.Fatalf("InDependencyOrder() must fail")
+			}
+
+			got := map[string]string{}
+			for _, res := range report.Results() {
+				got[res.Name] = res.Status
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInDependencyOrder_ReportParallelism(t *testing.T) {
+	apps := testApps(map[string][]string{
+		"goblin":  {},
+		"squibby": {},
+		"troll":   {},
+		"wisp":    {"goblin", "squibby", "troll"},
+	})
+
+	var mu sync.Mutex
+	var first string
+
+	report := &Report{}
+
+	err := InDependencyOrder(context.Background(), apps, func(ctx context.Context, name string) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(first) == 0 {
+			first = name
+			return fmt.Errorf("can't deploy %s", name)
+		}
+
+		return nil
+	}, WithParallelism(1), WithReport(report))
+	if err == nil {
+		t.Fatalf("InDependencyOrder() must fail")
+	}
+
+	got := map[string]string{}
+	for _, res := range report.Results() {
+		got[res.Name] = res.Status
+	}
+
+	want := map[string]string{}
+	for name := range apps {
+		want[name] = ResultSkipped
+	}
+	want[first] = ResultFailed
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	apps := testApps(map[string][]string{
+		"goblin":  {},
+		"squibby": {"goblin"},
+		"troll":   {"squibby"},
+		"wisp":    {},
+		"ogre":    {"wisp"},
+	})
+
+	tests := []struct {
+		name           string
+		targets        []string
+		withDeps       bool
+		withDependents bool
+		want           []string
+	}{
+		{
+			name:    "only targets",
+			targets: []string{"squibby", "wisp", "unknown"},
+			want:    []string{"squibby", "wisp"},
+		},
+		{
+			name:     "with deps",
+			targets:  []string{"troll"},
+			withDeps: true,
+			want:     []string{"goblin", "squibby", "troll"},
+		},
+		{
+			name:           "with dependents",
+			targets:        []string{"goblin"},
+			withDependents: true,
+			want:           []string{"goblin", "squibby", "troll"},
+		},
+		{
+			name:           "with deps and dependents",
+			targets:        []string{"squibby", "ogre"},
+			withDeps:       true,
+			withDependents: true,
+			want:           []string{"goblin", "ogre", "squibby", "troll", "wisp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Select(apps, tt.targets, tt.withDeps, tt.withDependents)
+
+			var names []string
+			for name := range got {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("Select() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraph_Cycles(t *testing.T) {
+	apps := testApps(map[string][]string{
+		"goblin":  {"troll"},
+		"squibby": {"goblin"},
+		"troll":   {"squibby"},
+		"wisp":    {"wisp"},
+		"ogre":    {"goblin", "missing"},
+	})
+
+	g := NewGraph(apps, AppStopped)
+
+	want := [][]string{{"goblin", "squibby", "troll"}, {"wisp"}}
+	if got := g.Cycles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cycles() = %v, want %v", got, want)
+	}
+
+	wantDangling := map[string][]string{"ogre": {"missing"}}
+	if !reflect.DeepEqual(g.Dangling, wantDangling) {
+		t.Errorf("Dangling = %v, want %v", g.Dangling, wantDangling)
+	}
+}
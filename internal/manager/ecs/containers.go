@@ -0,0 +1,54 @@
+package ecs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// containerNames returns sorted names of the containers built from the containers map of the app
+func (e *Manager) containerNames() []string {
+	var names []string
+	for name := range e.App.Containers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// containerRepository returns the image repository of the container from the containers map
+func (e *Manager) containerRepository(name string) string {
+	return fmt.Sprintf("%s-%s-%s", e.Project.Namespace, e.App.Name, name)
+}
+
+// containerPath returns the absolute path to the build context of the container from the containers map
+func (e *Manager) containerPath(name string) string {
+	p := e.App.Containers[name]
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(e.Project.RootDir, p)
+	}
+
+	return p
+}
+
+// containerImage returns the image the container from the containers map is deployed with
+func (e *Manager) containerImage(name string) string {
+	tag := e.Project.Tag
+	if len(tag) == 0 {
+		tag = fmt.Sprintf("%s-latest", e.Project.Env)
+	}
+
+	return fmt.Sprintf("%s/%s:%s", e.App.DockerRegistry, e.containerRepository(name), tag)
+}
+
+// containerImages returns images of the containers from the containers map by container name
+func (e *Manager) containerImages() map[string]string {
+	images := map[string]string{}
+	for name := range e.App.Containers {
+		images[name] = e.containerImage(name)
+	}
+
+	return images
+}
@@ -64,12 +64,20 @@ func (e *Manager) deployWithDocker(w io.Writer) error {
 		fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name),
 		"--image", e.App.Name,
 		e.App.Image,
+	}
+
+	for _, name := range e.containerNames() {
+		cmd = append(cmd, "--image", name, e.containerImage(name))
+	}
+
+	cmd = append(cmd,
 		"--diff",
 		"--timeout", strconv.Itoa(e.App.Timeout),
 		"--rollback",
+		"--no-deregister",
 		"-e", e.App.Name,
 		"DD_VERSION", e.Project.Tag,
-	}
+	)
 
 	cfg := container.Config{
 		AttachStdout: true,
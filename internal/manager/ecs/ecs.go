@@ -8,6 +8,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/hazelops/ize/internal/aws/utils"
@@ -58,6 +59,22 @@ func (e *Manager) prepare() {
 	if e.App.Timeout == 0 {
 		e.App.Timeout = 300
 	}
+
+	if len(e.App.DeploymentStrategy) == 0 {
+		e.App.DeploymentStrategy = strategyRolling
+	}
+
+	if e.App.BakeTime == 0 {
+		e.App.BakeTime = 300
+	}
+
+	if e.App.CanaryPercent == 0 {
+		e.App.CanaryPercent = 10
+	}
+
+	if e.App.KeepRevisions == 0 {
+		e.App.KeepRevisions = 5
+	}
 }
 
 // Deploy deploys app container to ECS via ECS deploy
@@ -105,17 +122,35 @@ func (e *Manager) Deploy(ui terminal.UI) error {
 			fmt.Sprintf("%s-%s", e.Project.Env, "latest"))
 	}
 
-	if e.Project.PreferRuntime == "native" {
+	switch {
+	case e.App.DeploymentStrategy == strategyBlueGreen || e.App.DeploymentStrategy == strategyCanary:
+		err := e.deployTrafficShift(s.TermOutput())
+		pterm.SetDefaultOutput(os.Stdout)
+		if err != nil {
+			return fmt.Errorf("unable to deploy app: %w", err)
+		}
+	case e.App.DeploymentStrategy != strategyRolling:
+		return fmt.Errorf("unknown deployment strategy: %s", e.App.DeploymentStrategy)
+	case e.Project.PreferRuntime == "native" || len(e.nativeOptions()) != 0:
+		if e.Project.PreferRuntime != "native" {
+			logrus.Debugf("%s is deployed natively because of %s", e.App.Name, strings.Join(e.nativeOptions(), ", "))
+		}
+
 		err := e.deployLocal(s.TermOutput())
 		pterm.SetDefaultOutput(os.Stdout)
 		if err != nil {
 			return fmt.Errorf("unable to deploy app: %w", err)
 		}
-	} else {
+	default:
 		err := e.deployWithDocker(s.TermOutput())
 		if err != nil {
 			return fmt.Errorf("unable to deploy app: %w", err)
 		}
+
+		// ecs-deploy keeps previous revisions for ize history and rollback, they are pruned like in the native deployment
+		if err = e.pruneDeployedRevisions(); err != nil {
+			return fmt.Errorf("unable to deploy app: %w", err)
+		}
 	}
 
 	s.Done()
@@ -125,6 +160,26 @@ func (e *Manager) Deploy(ui terminal.UI) error {
 	return nil
 }
 
+// nativeOptions returns the app options the docker runtime doesn't support.
+// ecs-deploy can only change images of the existing task definition.
+func (e *Manager) nativeOptions() []string {
+	var options []string
+
+	if len(e.App.TaskDefinitionFile) != 0 {
+		options = append(options, "task_definition_file")
+	}
+
+	if e.App.PreDeploy != nil {
+		options = append(options, "pre_deploy")
+	}
+
+	if e.App.CircuitBreaker {
+		options = append(options, "circuit_breaker")
+	}
+
+	return options
+}
+
 func (e *Manager) Redeploy(ui terminal.UI) error {
 	e.prepare()
 
@@ -146,7 +201,8 @@ func (e *Manager) Redeploy(ui terminal.UI) error {
 	s := sg.Add("%s: redeploying app container...", e.App.Name)
 	defer func() { s.Abort(); time.Sleep(50 * time.Millisecond) }()
 
-	if e.Project.PreferRuntime == "native" {
+	// the circuit breaker is enabled by the native deployment only
+	if e.Project.PreferRuntime == "native" || e.App.CircuitBreaker {
 		err := e.redeployLocal(s.TermOutput())
 		pterm.SetDefaultOutput(os.Stdout)
 		if err != nil {
@@ -177,13 +233,31 @@ func (e *Manager) Push(ui terminal.UI) error {
 
 	if len(e.App.Image) != 0 {
 		s.Update("%s: pushing app image... (skipped, using %s) ", e.App.Name, e.App.Image)
-		s.Done()
+	} else {
+		err := e.pushImage(s, fmt.Sprintf("%s-%s", e.Project.Namespace, e.App.Name))
+		if err != nil {
+			return err
+		}
+	}
 
-		return nil
+	s.Done()
+
+	for _, name := range e.containerNames() {
+		s = sg.Add("%s: push %s container image...", e.App.Name, name)
+
+		err := e.pushImage(s, e.containerRepository(name))
+		if err != nil {
+			return fmt.Errorf("%s container: %w", name, err)
+		}
+
+		s.Done()
 	}
 
-	image := fmt.Sprintf("%s-%s", e.Project.Namespace, e.App.Name)
+	return nil
+}
 
+// pushImage pushes the image to the ECR repository with the same name, creating the repository if needed
+func (e *Manager) pushImage(s terminal.Step, image string) error {
 	svc := e.Project.AWSClient.ECRClient
 
 	var repository *ecr.Repository
@@ -248,8 +322,6 @@ func (e *Manager) Push(ui terminal.UI) error {
 		return fmt.Errorf("can't push image: %w", err)
 	}
 
-	s.Done()
-
 	return nil
 }
 
@@ -264,15 +336,34 @@ func (e *Manager) Build(ui terminal.UI) error {
 
 	if len(e.App.Image) != 0 {
 		s.Update("%s: building app container... (skipped, using %s)", e.App.Name, e.App.Image)
+	} else {
+		err := e.buildImage(ui, s, e.App.Name, e.App.Path, fmt.Sprintf("%s-%s", e.Project.Namespace, e.App.Name))
+		if err != nil {
+			return err
+		}
+	}
+
+	s.Done()
+
+	for _, name := range e.containerNames() {
+		s = sg.Add("%s: building %s container...", e.App.Name, name)
+
+		err := e.buildImage(ui, s, name, e.containerPath(name), e.containerRepository(name))
+		if err != nil {
+			return fmt.Errorf("%s container: %w", name, err)
+		}
 
 		s.Done()
-		return nil
 	}
 
-	image := fmt.Sprintf("%s-%s", e.Project.Namespace, e.App.Name)
+	return nil
+}
+
+// buildImage builds the image from the Dockerfile in the path
+func (e *Manager) buildImage(ui terminal.UI, s terminal.Step, name string, appPath string, image string) error {
 	imageUri := fmt.Sprintf("%s/%s", e.App.DockerRegistry, image)
 
-	relProjectPath, err := filepath.Rel(e.Project.RootDir, e.App.Path)
+	relProjectPath, err := filepath.Rel(e.Project.RootDir, appPath)
 	if err != nil {
 		return fmt.Errorf("unable to get relative path: %w", err)
 	}
@@ -280,7 +371,7 @@ func (e *Manager) Build(ui terminal.UI) error {
 	buildArgs := map[string]*string{
 		"PROJECT_PATH": &relProjectPath,
 		"APP_PATH":     &relProjectPath,
-		"APP_NAME":     &e.App.Name,
+		"APP_NAME":     &name,
 	}
 
 	tags := []string{
@@ -289,7 +380,7 @@ func (e *Manager) Build(ui terminal.UI) error {
 		fmt.Sprintf("%s:%s", imageUri, fmt.Sprintf("%s-latest", e.Project.Env)),
 	}
 
-	dockerfile := path.Join(e.App.Path, "Dockerfile")
+	dockerfile := path.Join(appPath, "Dockerfile")
 
 	cache := []string{fmt.Sprintf("%s:%s", imageUri, fmt.Sprintf("%s-latest", e.Project.Env))}
 
@@ -311,8 +402,6 @@ func (e *Manager) Build(ui terminal.UI) error {
 		return fmt.Errorf("unable to build image: %w", err)
 	}
 
-	s.Done()
-
 	return nil
 }
 
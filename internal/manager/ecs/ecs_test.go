@@ -1,15 +1,23 @@
 package ecs
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/golang/mock/gomock"
 	"github.com/hazelops/ize/internal/config"
 	"github.com/hazelops/ize/internal/generate"
@@ -143,14 +151,16 @@ func TestManager_Deploy(t *testing.T) {
 		ui terminal.UI
 	}
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		wantErr bool
-		env     map[string]string
-		mockCWL func(m *mocks.MockCloudWatchLogsAPI)
-		mockECS func(m *mocks.MockECSAPI)
-		mockELB func(m *mocks.MockELBV2API)
+		name          string
+		fields        fields
+		args          args
+		wantErr       bool
+		env           map[string]string
+		preferRuntime string
+		mockCWL       func(m *mocks.MockCloudWatchLogsAPI)
+		mockECS       func(m *mocks.MockECSAPI)
+		mockELB       func(m *mocks.MockELBV2API)
+		mockSSM       func(m *mocks.MockSSMAPI)
 	}{
 		{
 			name: "success",
@@ -218,9 +228,7 @@ func TestManager_Deploy(t *testing.T) {
 						},
 					},
 				}, nil).Times(1)
-				m.EXPECT().DeregisterTaskDefinition(gomock.Any()).Return(&ecs.DeregisterTaskDefinitionOutput{
-					TaskDefinition: &ecs.TaskDefinition{},
-				}, nil).Times(1)
+				m.EXPECT().ListTaskDefinitionsPages(gomock.Any(), gomock.Any()).Return(nil).Times(1)
 				m.EXPECT().RegisterTaskDefinition(gomock.Any()).Return(&ecs.RegisterTaskDefinitionOutput{
 					TaskDefinition: &ecs.TaskDefinition{
 						Family:            aws.String("test"),
@@ -299,9 +307,7 @@ func TestManager_Deploy(t *testing.T) {
 						},
 					},
 				}, nil).Times(1)
-				m.EXPECT().DeregisterTaskDefinition(gomock.Any()).Return(&ecs.DeregisterTaskDefinitionOutput{
-					TaskDefinition: &ecs.TaskDefinition{},
-				}, nil).Times(1)
+				m.EXPECT().ListTaskDefinitionsPages(gomock.Any(), gomock.Any()).Return(nil).Times(1)
 				m.EXPECT().RegisterTaskDefinition(gomock.Any()).Return(&ecs.RegisterTaskDefinitionOutput{
 					TaskDefinition: &ecs.TaskDefinition{
 						Family:            aws.String("test"),
@@ -387,9 +393,7 @@ func TestManager_Deploy(t *testing.T) {
 						},
 					},
 				}, nil).Times(1)
-				m.EXPECT().DeregisterTaskDefinition(gomock.Any()).Return(&ecs.DeregisterTaskDefinitionOutput{
-					TaskDefinition: &ecs.TaskDefinition{},
-				}, nil).Times(1)
+				m.EXPECT().ListTaskDefinitionsPages(gomock.Any(), gomock.Any()).Return(nil).Times(1)
 			},
 			mockELB: func(m *mocks.MockELBV2API) {
 				m.EXPECT().DescribeTargetGroups(gomock.Any()).Return(&elbv2.DescribeTargetGroupsOutput{
@@ -507,6 +511,58 @@ func TestManager_Deploy(t *testing.T) {
 			mockELB: func(m *mocks.MockELBV2API) {},
 			wantErr: true,
 		},
+		{
+			name: "pre_deploy with docker runtime",
+			fields: fields{
+				Project: new(config.Project),
+				App: &config.Ecs{
+					Name:      "goblin",
+					PreDeploy: &config.PreDeploy{Command: []string{"bin/migrate"}},
+				},
+			},
+			args: args{ui: terminal.ConsoleUI(context.TODO(), true)},
+			env: map[string]string{
+				"ENV":         "test",
+				"AWS_PROFILE": "test",
+				"AWS_REGION":  "test",
+				"NAMESPACE":   "test",
+			},
+			preferRuntime: "docker",
+			mockCWL:       func(m *mocks.MockCloudWatchLogsAPI) {},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+					Services: []*ecs.Service{{TaskDefinition: aws.String("test-arn")}},
+				}, nil).Times(1)
+				m.EXPECT().ListTaskDefinitions(gomock.Any()).Return(&ecs.ListTaskDefinitionsOutput{
+					TaskDefinitionArns: []*string{aws.String("test-arn")},
+				}, nil).Times(1)
+				m.EXPECT().DescribeTaskDefinition(gomock.Any()).Return(&ecs.DescribeTaskDefinitionOutput{
+					TaskDefinition: &ecs.TaskDefinition{
+						ContainerDefinitions: []*ecs.ContainerDefinition{{
+							Image: aws.String("test"),
+							Name:  aws.String("goblin"),
+						}},
+						Family:            aws.String("test-goblin"),
+						Revision:          aws.Int64(1),
+						TaskDefinitionArn: aws.String("test-arn"),
+					},
+				}, nil).Times(1)
+				m.EXPECT().RegisterTaskDefinition(gomock.Any()).Return(&ecs.RegisterTaskDefinitionOutput{
+					TaskDefinition: &ecs.TaskDefinition{
+						Family:            aws.String("test-goblin"),
+						Revision:          aws.Int64(2),
+						TaskDefinitionArn: aws.String("test-goblin:2"),
+					},
+				}, nil).Times(1)
+				// the pre-deploy task can't be started, so the new revision isn't deployed
+				m.EXPECT().DeregisterTaskDefinition(gomock.Any()).Return(&ecs.DeregisterTaskDefinitionOutput{}, nil).Times(1)
+			},
+			mockELB: func(m *mocks.MockELBV2API) {},
+			mockSSM: func(m *mocks.MockSSMAPI) {
+				m.EXPECT().GetParameter(gomock.Any()).Return(nil, awserr.New(ssm.ErrCodeParameterNotFound, "", nil)).Times(1)
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -538,18 +594,26 @@ func TestManager_Deploy(t *testing.T) {
 			mockECSAPI := mocks.NewMockECSAPI(ctrl)
 			mockCWLAPI := mocks.NewMockCloudWatchLogsAPI(ctrl)
 			mockELBAPI := mocks.NewMockELBV2API(ctrl)
+			mockSSMAPI := mocks.NewMockSSMAPI(ctrl)
 			tt.mockECS(mockECSAPI)
 			tt.mockCWL(mockCWLAPI)
 			tt.mockELB(mockELBAPI)
+			if tt.mockSSM != nil {
+				tt.mockSSM(mockSSMAPI)
+			}
 
 			config.InitConfig()
-			tt.fields.Project.AWSClient = config.NewAWSClient(config.WithECSClient(mockECSAPI), config.WithCloudWatchLogsClient(mockCWLAPI), config.WithELBV2Client(mockELBAPI))
+			tt.fields.Project.AWSClient = config.NewAWSClient(config.WithECSClient(mockECSAPI), config.WithCloudWatchLogsClient(mockCWLAPI), config.WithELBV2Client(mockELBAPI), config.WithSSMClient(mockSSMAPI))
 			err = tt.fields.Project.GetTestConfig()
 			if err != nil {
 				t.Error(err)
 				return
 			}
 
+			if len(tt.preferRuntime) != 0 {
+				tt.fields.Project.PreferRuntime = tt.preferRuntime
+			}
+
 			e := &Manager{
 				Project: tt.fields.Project,
 				App:     tt.fields.App,
@@ -562,6 +626,26 @@ func TestManager_Deploy(t *testing.T) {
 	}
 }
 
+func TestManager_nativeOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		app  *config.Ecs
+		want []string
+	}{
+		{name: "images only", app: &config.Ecs{Name: "goblin"}},
+		{name: "pre_deploy", app: &config.Ecs{Name: "goblin", PreDeploy: &config.PreDeploy{Command: []string{"migrate"}}}, want: []string{"pre_deploy"}},
+		{name: "circuit_breaker and task_definition_file", app: &config.Ecs{Name: "goblin", CircuitBreaker: true, TaskDefinitionFile: "td.json"}, want: []string{"task_definition_file", "circuit_breaker"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Manager{Project: new(config.Project), App: tt.app}
+			if got := e.nativeOptions(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("nativeOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestManager_Redeploy(t *testing.T) {
 	type fields struct {
 		Project *config.Project
@@ -1038,3 +1122,1315 @@ func TestManager_Destroy(t *testing.T) {
 		})
 	}
 }
+
+func TestManager_Plan(t *testing.T) {
+	appPath := t.TempDir()
+	err := os.WriteFile(filepath.Join(appPath, "task-definition.json"), []byte(`{
+  "family": "{{.Env}}-{{.Name}}",
+  "cpu": "512",
+  "containerDefinitions": [
+    {"name": "goblin", "image": "{{.Image}}"},
+    {"name": "datadog", "image": "datadog:{{.Tag}}"}
+  ]
+}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		app         *config.Ecs
+		mockECS     func(m *mocks.MockECSAPI)
+		wantChanged bool
+		wantImages  []ImageChange
+		wantChanges []string
+		wantErr     bool
+	}{
+		{
+			name: "image changed",
+			app:  &config.Ecs{Name: "goblin", Image: "goblin:new"},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+					Services: []*ecs.Service{{TaskDefinition: aws.String("test-arn")}},
+				}, nil).Times(1)
+				m.EXPECT().ListTaskDefinitions(gomock.Any()).Return(&ecs.ListTaskDefinitionsOutput{
+					TaskDefinitionArns: []*string{aws.String("test-arn")},
+				}, nil).Times(1)
+				m.EXPECT().DescribeTaskDefinition(gomock.Any()).Return(&ecs.DescribeTaskDefinitionOutput{
+					TaskDefinition: &ecs.TaskDefinition{
+						ContainerDefinitions: []*ecs.ContainerDefinition{
+							{Image: aws.String("goblin:old"), Name: aws.String("goblin")},
+							{Image: aws.String("datadog"), Name: aws.String("datadog")},
+						},
+						Family:            aws.String("test-goblin"),
+						Revision:          aws.Int64(3),
+						TaskDefinitionArn: aws.String("test-arn"),
+					},
+				}, nil).Times(1)
+			},
+			wantChanged: true,
+		},
+		{
+			name: "no changes",
+			app:  &config.Ecs{Name: "goblin", Image: "goblin:old"},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+					Services: []*ecs.Service{{TaskDefinition: aws.String("test-arn")}},
+				}, nil).Times(1)
+				m.EXPECT().ListTaskDefinitions(gomock.Any()).Return(&ecs.ListTaskDefinitionsOutput{
+					TaskDefinitionArns: []*string{aws.String("test-arn")},
+				}, nil).Times(1)
+				m.EXPECT().DescribeTaskDefinition(gomock.Any()).Return(&ecs.DescribeTaskDefinitionOutput{
+					TaskDefinition: &ecs.TaskDefinition{
+						ContainerDefinitions: []*ecs.ContainerDefinition{
+							{Image: aws.String("goblin:old"), Name: aws.String("goblin")},
+						},
+						Family:            aws.String("test-goblin"),
+						Revision:          aws.Int64(3),
+						TaskDefinitionArn: aws.String("test-arn"),
+					},
+				}, nil).Times(1)
+			},
+			wantChanged: false,
+		},
+		{
+			name: "containers",
+			app:  &config.Ecs{Name: "goblin", Image: "goblin:old", DockerRegistry: "registry", Containers: map[string]string{"nginx": "apps/nginx"}},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+					Services: []*ecs.Service{{TaskDefinition: aws.String("test-arn")}},
+				}, nil).Times(1)
+				m.EXPECT().ListTaskDefinitions(gomock.Any()).Return(&ecs.ListTaskDefinitionsOutput{
+					TaskDefinitionArns: []*string{aws.String("test-arn")},
+				}, nil).Times(1)
+				m.EXPECT().DescribeTaskDefinition(gomock.Any()).Return(&ecs.DescribeTaskDefinitionOutput{
+					TaskDefinition: &ecs.TaskDefinition{
+						ContainerDefinitions: []*ecs.ContainerDefinition{
+							{Image: aws.String("goblin:old"), Name: aws.String("goblin")},
+							{Image: aws.String("registry/test-goblin-nginx:v0"), Name: aws.String("nginx")},
+							{Image: aws.String("datadog"), Name: aws.String("datadog")},
+						},
+						Family:            aws.String("test-goblin"),
+						Revision:          aws.Int64(3),
+						TaskDefinitionArn: aws.String("test-arn"),
+					},
+				}, nil).Times(1)
+			},
+			wantChanged: true,
+			wantImages: []ImageChange{
+				{Container: "goblin", Before: "goblin:old", After: "goblin:old"},
+				{Container: "nginx", Before: "registry/test-goblin-nginx:v0", After: "registry/test-goblin-nginx:v1"},
+				{Container: "datadog", Before: "datadog", After: "datadog"},
+			},
+		},
+		{
+			name: "task definition file",
+			app:  &config.Ecs{Name: "goblin", Image: "goblin:old", Path: appPath, TaskDefinitionFile: "task-definition.json"},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+					Services: []*ecs.Service{{TaskDefinition: aws.String("test-arn")}},
+				}, nil).Times(1)
+				m.EXPECT().ListTaskDefinitions(gomock.Any()).Return(&ecs.ListTaskDefinitionsOutput{
+					TaskDefinitionArns: []*string{aws.String("test-arn")},
+				}, nil).Times(1)
+				m.EXPECT().DescribeTaskDefinition(gomock.Any()).Return(&ecs.DescribeTaskDefinitionOutput{
+					TaskDefinition: &ecs.TaskDefinition{
+						ContainerDefinitions: []*ecs.ContainerDefinition{
+							{Image: aws.String("goblin:old"), Name: aws.String("goblin")},
+							{Image: aws.String("datadog:v1"), Name: aws.String("datadog")},
+						},
+						Cpu:               aws.String("256"),
+						Family:            aws.String("test-goblin"),
+						Revision:          aws.Int64(3),
+						TaskDefinitionArn: aws.String("test-arn"),
+					},
+				}, nil).Times(1)
+			},
+			wantChanged: true,
+			wantChanges: []string{`-  "Cpu": "256",`, `+  "Cpu": "512",`},
+		},
+		{
+			name: "task definition file without changes",
+			app:  &config.Ecs{Name: "goblin", Image: "goblin:old", Path: appPath, TaskDefinitionFile: "task-definition.json"},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+					Services: []*ecs.Service{{TaskDefinition: aws.String("test-arn")}},
+				}, nil).Times(1)
+				m.EXPECT().ListTaskDefinitions(gomock.Any()).Return(&ecs.ListTaskDefinitionsOutput{
+					TaskDefinitionArns: []*string{aws.String("test-arn")},
+				}, nil).Times(1)
+				m.EXPECT().DescribeTaskDefinition(gomock.Any()).Return(&ecs.DescribeTaskDefinitionOutput{
+					TaskDefinition: &ecs.TaskDefinition{
+						ContainerDefinitions: []*ecs.ContainerDefinition{
+							{Image: aws.String("goblin:old"), Name: aws.String("goblin")},
+							{Image: aws.String("datadog:v1"), Name: aws.String("datadog")},
+						},
+						Cpu:               aws.String("512"),
+						Family:            aws.String("test-goblin"),
+						Revision:          aws.Int64(3),
+						TaskDefinitionArn: aws.String("test-arn"),
+						Status:            aws.String("ACTIVE"),
+					},
+				}, nil).Times(1)
+			},
+			wantChanged: false,
+		},
+		{
+			name: "service not found",
+			app:  &config.Ecs{Name: "goblin"},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{}, nil).Times(1)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "skip deploy",
+			app:     &config.Ecs{Name: "goblin", SkipDeploy: true},
+			mockECS: func(m *mocks.MockECSAPI) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockECSAPI := mocks.NewMockECSAPI(ctrl)
+			tt.mockECS(mockECSAPI)
+
+			e := &Manager{
+				Project: &config.Project{
+					Env:       "test",
+					Namespace: "test",
+					Tag:       "v1",
+					AWSClient: config.NewAWSClient(config.WithECSClient(mockECSAPI)),
+				},
+				App: tt.app,
+			}
+
+			got, err := e.Plan()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Plan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if got.Changed() != tt.wantChanged {
+				t.Errorf("Plan() changed = %v, want %v (%+v)", got.Changed(), tt.wantChanged, got.Images)
+			}
+
+			if tt.wantImages != nil && !reflect.DeepEqual(got.Images, tt.wantImages) {
+				t.Errorf("Plan() images = %+v, want %+v", got.Images, tt.wantImages)
+			}
+
+			for _, c := range tt.wantChanges {
+				if !strings.Contains(got.Changes, c) {
+					t.Errorf("Plan() changes = %s, want %s", got.Changes, c)
+				}
+			}
+		})
+	}
+}
+
+func TestManager_deployTrafficShift(t *testing.T) {
+	trafficShiftPollInterval = 0
+
+	service := &ecs.Service{
+		DeploymentController: &ecs.DeploymentController{Type: aws.String(ecs.DeploymentControllerTypeExternal)},
+		TaskSets: []*ecs.TaskSet{
+			{
+				Status:         aws.String("ACTIVE"),
+				TaskSetArn:     aws.String("old-ts"),
+				TaskDefinition: aws.String("old-td"),
+			},
+			{
+				Status:         aws.String("PRIMARY"),
+				TaskSetArn:     aws.String("primary-ts"),
+				TaskDefinition: aws.String("primary-td"),
+				LaunchType:     aws.String(ecs.LaunchTypeFargate),
+				LoadBalancers: []*ecs.LoadBalancer{{
+					TargetGroupArn: aws.String("tg-blue"),
+					ContainerName:  aws.String("goblin"),
+					ContainerPort:  aws.Int64(3000),
+				}},
+			},
+		},
+	}
+
+	healthy := &elbv2.DescribeTargetHealthOutput{
+		TargetHealthDescriptions: []*elbv2.TargetHealthDescription{
+			{TargetHealth: &elbv2.TargetHealth{State: aws.String(elbv2.TargetHealthStateEnumHealthy)}},
+		},
+	}
+	unhealthy := &elbv2.DescribeTargetHealthOutput{
+		TargetHealthDescriptions: []*elbv2.TargetHealthDescription{
+			{TargetHealth: &elbv2.TargetHealth{State: aws.String(elbv2.TargetHealthStateEnumUnhealthy)}},
+		},
+	}
+
+	// actions of the listener are returned anew on each call as shiftTraffic changes them
+	listenerActions := func() []*elbv2.Action {
+		return []*elbv2.Action{
+			{
+				Type:  aws.String(elbv2.ActionTypeEnumAuthenticateOidc),
+				Order: aws.Int64(1),
+				AuthenticateOidcConfig: &elbv2.AuthenticateOidcActionConfig{
+					ClientId: aws.String("goblin"),
+					Issuer:   aws.String("https://idp.example.com"),
+				},
+			},
+			{
+				Type:           aws.String(elbv2.ActionTypeEnumForward),
+				Order:          aws.Int64(2),
+				TargetGroupArn: aws.String("tg-blue"),
+				ForwardConfig: &elbv2.ForwardActionConfig{
+					TargetGroups:                []*elbv2.TargetGroupTuple{{TargetGroupArn: aws.String("tg-blue"), Weight: aws.Int64(1)}},
+					TargetGroupStickinessConfig: &elbv2.TargetGroupStickinessConfig{Enabled: aws.Bool(true), DurationSeconds: aws.Int64(300)},
+				},
+			},
+		}
+	}
+	mockListener := func(m *mocks.MockELBV2API, times int) {
+		m.EXPECT().DescribeListeners(gomock.Any()).DoAndReturn(func(input *elbv2.DescribeListenersInput) (*elbv2.DescribeListenersOutput, error) {
+			return &elbv2.DescribeListenersOutput{Listeners: []*elbv2.Listener{{ListenerArn: input.ListenerArns[0], DefaultActions: listenerActions()}}}, nil
+		}).Times(times)
+	}
+
+	mockRegister := func(m *mocks.MockECSAPI) {
+		m.EXPECT().DescribeTaskDefinition(gomock.Any()).Return(&ecs.DescribeTaskDefinitionOutput{
+			TaskDefinition: &ecs.TaskDefinition{
+				ContainerDefinitions: []*ecs.ContainerDefinition{{
+					Image: aws.String("goblin:old"),
+					Name:  aws.String("goblin"),
+				}},
+				Family:            aws.String("test-goblin"),
+				Revision:          aws.Int64(1),
+				TaskDefinitionArn: aws.String("primary-td"),
+			},
+		}, nil).Times(1)
+		m.EXPECT().RegisterTaskDefinition(gomock.Any()).Return(&ecs.RegisterTaskDefinitionOutput{
+			TaskDefinition: &ecs.TaskDefinition{
+				Family:            aws.String("test-goblin"),
+				Revision:          aws.Int64(2),
+				TaskDefinitionArn: aws.String("new-td"),
+			},
+		}, nil).Times(1)
+		m.EXPECT().CreateTaskSet(gomock.Any()).DoAndReturn(func(input *ecs.CreateTaskSetInput) (*ecs.CreateTaskSetOutput, error) {
+			if *input.LoadBalancers[0].TargetGroupArn != "tg-green" {
+				t.Errorf("task set created in %s, want tg-green", *input.LoadBalancers[0].TargetGroupArn)
+			}
+			return &ecs.CreateTaskSetOutput{TaskSet: &ecs.TaskSet{TaskSetArn: aws.String("new-ts"), Id: aws.String("new")}}, nil
+		}).Times(1)
+		m.EXPECT().DescribeTaskSets(gomock.Any()).Return(&ecs.DescribeTaskSetsOutput{
+			TaskSets: []*ecs.TaskSet{{StabilityStatus: aws.String(ecs.StabilityStatusSteadyState)}},
+		}, nil).Times(1)
+	}
+
+	tests := []struct {
+		name    string
+		app     *config.Ecs
+		mockECS func(m *mocks.MockECSAPI)
+		mockELB func(m *mocks.MockELBV2API)
+		wantErr bool
+	}{
+		{
+			name: "canary success",
+			app: &config.Ecs{
+				Name:               "goblin",
+				Image:              "goblin:new",
+				Timeout:            60,
+				DeploymentStrategy: "canary",
+				CanaryPercent:      10,
+				ListenerArn:        "arn:aws:elasticloadbalancing:us-east-1:0:listener/app/test/1/1",
+				TargetGroupArns:    []string{"tg-blue", "tg-green"},
+			},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+					Services: []*ecs.Service{service},
+				}, nil).Times(1)
+				mockRegister(m)
+				m.EXPECT().UpdateServicePrimaryTaskSet(gomock.Any()).Return(&ecs.UpdateServicePrimaryTaskSetOutput{}, nil).Times(1)
+				m.EXPECT().DeleteTaskSet(gomock.Any()).Return(&ecs.DeleteTaskSetOutput{}, nil).Times(1)
+				m.EXPECT().ListTaskDefinitionsPages(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			mockELB: func(m *mocks.MockELBV2API) {
+				m.EXPECT().DescribeTargetHealth(gomock.Any()).Return(healthy, nil).Times(3)
+				mockListener(m, 2)
+				m.EXPECT().ModifyListener(gomock.Any()).DoAndReturn(func(input *elbv2.ModifyListenerInput) (*elbv2.ModifyListenerOutput, error) {
+					if len(input.DefaultActions) != 2 {
+						t.Fatalf("listener modified with %d actions, want 2", len(input.DefaultActions))
+					}
+					auth, forward := input.DefaultActions[0], input.DefaultActions[1]
+					if *auth.Type != elbv2.ActionTypeEnumAuthenticateOidc || !aws.BoolValue(auth.AuthenticateOidcConfig.UseExistingClientSecret) {
+						t.Errorf("authenticate-oidc action isn't kept: %v", auth)
+					}
+					if forward.TargetGroupArn != nil || forward.ForwardConfig.TargetGroupStickinessConfig == nil || !*forward.ForwardConfig.TargetGroupStickinessConfig.Enabled {
+						t.Errorf("forward action stickiness isn't kept: %v", forward)
+					}
+					if tgs := forward.ForwardConfig.TargetGroups; len(tgs) != 2 || *tgs[0].TargetGroupArn != "tg-blue" || *tgs[1].TargetGroupArn != "tg-green" {
+						t.Errorf("forward action target groups = %v, want tg-blue and tg-green", tgs)
+					}
+					return &elbv2.ModifyListenerOutput{}, nil
+				}).Times(2)
+			},
+		},
+		{
+			name: "blue/green rollback on unhealthy targets",
+			app: &config.Ecs{
+				Name:               "goblin",
+				Image:              "goblin:new",
+				Timeout:            60,
+				DeploymentStrategy: "blue_green",
+				ListenerArn:        "arn:aws:elasticloadbalancing:us-east-1:0:listener-rule/app/test/1/1/1",
+				TargetGroupArns:    []string{"tg-green", "tg-blue"},
+			},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+					Services: []*ecs.Service{service},
+				}, nil).Times(1)
+				mockRegister(m)
+				m.EXPECT().DeleteTaskSet(gomock.Any()).Return(&ecs.DeleteTaskSetOutput{}, nil).Times(1)
+				m.EXPECT().DeregisterTaskDefinition(gomock.Any()).Return(&ecs.DeregisterTaskDefinitionOutput{}, nil).Times(1)
+			},
+			mockELB: func(m *mocks.MockELBV2API) {
+				m.EXPECT().DescribeTargetHealth(gomock.Any()).Return(healthy, nil).Times(1)
+				m.EXPECT().DescribeTargetHealth(gomock.Any()).Return(unhealthy, nil).Times(1)
+				m.EXPECT().DescribeRules(gomock.Any()).DoAndReturn(func(input *elbv2.DescribeRulesInput) (*elbv2.DescribeRulesOutput, error) {
+					return &elbv2.DescribeRulesOutput{Rules: []*elbv2.Rule{{RuleArn: input.RuleArns[0], Actions: listenerActions()}}}, nil
+				}).Times(2)
+				m.EXPECT().ModifyRule(gomock.Any()).DoAndReturn(func(input *elbv2.ModifyRuleInput) (*elbv2.ModifyRuleOutput, error) {
+					if len(input.Actions) != 2 || *input.Actions[0].Type != elbv2.ActionTypeEnumAuthenticateOidc {
+						t.Errorf("rule modified with actions %v, want authenticate-oidc and forward", input.Actions)
+					}
+					return &elbv2.ModifyRuleOutput{}, nil
+				}).Times(2)
+			},
+			wantErr: true,
+		},
+		{
+			name: "canary rollback when traffic can't be shifted",
+			app: &config.Ecs{
+				Name:               "goblin",
+				Image:              "goblin:new",
+				Timeout:            60,
+				DeploymentStrategy: "canary",
+				CanaryPercent:      10,
+				ListenerArn:        "arn:aws:elasticloadbalancing:us-east-1:0:listener/app/test/1/1",
+				TargetGroupArns:    []string{"tg-blue", "tg-green"},
+			},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+					Services: []*ecs.Service{service},
+				}, nil).Times(1)
+				mockRegister(m)
+				m.EXPECT().DeleteTaskSet(gomock.Any()).Return(&ecs.DeleteTaskSetOutput{}, nil).Times(1)
+				m.EXPECT().DeregisterTaskDefinition(gomock.Any()).Return(&ecs.DeregisterTaskDefinitionOutput{}, nil).Times(1)
+			},
+			mockELB: func(m *mocks.MockELBV2API) {
+				m.EXPECT().DescribeTargetHealth(gomock.Any()).Return(healthy, nil).Times(2)
+				mockListener(m, 3)
+				m.EXPECT().ModifyListener(gomock.Any()).Return(&elbv2.ModifyListenerOutput{}, nil).Times(1)
+				m.EXPECT().ModifyListener(gomock.Any()).Return(nil, awserr.New(elbv2.ErrCodeListenerNotFoundException, "", nil)).Times(1)
+				m.EXPECT().ModifyListener(gomock.Any()).DoAndReturn(func(input *elbv2.ModifyListenerInput) (*elbv2.ModifyListenerOutput, error) {
+					if w := *input.DefaultActions[1].ForwardConfig.TargetGroups[0].Weight; w != 100 {
+						t.Errorf("traffic shifted back with blue weight %d, want 100", w)
+					}
+					return &elbv2.ModifyListenerOutput{}, nil
+				}).Times(1)
+			},
+			wantErr: true,
+		},
+		{
+			name: "rollback when previous task set can't be deleted",
+			app: &config.Ecs{
+				Name:               "goblin",
+				Image:              "goblin:new",
+				Timeout:            60,
+				DeploymentStrategy: "blue_green",
+				ListenerArn:        "arn:aws:elasticloadbalancing:us-east-1:0:listener/app/test/1/1",
+				TargetGroupArns:    []string{"tg-blue", "tg-green"},
+			},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+					Services: []*ecs.Service{service},
+				}, nil).Times(1)
+				mockRegister(m)
+				m.EXPECT().UpdateServicePrimaryTaskSet(gomock.Any()).Return(&ecs.UpdateServicePrimaryTaskSetOutput{}, nil).Times(1)
+				m.EXPECT().DeleteTaskSet(gomock.Any()).Return(nil, awserr.New(ecs.ErrCodeServerException, "", nil)).Times(1)
+				m.EXPECT().UpdateServicePrimaryTaskSet(gomock.Any()).DoAndReturn(func(input *ecs.UpdateServicePrimaryTaskSetInput) (*ecs.UpdateServicePrimaryTaskSetOutput, error) {
+					if *input.PrimaryTaskSet != "primary-ts" {
+						t.Errorf("primary task set restored to %s, want primary-ts", *input.PrimaryTaskSet)
+					}
+					return &ecs.UpdateServicePrimaryTaskSetOutput{}, nil
+				}).Times(1)
+				m.EXPECT().DeleteTaskSet(gomock.Any()).Return(&ecs.DeleteTaskSetOutput{}, nil).Times(1)
+				m.EXPECT().DeregisterTaskDefinition(gomock.Any()).Return(&ecs.DeregisterTaskDefinitionOutput{}, nil).Times(1)
+			},
+			mockELB: func(m *mocks.MockELBV2API) {
+				m.EXPECT().DescribeTargetHealth(gomock.Any()).Return(healthy, nil).Times(2)
+				mockListener(m, 2)
+				m.EXPECT().ModifyListener(gomock.Any()).Return(&elbv2.ModifyListenerOutput{}, nil).Times(2)
+			},
+			wantErr: true,
+		},
+		{
+			name: "rolling deployment controller",
+			app: &config.Ecs{
+				Name:               "goblin",
+				DeploymentStrategy: "blue_green",
+				ListenerArn:        "arn:aws:elasticloadbalancing:us-east-1:0:listener/app/test/1/1",
+				TargetGroupArns:    []string{"tg-blue", "tg-green"},
+			},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+					Services: []*ecs.Service{{
+						DeploymentController: &ecs.DeploymentController{Type: aws.String(ecs.DeploymentControllerTypeEcs)},
+					}},
+				}, nil).Times(1)
+			},
+			mockELB: func(m *mocks.MockELBV2API) {},
+			wantErr: true,
+		},
+		{
+			name: "missing target groups",
+			app: &config.Ecs{
+				Name:               "goblin",
+				DeploymentStrategy: "canary",
+				ListenerArn:        "arn:aws:elasticloadbalancing:us-east-1:0:listener/app/test/1/1",
+			},
+			mockECS: func(m *mocks.MockECSAPI) {},
+			mockELB: func(m *mocks.MockELBV2API) {},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockECSAPI := mocks.NewMockECSAPI(ctrl)
+			mockELBAPI := mocks.NewMockELBV2API(ctrl)
+			tt.mockECS(mockECSAPI)
+			tt.mockELB(mockELBAPI)
+
+			e := &Manager{
+				Project: &config.Project{
+					Env:       "test",
+					Namespace: "test",
+					AWSClient: config.NewAWSClient(config.WithECSClient(mockECSAPI), config.WithELBV2Client(mockELBAPI)),
+				},
+				App: tt.app,
+			}
+
+			if err := e.deployTrafficShift(os.Stdout); (err != nil) != tt.wantErr {
+				t.Errorf("deployTrafficShift() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_registerTaskDefinition(t *testing.T) {
+	source := &ecs.TaskDefinition{
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Image: aws.String("goblin:old"), Name: aws.String("goblin")},
+			{Image: aws.String("envoy:v1"), Name: aws.String("envoy")},
+		},
+		Cpu:              aws.String("256"),
+		EphemeralStorage: &ecs.EphemeralStorage{SizeInGiB: aws.Int64(50)},
+		ExecutionRoleArn: aws.String("execution-role"),
+		Family:           aws.String("test-goblin"),
+		InferenceAccelerators: []*ecs.InferenceAccelerator{
+			{DeviceName: aws.String("device"), DeviceType: aws.String("eia2.medium")},
+		},
+		IpcMode:     aws.String(ecs.IpcModeTask),
+		Memory:      aws.String("512"),
+		NetworkMode: aws.String(ecs.NetworkModeAwsvpc),
+		PidMode:     aws.String(ecs.PidModeTask),
+		PlacementConstraints: []*ecs.TaskDefinitionPlacementConstraint{
+			{Type: aws.String(ecs.TaskDefinitionPlacementConstraintTypeMemberOf), Expression: aws.String("attribute:ecs.availability-zone in [us-east-1a]")},
+		},
+		ProxyConfiguration: &ecs.ProxyConfiguration{
+			ContainerName: aws.String("envoy"),
+			Type:          aws.String(ecs.ProxyConfigurationTypeAppmesh),
+		},
+		RequiresCompatibilities: aws.StringSlice([]string{ecs.CompatibilityFargate}),
+		Revision:                aws.Int64(3),
+		RuntimePlatform:         &ecs.RuntimePlatform{CpuArchitecture: aws.String(ecs.CPUArchitectureArm64)},
+		Status:                  aws.String(ecs.TaskDefinitionStatusActive),
+		TaskDefinitionArn:       aws.String("test-arn"),
+		TaskRoleArn:             aws.String("task-role"),
+		Volumes:                 []*ecs.Volume{{Name: aws.String("data")}},
+	}
+	tags := []*ecs.Tag{{Key: aws.String("team"), Value: aws.String("goblins")}}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var got *ecs.RegisterTaskDefinitionInput
+
+	mockECSAPI := mocks.NewMockECSAPI(ctrl)
+	mockECSAPI.EXPECT().RegisterTaskDefinition(gomock.Any()).DoAndReturn(func(input *ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionOutput, error) {
+		got = input
+		return &ecs.RegisterTaskDefinitionOutput{
+			TaskDefinition: &ecs.TaskDefinition{Family: input.Family, Revision: aws.Int64(4)},
+		}, nil
+	}).Times(1)
+
+	e := &Manager{
+		Project: &config.Project{
+			Env:       "test",
+			Tag:       "new",
+			AWSClient: config.NewAWSClient(config.WithECSClient(mockECSAPI)),
+		},
+		App: &config.Ecs{Name: "goblin"},
+	}
+
+	_, err := e.registerTaskDefinition(source, tags)
+	if err != nil {
+		t.Fatalf("registerTaskDefinition() error = %v", err)
+	}
+
+	want := &ecs.RegisterTaskDefinitionInput{
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Image: aws.String("goblin:new"), Name: aws.String("goblin")},
+			{Image: aws.String("envoy:v1"), Name: aws.String("envoy")},
+		},
+		Cpu:                     source.Cpu,
+		EphemeralStorage:        source.EphemeralStorage,
+		ExecutionRoleArn:        source.ExecutionRoleArn,
+		Family:                  source.Family,
+		InferenceAccelerators:   source.InferenceAccelerators,
+		IpcMode:                 source.IpcMode,
+		Memory:                  source.Memory,
+		NetworkMode:             source.NetworkMode,
+		PidMode:                 source.PidMode,
+		PlacementConstraints:    source.PlacementConstraints,
+		ProxyConfiguration:      source.ProxyConfiguration,
+		RequiresCompatibilities: source.RequiresCompatibilities,
+		RuntimePlatform:         source.RuntimePlatform,
+		Tags:                    tags,
+		TaskRoleArn:             source.TaskRoleArn,
+		Volumes:                 source.Volumes,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("registerTaskDefinition() input = %v, want %v", got, want)
+	}
+}
+
+// TestRegisterInput guards against fields added to the task definition by SDK updates
+// that registerInput can't copy
+func TestRegisterInput(t *testing.T) {
+	src := reflect.TypeOf(ecs.TaskDefinition{})
+	dst := reflect.TypeOf(ecs.RegisterTaskDefinitionInput{})
+
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Field(i)
+		if !field.IsExported() || field.Name == "Tags" {
+			continue
+		}
+
+		f, ok := src.FieldByName(field.Name)
+		if !ok || f.Type != field.Type {
+			t.Errorf("field %s of RegisterTaskDefinitionInput is not copied from TaskDefinition", field.Name)
+		}
+	}
+}
+
+func TestRollout_watch(t *testing.T) {
+	since := time.Now()
+
+	tests := []struct {
+		name           string
+		circuitBreaker bool
+		deployment     string
+		service        *ecs.Service
+		mockECS        func(m *mocks.MockECSAPI)
+		want           bool
+		wantErr        error
+	}{
+		{
+			name: "completed",
+			service: &ecs.Service{
+				Deployments: []*ecs.Deployment{
+					{Status: aws.String("PRIMARY"), RolloutState: aws.String(ecs.DeploymentRolloutStateCompleted)},
+				},
+				Events: []*ecs.ServiceEvent{
+					{Id: aws.String("2"), CreatedAt: aws.Time(since.Add(time.Minute)), Message: aws.String("(service test-goblin) has reached a steady state.")},
+					{Id: aws.String("1"), CreatedAt: aws.Time(since.Add(-time.Minute)), Message: aws.String("(service test-goblin) has started 1 tasks.")},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "in progress",
+			service: &ecs.Service{
+				Deployments: []*ecs.Deployment{
+					{Status: aws.String("PRIMARY"), RolloutState: aws.String(ecs.DeploymentRolloutStateInProgress), FailedTasks: aws.Int64(1)},
+					{Status: aws.String("ACTIVE"), RolloutState: aws.String(ecs.DeploymentRolloutStateCompleted)},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "completed with previous deployment draining",
+			service: &ecs.Service{
+				Deployments: []*ecs.Deployment{
+					{Status: aws.String("PRIMARY"), RolloutState: aws.String(ecs.DeploymentRolloutStateCompleted)},
+					{Status: aws.String("ACTIVE"), RolloutState: aws.String(ecs.DeploymentRolloutStateCompleted)},
+				},
+			},
+			want: false,
+		},
+		{
+			name:           "rolled back by circuit breaker",
+			circuitBreaker: true,
+			service: &ecs.Service{
+				Deployments: []*ecs.Deployment{
+					{Status: aws.String("PRIMARY"), RolloutState: aws.String(ecs.DeploymentRolloutStateFailed), RolloutStateReason: aws.String("tasks failed to start")},
+				},
+			},
+			wantErr: errRolledBack,
+		},
+		{
+			name:           "rolled back to a completed deployment",
+			circuitBreaker: true,
+			deployment:     "ecs-svc/new",
+			service: &ecs.Service{
+				Deployments: []*ecs.Deployment{
+					{Id: aws.String("ecs-svc/rollback"), Status: aws.String("PRIMARY"), RolloutState: aws.String(ecs.DeploymentRolloutStateCompleted)},
+				},
+			},
+			wantErr: errRolledBack,
+		},
+		{
+			name:           "rolling back",
+			circuitBreaker: true,
+			deployment:     "ecs-svc/new",
+			service: &ecs.Service{
+				Deployments: []*ecs.Deployment{
+					{Id: aws.String("ecs-svc/rollback"), Status: aws.String("PRIMARY"), RolloutState: aws.String(ecs.DeploymentRolloutStateInProgress)},
+					{Id: aws.String("ecs-svc/new"), Status: aws.String("ACTIVE"), RolloutState: aws.String(ecs.DeploymentRolloutStateFailed), RolloutStateReason: aws.String("tasks failed to start")},
+				},
+			},
+			wantErr: errRolledBack,
+		},
+		{
+			name:       "tracked deployment completed",
+			deployment: "ecs-svc/new",
+			service: &ecs.Service{
+				Deployments: []*ecs.Deployment{
+					{Id: aws.String("ecs-svc/new"), Status: aws.String("PRIMARY"), RolloutState: aws.String(ecs.DeploymentRolloutStateCompleted)},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "no rollout state",
+			service: &ecs.Service{
+				DesiredCount:   aws.Int64(1),
+				TaskDefinition: aws.String("test-arn"),
+				Deployments:    []*ecs.Deployment{{}},
+			},
+			mockECS: func(m *mocks.MockECSAPI) {
+				m.EXPECT().ListTasks(gomock.Any()).Return(&ecs.ListTasksOutput{
+					TaskArns: []*string{aws.String("test")},
+				}, nil).Times(1)
+				m.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+					Tasks: []*ecs.Task{{LastStatus: aws.String("RUNNING"), TaskDefinitionArn: aws.String("test-arn")}},
+				}, nil).Times(1)
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockECSAPI := mocks.NewMockECSAPI(ctrl)
+			mockECSAPI.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+				Services: []*ecs.Service{tt.service},
+			}, nil).Times(1)
+			if tt.mockECS != nil {
+				tt.mockECS(mockECSAPI)
+			}
+
+			e := &Manager{
+				Project: &config.Project{
+					AWSClient: config.NewAWSClient(config.WithECSClient(mockECSAPI)),
+				},
+				App: &config.Ecs{Name: "goblin", Cluster: "test", CircuitBreaker: tt.circuitBreaker},
+			}
+
+			got, err := newRollout(since, tt.deployment).watch(e, "test-goblin")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("watch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("watch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_pruneRevisions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var deregistered []string
+
+	mockECSAPI := mocks.NewMockECSAPI(ctrl)
+	mockECSAPI.EXPECT().ListTaskDefinitionsPages(gomock.Any(), gomock.Any()).DoAndReturn(func(input *ecs.ListTaskDefinitionsInput, fn func(*ecs.ListTaskDefinitionsOutput, bool) bool) error {
+		fn(&ecs.ListTaskDefinitionsOutput{
+			TaskDefinitionArns: aws.StringSlice([]string{"test-goblin:7", "test-goblin:6", "test-goblin:5"}),
+		}, false)
+		fn(&ecs.ListTaskDefinitionsOutput{
+			TaskDefinitionArns: aws.StringSlice([]string{"test-goblin:4", "test-goblin:3"}),
+		}, true)
+		return nil
+	}).Times(1)
+	mockECSAPI.EXPECT().DeregisterTaskDefinition(gomock.Any()).DoAndReturn(func(input *ecs.DeregisterTaskDefinitionInput) (*ecs.DeregisterTaskDefinitionOutput, error) {
+		deregistered = append(deregistered, *input.TaskDefinition)
+		return &ecs.DeregisterTaskDefinitionOutput{}, nil
+	}).Times(2)
+
+	e := &Manager{
+		Project: &config.Project{
+			AWSClient: config.NewAWSClient(config.WithECSClient(mockECSAPI)),
+		},
+		App: &config.Ecs{Name: "goblin", KeepRevisions: 2},
+	}
+
+	// the running revision is never deregistered
+	err := e.pruneRevisions("test-goblin", &ecs.TaskDefinition{TaskDefinitionArn: aws.String("test-goblin:4")})
+	if err != nil {
+		t.Fatalf("pruneRevisions() error = %v", err)
+	}
+
+	want := []string{"test-goblin:5", "test-goblin:3"}
+	if !reflect.DeepEqual(deregistered, want) {
+		t.Errorf("pruneRevisions() deregistered = %v, want %v", deregistered, want)
+	}
+}
+
+func TestManager_History(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var described []string
+
+	mockECSAPI := mocks.NewMockECSAPI(ctrl)
+	mockECSAPI.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{{TaskDefinition: aws.String("test-goblin:3")}},
+	}, nil).Times(1)
+	mockECSAPI.EXPECT().ListTaskDefinitionsPages(gomock.Any(), gomock.Any()).DoAndReturn(func(input *ecs.ListTaskDefinitionsInput, fn func(*ecs.ListTaskDefinitionsOutput, bool) bool) error {
+		fn(&ecs.ListTaskDefinitionsOutput{
+			TaskDefinitionArns: aws.StringSlice([]string{"test-goblin:7", "test-goblin:6", "test-goblin:5", "test-goblin:4", "test-goblin:3"}),
+		}, true)
+		return nil
+	}).Times(1)
+	mockECSAPI.EXPECT().DescribeTaskDefinition(gomock.Any()).DoAndReturn(func(input *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error) {
+		described = append(described, *input.TaskDefinition)
+		r, _ := revisionOf(*input.TaskDefinition)
+		return &ecs.DescribeTaskDefinitionOutput{TaskDefinition: &ecs.TaskDefinition{
+			TaskDefinitionArn: input.TaskDefinition,
+			Revision:          aws.Int64(r),
+		}}, nil
+	}).Times(3)
+
+	e := &Manager{
+		Project: &config.Project{
+			Env:       "test",
+			AWSClient: config.NewAWSClient(config.WithECSClient(mockECSAPI)),
+		},
+		App: &config.Ecs{Name: "goblin", Cluster: "test"},
+	}
+
+	// the running revision is shown even if it's older than the limit
+	revisions, err := e.History(2)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+
+	want := []string{"test-goblin:7", "test-goblin:6", "test-goblin:3"}
+	if !reflect.DeepEqual(described, want) {
+		t.Errorf("History() described = %v, want %v", described, want)
+	}
+
+	if len(revisions) != 3 || !revisions[2].Current {
+		t.Errorf("History() = %v, want the running revision last", revisions)
+	}
+}
+
+func TestManager_rollbackLocal(t *testing.T) {
+	tests := []struct {
+		name     string
+		revision int64
+		list     []string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "previous revision",
+			list: []string{"test-goblin:7", "test-goblin:6", "test-goblin:4"},
+			want: "test-goblin:4",
+		},
+		{
+			name:     "to revision",
+			revision: 2,
+			want:     "test-goblin:2",
+		},
+		{
+			name:     "running revision",
+			revision: 6,
+			wantErr:  true,
+		},
+		{
+			name:    "nothing to roll back to",
+			list:    []string{"test-goblin:6"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockECSAPI := mocks.NewMockECSAPI(ctrl)
+			mockECSAPI.EXPECT().DescribeServices(gomock.Any()).Return(&ecs.DescribeServicesOutput{
+				Services: []*ecs.Service{{
+					TaskDefinition: aws.String("test-goblin:6"),
+					Deployments: []*ecs.Deployment{
+						{Status: aws.String("PRIMARY"), RolloutState: aws.String(ecs.DeploymentRolloutStateCompleted)},
+					},
+				}},
+			}, nil).AnyTimes()
+			mockECSAPI.EXPECT().DescribeTaskDefinition(gomock.Any()).DoAndReturn(func(input *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error) {
+				r, _ := revisionOf(*input.TaskDefinition)
+				return &ecs.DescribeTaskDefinitionOutput{
+					TaskDefinition: &ecs.TaskDefinition{
+						Family:            aws.String("test-goblin"),
+						Revision:          aws.Int64(r),
+						TaskDefinitionArn: input.TaskDefinition,
+					},
+				}, nil
+			}).AnyTimes()
+			if tt.list != nil {
+				mockECSAPI.EXPECT().ListTaskDefinitionsPages(gomock.Any(), gomock.Any()).DoAndReturn(func(input *ecs.ListTaskDefinitionsInput, fn func(*ecs.ListTaskDefinitionsOutput, bool) bool) error {
+					fn(&ecs.ListTaskDefinitionsOutput{TaskDefinitionArns: aws.StringSlice(tt.list)}, true)
+					return nil
+				}).Times(1)
+			}
+
+			var got string
+			if !tt.wantErr {
+				mockECSAPI.EXPECT().UpdateService(gomock.Any()).DoAndReturn(func(input *ecs.UpdateServiceInput) (*ecs.UpdateServiceOutput, error) {
+					got = *input.TaskDefinition
+					return &ecs.UpdateServiceOutput{Service: &ecs.Service{}}, nil
+				}).Times(1)
+			}
+
+			e := &Manager{
+				Project: &config.Project{
+					Env:       "test",
+					AWSClient: config.NewAWSClient(config.WithECSClient(mockECSAPI)),
+				},
+				App: &config.Ecs{Name: "goblin", Cluster: "test", Timeout: 1},
+			}
+
+			err := e.rollbackLocal(os.Stdout, tt.revision)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rollbackLocal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("rollbackLocal() deployed %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_preDeploy(t *testing.T) {
+	taskPollInterval = 0
+
+	output := base64.StdEncoding.EncodeToString([]byte(`{
+		"vpc_private_subnets": {"value": ["subnet-1", "subnet-2"]},
+		"security_groups": {"value": "sg-1"}
+	}`))
+
+	tests := []struct {
+		name           string
+		exitCode       *int64
+		timeout        bool
+		wantDeregister bool
+		wantErr        bool
+	}{
+		{
+			name:     "success",
+			exitCode: aws.Int64(0),
+		},
+		{
+			name:           "non-zero exit code",
+			exitCode:       aws.Int64(1),
+			wantDeregister: true,
+			wantErr:        true,
+		},
+		{
+			name:           "container didn't start",
+			wantDeregister: true,
+			wantErr:        true,
+		},
+		{
+			name:           "timeout",
+			timeout:        true,
+			wantDeregister: true,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockSSMAPI := mocks.NewMockSSMAPI(ctrl)
+			mockSSMAPI.EXPECT().GetParameter(gomock.Any()).Return(&ssm.GetParameterOutput{
+				Parameter: &ssm.Parameter{Value: aws.String(output)},
+			}, nil).Times(1)
+
+			mockECSAPI := mocks.NewMockECSAPI(ctrl)
+			mockECSAPI.EXPECT().RunTaskWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *ecs.RunTaskInput, opts ...interface{}) (*ecs.RunTaskOutput, error) {
+				if *input.TaskDefinition != "test-goblin:4" {
+					t.Errorf("RunTask() task definition = %s, want test-goblin:4", *input.TaskDefinition)
+				}
+
+				override := input.Overrides.ContainerOverrides[0]
+				if *override.Name != "goblin" || !reflect.DeepEqual(aws.StringValueSlice(override.Command), []string{"bin/migrate", "up"}) {
+					t.Errorf("RunTask() override = %v", override)
+				}
+
+				return &ecs.RunTaskOutput{
+					Tasks: []*ecs.Task{{TaskArn: aws.String("arn:aws:ecs:us-east-1:0:task/test/abc")}},
+				}, nil
+			}).Times(1)
+			if tt.timeout {
+				taskPollInterval = 100 * time.Millisecond
+				defer func() { taskPollInterval = 0 }()
+
+				mockECSAPI.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+					Tasks: []*ecs.Task{{LastStatus: aws.String(ecs.DesiredStatusRunning)}},
+				}, nil).MinTimes(1)
+				mockECSAPI.EXPECT().StopTask(gomock.Any()).Return(&ecs.StopTaskOutput{}, nil).Times(1)
+			} else {
+				mockECSAPI.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+					Tasks: []*ecs.Task{{LastStatus: aws.String(ecs.DesiredStatusRunning)}},
+				}, nil).Times(1)
+				mockECSAPI.EXPECT().DescribeTasks(gomock.Any()).Return(&ecs.DescribeTasksOutput{
+					Tasks: []*ecs.Task{{
+						LastStatus:    aws.String(ecs.DesiredStatusStopped),
+						StoppedReason: aws.String("Essential container in task exited"),
+						Containers:    []*ecs.Container{{Name: aws.String("goblin"), ExitCode: tt.exitCode}},
+					}},
+				}, nil).Times(1)
+			}
+			if tt.wantDeregister {
+				mockECSAPI.EXPECT().DeregisterTaskDefinition(gomock.Any()).Return(&ecs.DeregisterTaskDefinitionOutput{}, nil).Times(1)
+			}
+
+			mockCWLAPI := mocks.NewMockCloudWatchLogsAPI(ctrl)
+			mockCWLAPI.EXPECT().GetLogEvents(gomock.Any()).Return(&cloudwatchlogs.GetLogEventsOutput{
+				Events: []*cloudwatchlogs.OutputLogEvent{{Message: aws.String("migrated")}},
+			}, nil).AnyTimes()
+
+			e := &Manager{
+				Project: &config.Project{
+					Env: "test",
+					AWSClient: config.NewAWSClient(
+						config.WithECSClient(mockECSAPI),
+						config.WithSSMClient(mockSSMAPI),
+						config.WithCloudWatchLogsClient(mockCWLAPI),
+					),
+				},
+				App: &config.Ecs{
+					Name:      "goblin",
+					Cluster:   "test",
+					Timeout:   1,
+					PreDeploy: &config.PreDeploy{Command: []string{"bin/migrate", "up"}},
+				},
+			}
+
+			var out bytes.Buffer
+
+			err := e.preDeploy(&out, &ecs.TaskDefinition{
+				Family:            aws.String("test-goblin"),
+				Revision:          aws.Int64(4),
+				TaskDefinitionArn: aws.String("test-goblin:4"),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("preDeploy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !strings.Contains(out.String(), "migrated") {
+				t.Errorf("preDeploy() output = %s, want task logs", out.String())
+			}
+		})
+	}
+}
+
+func TestTask_input(t *testing.T) {
+	network := &ecs.NetworkConfiguration{AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+		Subnets: aws.StringSlice([]string{"subnet-1"}),
+	}}
+
+	tests := []struct {
+		name string
+		task Task
+		want *ecs.RunTaskInput
+	}{
+		{
+			name: "defaults",
+			task: Task{Cluster: "dev-testnut", TaskDefinition: "dev-goblin", Container: "goblin", Subnets: []string{"subnet-1"}},
+			want: &ecs.RunTaskInput{
+				TaskDefinition:       aws.String("dev-goblin"),
+				StartedBy:            aws.String("IZE"),
+				Cluster:              aws.String("dev-testnut"),
+				LaunchType:           aws.String(ecs.LaunchTypeFargate),
+				NetworkConfiguration: network,
+			},
+		},
+		{
+			name: "overrides",
+			task: Task{
+				Cluster:        "dev-testnut",
+				TaskDefinition: "dev-goblin",
+				Container:      "goblin",
+				Command:        []string{"rake", "db:migrate"},
+				Environment:    map[string]string{"RAILS_ENV": "staging", "DEBUG": "1"},
+				Cpu:            "1024",
+				Memory:         "2048",
+				LaunchType:     ecs.LaunchTypeEc2,
+				Subnets:        []string{"subnet-1"},
+				SecurityGroups: []string{"sg-1", "sg-2"},
+			},
+			want: &ecs.RunTaskInput{
+				TaskDefinition: aws.String("dev-goblin"),
+				StartedBy:      aws.String("IZE"),
+				Cluster:        aws.String("dev-testnut"),
+				LaunchType:     aws.String(ecs.LaunchTypeEc2),
+				NetworkConfiguration: &ecs.NetworkConfiguration{AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+					Subnets:        aws.StringSlice([]string{"subnet-1"}),
+					SecurityGroups: aws.StringSlice([]string{"sg-1", "sg-2"}),
+				}},
+				Overrides: &ecs.TaskOverride{
+					Cpu:    aws.String("1024"),
+					Memory: aws.String("2048"),
+					ContainerOverrides: []*ecs.ContainerOverride{{
+						Name:    aws.String("goblin"),
+						Command: aws.StringSlice([]string{"rake", "db:migrate"}),
+						Environment: []*ecs.KeyValuePair{
+							{Name: aws.String("DEBUG"), Value: aws.String("1")},
+							{Name: aws.String("RAILS_ENV"), Value: aws.String("staging")},
+						},
+					}},
+				},
+			},
+		},
+		{
+			name: "capacity provider",
+			task: Task{Cluster: "dev-testnut", TaskDefinition: "dev-goblin", Container: "goblin", CapacityProvider: "FARGATE_SPOT", Subnets: []string{"subnet-1"}},
+			want: &ecs.RunTaskInput{
+				TaskDefinition: aws.String("dev-goblin"),
+				StartedBy:      aws.String("IZE"),
+				Cluster:        aws.String("dev-testnut"),
+				CapacityProviderStrategy: []*ecs.CapacityProviderStrategyItem{{
+					CapacityProvider: aws.String("FARGATE_SPOT"),
+					Weight:           aws.Int64(1),
+				}},
+				NetworkConfiguration: network,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.task.input(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("input() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterLogs(t *testing.T) {
+	logsPollInterval = time.Millisecond
+	logsLookback = 15 * time.Millisecond
+
+	event := func(id string, ts int64, stream string) *cloudwatchlogs.FilteredLogEvent {
+		return &cloudwatchlogs.FilteredLogEvent{
+			EventId:       aws.String(id),
+			LogStreamName: aws.String(stream),
+			Message:       aws.String(id + "\n"),
+			Timestamp:     aws.Int64(ts),
+		}
+	}
+
+	// The second poll starts within the lookback of the latest timestamp, returns seen events again
+	// and an event ingested late with an earlier timestamp
+	polls := [][]*cloudwatchlogs.FilteredLogEvent{
+		{event("2", 20, "main/goblin/task2"), event("1", 10, "main/goblin/task1"), event("3", 20, "main/nginx/task1")},
+		{event("2", 20, "main/goblin/task2"), event("3", 20, "main/nginx/task1"), event("4", 30, "main/goblin/task1"), event("5", 15, "main/goblin/task2")},
+	}
+	starts := []int64{time.Time{}.UnixMilli(), 5}
+
+	tests := []struct {
+		name       string
+		filter     LogFilter
+		wantPrefix string
+		want       []string
+	}{
+		{
+			name:   "all tasks and containers",
+			filter: LogFilter{LogGroup: "dev-goblin", Follow: true},
+			want:   []string{"1 task1 goblin", "2 task2 goblin", "3 task1 nginx", "5 task2 goblin", "4 task1 goblin"},
+		},
+		{
+			name:   "task",
+			filter: LogFilter{LogGroup: "dev-goblin", Task: "task1", Follow: true},
+			want:   []string{"1 task1 goblin", "3 task1 nginx", "4 task1 goblin"},
+		},
+		{
+			name:       "container of task without following",
+			filter:     LogFilter{LogGroup: "dev-goblin", Container: "goblin", Task: "task1"},
+			wantPrefix: "main/goblin/task1",
+			want:       []string{"1 task1 goblin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			poll := 0
+
+			mockCWL := mocks.NewMockCloudWatchLogsAPI(ctrl)
+			mockCWL.EXPECT().FilterLogEventsPagesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, input *cloudwatchlogs.FilterLogEventsInput, fn func(*cloudwatchlogs.FilterLogEventsOutput, bool) bool, opts ...interface{}) error {
+					if got := aws.StringValue(input.LogStreamNamePrefix); got != tt.wantPrefix {
+						t.Errorf("LogStreamNamePrefix = %s, want %s", got, tt.wantPrefix)
+					}
+
+					if got := aws.Int64Value(input.StartTime); got != starts[poll] {
+						t.Errorf("StartTime of poll %d = %d, want %d", poll, got, starts[poll])
+					}
+
+					if poll == len(polls)-1 {
+						cancel()
+					}
+
+					fn(&cloudwatchlogs.FilterLogEventsOutput{Events: polls[poll]}, true)
+					poll++
+
+					return nil
+				}).AnyTimes()
+
+			var got []string
+			err := FilterLogs(ctx, mockCWL, tt.filter, func(e LogEvent) error {
+				got = append(got, strings.Join([]string{e.Message, e.Task, e.Container}, " "))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("FilterLogs() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterLogs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunQuery(t *testing.T) {
+	queryPollInterval = time.Millisecond
+
+	field := func(name, value string) *cloudwatchlogs.ResultField {
+		return &cloudwatchlogs.ResultField{Field: aws.String(name), Value: aws.String(value)}
+	}
+
+	tests := []struct {
+		name     string
+		statuses []string
+		want     *QueryResult
+		wantErr  bool
+	}{
+		{
+			name:     "complete",
+			statuses: []string{cloudwatchlogs.QueryStatusScheduled, cloudwatchlogs.QueryStatusRunning, cloudwatchlogs.QueryStatusComplete},
+			want: &QueryResult{
+				Fields: []string{"@timestamp", "@message", "status"},
+				Rows: []map[string]string{
+					{"@timestamp": "2022-08-01 10:00:00.000", "@message": "ok"},
+					{"@timestamp": "2022-08-01 10:00:01.000", "@message": "failed", "status": "500"},
+				},
+				RecordsScanned: 10,
+				RecordsMatched: 2,
+			},
+		},
+		{
+			name:     "failed",
+			statuses: []string{cloudwatchlogs.QueryStatusRunning, cloudwatchlogs.QueryStatusFailed},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			since := time.Unix(1659348000, 0)
+
+			mockCWL := mocks.NewMockCloudWatchLogsAPI(ctrl)
+			mockCWL.EXPECT().StartQueryWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *cloudwatchlogs.StartQueryInput, opts ...interface{}) (*cloudwatchlogs.StartQueryOutput, error) {
+				if aws.Int64Value(input.StartTime) != since.Unix() || aws.Int64Value(input.EndTime) != since.Add(time.Hour).Unix() {
+					t.Errorf("StartQuery() time range = %d-%d", aws.Int64Value(input.StartTime), aws.Int64Value(input.EndTime))
+				}
+				if input.Limit != nil {
+					t.Errorf("StartQuery() limit = %d, want nil", aws.Int64Value(input.Limit))
+				}
+				return &cloudwatchlogs.StartQueryOutput{QueryId: aws.String("query")}, nil
+			}).Times(1)
+
+			poll := 0
+			mockCWL.EXPECT().GetQueryResultsWithContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, input *cloudwatchlogs.GetQueryResultsInput, opts ...interface{}) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+				out := &cloudwatchlogs.GetQueryResultsOutput{Status: aws.String(tt.statuses[poll])}
+				poll++
+
+				if aws.StringValue(out.Status) == cloudwatchlogs.QueryStatusComplete {
+					out.Results = [][]*cloudwatchlogs.ResultField{
+						{field("@timestamp", "2022-08-01 10:00:00.000"), field("@message", "ok"), field("@ptr", "1")},
+						{field("@timestamp", "2022-08-01 10:00:01.000"), field("@message", "failed"), field("status", "500"), field("@ptr", "2")},
+					}
+					out.Statistics = &cloudwatchlogs.QueryStatistics{RecordsScanned: aws.Float64(10), RecordsMatched: aws.Float64(2)}
+				}
+
+				return out, nil
+			}).Times(len(tt.statuses))
+
+			got, err := RunQuery(context.Background(), mockCWL, Query{
+				LogGroups: []string{"dev-goblin"},
+				Query:     "fields @timestamp, @message",
+				Since:     since,
+				Until:     since.Add(time.Hour),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RunQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
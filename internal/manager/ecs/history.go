@@ -0,0 +1,290 @@
+package ecs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/hazelops/ize/internal/aws/utils"
+	"github.com/hazelops/ize/pkg/terminal"
+	"github.com/pterm/pterm"
+)
+
+// Revision is a registered revision of the app task definition
+type Revision struct {
+	Revision     int64
+	Image        string
+	RegisteredAt time.Time
+	RegisteredBy string
+	// Current is set for the revision the service is running
+	Current bool
+}
+
+// Tag returns the image tag of the app container
+func (r Revision) Tag() string {
+	i := strings.LastIndex(r.Image, ":")
+	if i == -1 || strings.Contains(r.Image[i:], "/") {
+		return "latest"
+	}
+
+	return r.Image[i+1:]
+}
+
+// History returns up to limit latest active revisions of the app task definition and the running one, the latest first.
+// If limit is 0, keep_revisions revisions are returned.
+func (e *Manager) History(limit int) ([]Revision, error) {
+	e.prepare()
+
+	if err := e.setSession(); err != nil {
+		return nil, err
+	}
+
+	svc := e.Project.AWSClient.ECSClient
+
+	name := fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name)
+
+	dso, err := getService(name, e.App.Cluster, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	arns, err := e.activeRevisions(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = e.App.KeepRevisions
+	}
+
+	if len(arns) > limit {
+		running := aws.StringValue(dso.Services[0].TaskDefinition)
+
+		latest := arns[:limit]
+		for _, arn := range arns[limit:] {
+			if *arn == running {
+				latest = append(latest, arn)
+			}
+		}
+
+		arns = latest
+	}
+
+	var revisions []Revision
+
+	for _, arn := range arns {
+		dtdo, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+			TaskDefinition: arn,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe task definition: %w", err)
+		}
+
+		td := dtdo.TaskDefinition
+
+		r := Revision{
+			Revision:     aws.Int64Value(td.Revision),
+			RegisteredAt: aws.TimeValue(td.RegisteredAt),
+			RegisteredBy: aws.StringValue(td.RegisteredBy),
+			Current:      aws.StringValue(td.TaskDefinitionArn) == aws.StringValue(dso.Services[0].TaskDefinition),
+		}
+
+		for _, c := range td.ContainerDefinitions {
+			if aws.StringValue(c.Name) == e.App.Name {
+				r.Image = aws.StringValue(c.Image)
+			}
+		}
+
+		revisions = append(revisions, r)
+	}
+
+	return revisions, nil
+}
+
+// Rollback redeploys the app with the revision of its task definition.
+// If the revision is 0, the latest active revision older than the running one is used.
+func (e *Manager) Rollback(ui terminal.UI, revision int64) error {
+	e.prepare()
+
+	if e.App.DeploymentStrategy != strategyRolling {
+		return fmt.Errorf("rollback is not supported for %s deployment strategy", e.App.DeploymentStrategy)
+	}
+
+	if err := e.setSession(); err != nil {
+		return err
+	}
+
+	sg := ui.StepGroup()
+	defer sg.Wait()
+
+	s := sg.Add("%s: rolling back app container...", e.App.Name)
+	defer func() { s.Abort(); time.Sleep(50 * time.Millisecond) }()
+
+	err := e.rollbackLocal(s.TermOutput(), revision)
+	pterm.SetDefaultOutput(os.Stdout)
+	if err != nil {
+		return fmt.Errorf("unable to rollback app: %w", err)
+	}
+
+	s.Done()
+	s = sg.Add("%s: rollback completed!", e.App.Name)
+	s.Done()
+
+	return nil
+}
+
+func (e *Manager) rollbackLocal(w io.Writer, revision int64) error {
+	pterm.SetDefaultOutput(w)
+
+	svc := e.Project.AWSClient.ECSClient
+
+	name := fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name)
+
+	dso, err := getService(name, e.App.Cluster, svc)
+	if err != nil {
+		return err
+	}
+
+	current, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: dso.Services[0].TaskDefinition,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to describe task definition: %w", err)
+	}
+
+	if revision == 0 {
+		arns, err := e.activeRevisions(name)
+		if err != nil {
+			return err
+		}
+
+		for _, arn := range arns {
+			r, err := revisionOf(*arn)
+			if err != nil {
+				return err
+			}
+
+			if r < *current.TaskDefinition.Revision {
+				revision = r
+				break
+			}
+		}
+
+		if revision == 0 {
+			return fmt.Errorf("no revision of %s older than %d to roll back to", name, *current.TaskDefinition.Revision)
+		}
+	}
+
+	if revision == *current.TaskDefinition.Revision {
+		return fmt.Errorf("%s is already running revision %d", name, revision)
+	}
+
+	dtdo, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(fmt.Sprintf("%s:%d", name, revision)),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to describe task definition: %w", err)
+	}
+
+	pterm.Printfln("Rolling back from %s:%d to %s:%d", name, *current.TaskDefinition.Revision, name, revision)
+
+	return e.updateTaskDefinition(dtdo.TaskDefinition, nil, name, "Deploying previous task definition")
+}
+
+// activeRevisions returns ARNs of active revisions of the task definition family, the latest first
+func (e *Manager) activeRevisions(family string) ([]*string, error) {
+	var arns []*string
+
+	err := e.Project.AWSClient.ECSClient.ListTaskDefinitionsPages(&ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String(family),
+		Status:       aws.String(ecs.TaskDefinitionStatusActive),
+		Sort:         aws.String(ecs.SortOrderDesc),
+	}, func(page *ecs.ListTaskDefinitionsOutput, lastPage bool) bool {
+		arns = append(arns, page.TaskDefinitionArns...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list task definitions: %w", err)
+	}
+
+	return arns, nil
+}
+
+// pruneRevisions deregisters active revisions of the task definition family except
+// the keep_revisions latest ones and the one the service is running
+func (e *Manager) pruneRevisions(family string, running *ecs.TaskDefinition) error {
+	arns, err := e.activeRevisions(family)
+	if err != nil {
+		return err
+	}
+
+	if len(arns) <= e.App.KeepRevisions {
+		return nil
+	}
+
+	for _, arn := range arns[e.App.KeepRevisions:] {
+		if *arn == aws.StringValue(running.TaskDefinitionArn) {
+			continue
+		}
+
+		r, err := revisionOf(*arn)
+		if err != nil {
+			return err
+		}
+
+		err = deregisterTaskDefinition(e.Project.AWSClient.ECSClient, &ecs.TaskDefinition{
+			TaskDefinitionArn: arn,
+			Family:            aws.String(family),
+			Revision:          aws.Int64(r),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pruneDeployedRevisions prunes revisions of the task definition of the app except the running one
+func (e *Manager) pruneDeployedRevisions() error {
+	name := fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name)
+
+	dso, err := getService(name, e.App.Cluster, e.Project.AWSClient.ECSClient)
+	if err != nil {
+		return err
+	}
+
+	return e.pruneRevisions(name, &ecs.TaskDefinition{TaskDefinitionArn: dso.Services[0].TaskDefinition})
+}
+
+// revisionOf returns the revision of the task definition ARN
+func revisionOf(arn string) (int64, error) {
+	var r int64
+
+	i := strings.LastIndex(arn, ":")
+	if _, err := fmt.Sscanf(arn[i+1:], "%d", &r); i == -1 || err != nil {
+		return 0, fmt.Errorf("can't parse revision of task definition %s", arn)
+	}
+
+	return r, nil
+}
+
+func (e *Manager) setSession() error {
+	if len(e.App.AwsRegion) != 0 && len(e.App.AwsProfile) != 0 {
+		sess, err := utils.GetSession(&utils.SessionConfig{
+			Region:  e.App.AwsRegion,
+			Profile: e.App.AwsProfile,
+		})
+		if err != nil {
+			return fmt.Errorf("can't get session: %w", err)
+		}
+
+		e.Project.SettingAWSClient(sess)
+	}
+
+	return nil
+}
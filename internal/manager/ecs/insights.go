@@ -0,0 +1,118 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+)
+
+// queryPollInterval is how often results of a running CloudWatch Logs Insights query are fetched
+var queryPollInterval = time.Second
+
+// Query is a CloudWatch Logs Insights query over the log groups
+type Query struct {
+	LogGroups []string
+	Query     string
+	Since     time.Time
+	Until     time.Time
+	// Limit is the maximum number of returned rows, the query decides if it's 0
+	Limit int64
+}
+
+// QueryResult is the result of a CloudWatch Logs Insights query
+type QueryResult struct {
+	// Fields are names of the result fields in the order of appearance
+	Fields []string
+	Rows   []map[string]string
+	// RecordsScanned and RecordsMatched are query statistics
+	RecordsScanned float64
+	RecordsMatched float64
+}
+
+// RunQuery starts the query and waits for its results. The query is stopped if ctx is canceled.
+func RunQuery(ctx context.Context, clw cloudwatchlogsiface.CloudWatchLogsAPI, q Query) (*QueryResult, error) {
+	input := &cloudwatchlogs.StartQueryInput{
+		LogGroupNames: aws.StringSlice(q.LogGroups),
+		QueryString:   aws.String(q.Query),
+		StartTime:     aws.Int64(q.Since.Unix()),
+		EndTime:       aws.Int64(q.Until.Unix()),
+	}
+
+	if q.Limit != 0 {
+		input.Limit = aws.Int64(q.Limit)
+	}
+
+	sqo, err := clw.StartQueryWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("can't start query: %w", err)
+	}
+
+	for {
+		out, err := clw.GetQueryResultsWithContext(ctx, &cloudwatchlogs.GetQueryResultsInput{
+			QueryId: sqo.QueryId,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				stopQuery(clw, sqo.QueryId)
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("can't get query results: %w", err)
+		}
+
+		switch aws.StringValue(out.Status) {
+		case cloudwatchlogs.QueryStatusComplete:
+			return newQueryResult(out), nil
+		case cloudwatchlogs.QueryStatusFailed, cloudwatchlogs.QueryStatusCancelled, cloudwatchlogs.QueryStatusTimeout:
+			return nil, fmt.Errorf("query %s: %s", aws.StringValue(sqo.QueryId), aws.StringValue(out.Status))
+		}
+
+		select {
+		case <-ctx.Done():
+			stopQuery(clw, sqo.QueryId)
+			return nil, ctx.Err()
+		case <-time.After(queryPollInterval):
+		}
+	}
+}
+
+// stopQuery stops the query, so that it doesn't keep scanning logs after ize exits
+func stopQuery(clw cloudwatchlogsiface.CloudWatchLogsAPI, id *string) {
+	_, _ = clw.StopQuery(&cloudwatchlogs.StopQueryInput{QueryId: id})
+}
+
+func newQueryResult(out *cloudwatchlogs.GetQueryResultsOutput) *QueryResult {
+	res := &QueryResult{}
+	known := map[string]bool{}
+
+	for _, row := range out.Results {
+		r := map[string]string{}
+
+		for _, f := range row {
+			name := aws.StringValue(f.Field)
+			// @ptr is an internal pointer to the log event
+			if name == "@ptr" {
+				continue
+			}
+
+			if !known[name] {
+				known[name] = true
+				res.Fields = append(res.Fields, name)
+			}
+
+			r[name] = aws.StringValue(f.Value)
+		}
+
+		res.Rows = append(res.Rows, r)
+	}
+
+	if out.Statistics != nil {
+		res.RecordsScanned = aws.Float64Value(out.Statistics.RecordsScanned)
+		res.RecordsMatched = aws.Float64Value(out.Statistics.RecordsMatched)
+	}
+
+	return res
+}
@@ -0,0 +1,151 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+)
+
+// logsPollInterval is how often new log events are fetched when following logs
+var logsPollInterval = 2 * time.Second
+
+// logsLookback is how far before the latest event each poll starts when following logs.
+// CloudWatch Logs may ingest events of other streams later than newer ones.
+var logsLookback = 30 * time.Second
+
+// LogEvent is a log event of a container of an ECS task
+type LogEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Task      string    `json:"task"`
+	Container string    `json:"container"`
+	Stream    string    `json:"stream"`
+	Message   string    `json:"message"`
+}
+
+// LogFilter selects log events of the app log group. Streams are named main/<container>/<task id>.
+type LogFilter struct {
+	LogGroup string
+	// Container and Task limit events to the container and the task if set
+	Container string
+	Task      string
+	// Pattern is a CloudWatch Logs filter pattern
+	Pattern string
+	Since   time.Time
+	// Until is the end of the time range, it disables following if set
+	Until  time.Time
+	Follow bool
+}
+
+// FilterLogs calls fn with log events of all tasks and containers matching the filter in chronological order.
+// If the filter follows logs, new events are fetched until ctx is canceled. Events ingested late
+// (within logsLookback of the latest one) are passed to fn once they are fetched.
+func FilterLogs(ctx context.Context, clw cloudwatchlogsiface.CloudWatchLogsAPI, filter LogFilter, fn func(LogEvent) error) error {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(filter.LogGroup),
+	}
+
+	if len(filter.Pattern) != 0 {
+		input.FilterPattern = aws.String(filter.Pattern)
+	}
+
+	if len(filter.Container) != 0 {
+		prefix := fmt.Sprintf("main/%s/", filter.Container)
+		if len(filter.Task) != 0 {
+			prefix += filter.Task
+		}
+		input.LogStreamNamePrefix = aws.String(prefix)
+	}
+
+	if !filter.Until.IsZero() {
+		input.EndTime = aws.Int64(filter.Until.UnixMilli())
+	}
+
+	follow := filter.Follow && filter.Until.IsZero()
+
+	since := filter.Since.UnixMilli()
+	latest := since
+	// timestamps of events by ID, events within the lookback are fetched again by the next poll
+	seen := map[string]int64{}
+
+	for {
+		input.StartTime = aws.Int64(since)
+
+		var events []*cloudwatchlogs.FilteredLogEvent
+
+		err := clw.FilterLogEventsPagesWithContext(ctx, input, func(page *cloudwatchlogs.FilterLogEventsOutput, lastPage bool) bool {
+			events = append(events, page.Events...)
+			return true
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("can't get logs of %s: %w", filter.LogGroup, err)
+		}
+
+		sort.SliceStable(events, func(i, j int) bool {
+			return aws.Int64Value(events[i].Timestamp) < aws.Int64Value(events[j].Timestamp)
+		})
+
+		for _, e := range events {
+			if _, ok := seen[aws.StringValue(e.EventId)]; ok {
+				continue
+			}
+
+			event := newLogEvent(e)
+			if ts := aws.Int64Value(e.Timestamp); ts > latest {
+				latest = ts
+			}
+			seen[aws.StringValue(e.EventId)] = aws.Int64Value(e.Timestamp)
+
+			if (len(filter.Task) != 0 && event.Task != filter.Task) || (len(filter.Container) != 0 && event.Container != filter.Container) {
+				continue
+			}
+
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+
+		if !follow {
+			return nil
+		}
+
+		if start := latest - logsLookback.Milliseconds(); start > since {
+			since = start
+		}
+
+		for id, ts := range seen {
+			if ts < since {
+				delete(seen, id)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logsPollInterval):
+		}
+	}
+}
+
+func newLogEvent(e *cloudwatchlogs.FilteredLogEvent) LogEvent {
+	event := LogEvent{
+		Timestamp: time.UnixMilli(aws.Int64Value(e.Timestamp)),
+		Stream:    aws.StringValue(e.LogStreamName),
+		Message:   strings.TrimSuffix(aws.StringValue(e.Message), "\n"),
+	}
+
+	if parts := strings.Split(event.Stream, "/"); len(parts) == 3 {
+		event.Container = parts[1]
+		event.Task = parts[2]
+	}
+
+	return event
+}
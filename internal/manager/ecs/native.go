@@ -1,6 +1,7 @@
 package ecs
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
@@ -23,117 +24,143 @@ func (e *Manager) deployLocal(w io.Writer) error {
 
 	name := fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name)
 
-	dso, err := svc.DescribeServices(&ecs.DescribeServicesInput{
-		Cluster:  &e.App.Cluster,
-		Services: []*string{&name},
-	})
+	running, base, err := e.taskDefinitions(name)
 	if err != nil {
 		return err
 	}
 
-	if len(dso.Services) == 0 {
-		return fmt.Errorf("app %s not found not found in %s cluster", name, e.App.Cluster)
-	}
+	oldTaskDef := *base.TaskDefinition
 
-	dtdo, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
-		TaskDefinition: dso.Services[0].TaskDefinition,
-	})
+	rtd, err := e.nextTaskDefinition(running, base)
 	if err != nil {
 		return err
 	}
 
-	definitions, err := svc.ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{
-		FamilyPrefix: &name,
-		Sort:         aws.String(ecs.SortOrderDesc),
-	})
-	if err != nil {
+	newTaskDef := *rtd
+
+	if err = e.preDeploy(w, &newTaskDef); err != nil {
 		return err
 	}
 
-	var oldTaskDef ecs.TaskDefinition
-	var newTaskDef ecs.TaskDefinition
+	if err = e.updateTaskDefinition(&newTaskDef, nil, name, "Deploying new task definition"); err != nil {
+		if err := e.getLastContainerLogs(fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name)); err != nil {
+			pterm.Println("Failed to get logs:", err)
+		}
 
-	if len(definitions.TaskDefinitionArns) != 0 && *dtdo.TaskDefinition.TaskDefinitionArn != *definitions.TaskDefinitionArns[0] {
-		definition, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
-			TaskDefinition: definitions.TaskDefinitionArns[0],
-		})
+		if errors.Is(err, errRolledBack) {
+			return err
+		}
+
+		sr, err := getStoppedReason(e.App.Cluster, name, svc)
 		if err != nil {
 			return err
 		}
 
-		oldTaskDef = *definition.TaskDefinition
-	} else {
-		oldTaskDef = *dtdo.TaskDefinition
+		pterm.Printfln("Container %s couldn't start: %s", name, sr)
+
+		pterm.Printfln("Rolling back to old task definition: %s:%d", *oldTaskDef.Family, *oldTaskDef.Revision)
+		e.App.Timeout = 600
+		if err = e.updateTaskDefinition(&oldTaskDef, &newTaskDef, name, "Deploying previous task definition"); err != nil {
+			return fmt.Errorf("unable to rollback to old task definition: %w", err)
+		}
+
+		pterm.Println("Rollback successful")
+
+		return fmt.Errorf("deployment failed, but service has been rolled back to previous task definition: %s", *oldTaskDef.Family)
 	}
 
-	pterm.Printfln("Deploying based on task definition: %s:%d", *oldTaskDef.Family, *oldTaskDef.Revision)
+	return e.pruneRevisions(name, &newTaskDef)
+}
+
+// registerTaskDefinition registers a new revision of the task definition with images of
+// the app container and the containers from the containers map changed to the ones being deployed
+func (e *Manager) registerTaskDefinition(td *ecs.TaskDefinition, tags []*ecs.Tag) (*ecs.TaskDefinition, error) {
+	pterm.Printfln("Deploying based on task definition: %s:%d", *td.Family, *td.Revision)
 
-	var image string
+	images := e.containerImages()
 
-	for i := 0; i < len(oldTaskDef.ContainerDefinitions); i++ {
-		container := oldTaskDef.ContainerDefinitions[i]
+	for i := 0; i < len(td.ContainerDefinitions); i++ {
+		container := td.ContainerDefinitions[i]
 
-		// We are changing the image/tag only for the app-specific container (not sidecars)
-		if *container.Name == e.App.Name {
+		var image string
+
+		// Sidecars are changed only if they are built by ize
+		switch _, ok := images[*container.Name]; {
+		case *container.Name == e.App.Name:
 			if len(e.Project.Tag) != 0 && len(e.App.Image) == 0 {
 				name := strings.Split(*container.Image, ":")[0]
 				image = fmt.Sprintf("%s:%s", name, e.Project.Tag)
 			} else {
 				image = e.App.Image
 			}
-
-			pterm.Printfln(`Changed image of container "%s" to : "%s" (was: "%s")`, *container.Name, image, *container.Image)
-			container.Image = &image
+		case ok:
+			image = images[*container.Name]
+		default:
+			continue
 		}
+
+		pterm.Printfln(`Changed image of container "%s" to : "%s" (was: "%s")`, *container.Name, image, *container.Image)
+		container.Image = &image
 	}
 
 	pterm.Println("Creating new task definition revision")
 
-	rtdo, err := svc.RegisterTaskDefinition(&ecs.RegisterTaskDefinitionInput{
-		ContainerDefinitions:    oldTaskDef.ContainerDefinitions,
-		Family:                  oldTaskDef.Family,
-		Volumes:                 oldTaskDef.Volumes,
-		TaskRoleArn:             oldTaskDef.TaskRoleArn,
-		ExecutionRoleArn:        oldTaskDef.ExecutionRoleArn,
-		RuntimePlatform:         oldTaskDef.RuntimePlatform,
-		RequiresCompatibilities: oldTaskDef.RequiresCompatibilities,
-		NetworkMode:             oldTaskDef.NetworkMode,
-		Cpu:                     oldTaskDef.Cpu,
-		Memory:                  oldTaskDef.Memory,
-	})
+	rtdo, err := e.Project.AWSClient.ECSClient.RegisterTaskDefinition(registerInput(td, tags))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	newTaskDef = *rtdo.TaskDefinition
-
 	pterm.Printfln("Successfully created revision: %s:%d", *rtdo.TaskDefinition.Family, *rtdo.TaskDefinition.Revision)
 
-	if err = e.updateTaskDefinition(&newTaskDef, &oldTaskDef, name, "Deploying new task definition"); err != nil {
-		err := e.getLastContainerLogs(fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name))
-		if err != nil {
-			pterm.Println("Failed to get logs:", err)
-		}
+	return rtdo.TaskDefinition, nil
+}
 
-		sr, err := getStoppedReason(e.App.Cluster, name, svc)
-		if err != nil {
-			return err
-		}
+// taskDefinitions returns the task definition used by the app service and the one the next
+// deployment is based on (the latest registered revision of the family) along with their tags
+func (e *Manager) taskDefinitions(name string) (*ecs.DescribeTaskDefinitionOutput, *ecs.DescribeTaskDefinitionOutput, error) {
+	svc := e.Project.AWSClient.ECSClient
 
-		pterm.Printfln("Container %s couldn't start: %s", name, sr)
+	dso, err := svc.DescribeServices(&ecs.DescribeServicesInput{
+		Cluster:  &e.App.Cluster,
+		Services: []*string{&name},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
-		pterm.Printfln("Rolling back to old task definition: %s:%d", *oldTaskDef.Family, *oldTaskDef.Revision)
-		e.App.Timeout = 600
-		if err = e.updateTaskDefinition(&oldTaskDef, &newTaskDef, name, "Deploying previous task definition"); err != nil {
-			return fmt.Errorf("unable to rollback to old task definition: %w", err)
-		}
+	if len(dso.Services) == 0 {
+		return nil, nil, fmt.Errorf("app %s not found not found in %s cluster", name, e.App.Cluster)
+	}
 
-		pterm.Println("Rollback successful")
+	dtdo, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: dso.Services[0].TaskDefinition,
+		Include:        aws.StringSlice([]string{ecs.TaskDefinitionFieldTags}),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
-		return fmt.Errorf("deployment failed, but service has been rolled back to previous task definition: %s", *oldTaskDef.Family)
+	definitions, err := svc.ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: &name,
+		Sort:         aws.String(ecs.SortOrderDesc),
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return nil
+	if len(definitions.TaskDefinitionArns) != 0 && *dtdo.TaskDefinition.TaskDefinitionArn != *definitions.TaskDefinitionArns[0] {
+		definition, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+			TaskDefinition: definitions.TaskDefinitionArns[0],
+			Include:        aws.StringSlice([]string{ecs.TaskDefinitionFieldTags}),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return dtdo, definition, nil
+	}
+
+	return dtdo, dtdo, nil
 }
 
 func (e *Manager) redeployLocal(w io.Writer) error {
@@ -227,16 +254,50 @@ func (e *Manager) updateTaskDefinition(newTD *ecs.TaskDefinition, oldTD *ecs.Tas
 
 	svc := e.Project.AWSClient.ECSClient
 
-	uso, err := svc.UpdateService(&ecs.UpdateServiceInput{
+	input := &ecs.UpdateServiceInput{
 		Service:            aws.String(serviceName),
 		Cluster:            aws.String(e.App.Cluster),
 		TaskDefinition:     aws.String(*newTD.TaskDefinitionArn),
 		ForceNewDeployment: aws.Bool(true),
-	})
+	}
+
+	if e.App.CircuitBreaker {
+		dso, err := getService(serviceName, e.App.Cluster, svc)
+		if err != nil {
+			return err
+		}
+
+		// The rest of the deployment configuration is kept as is
+		dc := &ecs.DeploymentConfiguration{}
+		if dso.Services[0].DeploymentConfiguration != nil {
+			*dc = *dso.Services[0].DeploymentConfiguration
+		}
+
+		dc.DeploymentCircuitBreaker = &ecs.DeploymentCircuitBreaker{
+			Enable:   aws.Bool(true),
+			Rollback: aws.Bool(true),
+		}
+		input.DeploymentConfiguration = dc
+	}
+
+	since := time.Now()
+
+	uso, err := svc.UpdateService(input)
 	if err != nil {
 		return fmt.Errorf("unable to update service: %w", err)
 	}
 
+	// the deployment created by the update is tracked, so that a rollback isn't taken for its completion
+	var deployment string
+	for _, d := range uso.Service.Deployments {
+		if aws.StringValue(d.Status) == "PRIMARY" {
+			deployment = aws.StringValue(d.Id)
+			if d.CreatedAt != nil {
+				since = *d.CreatedAt
+			}
+		}
+	}
+
 	var dtgo *elbv2.DescribeTargetGroupsOutput
 	if e.App.Unsafe {
 		elb := e.Project.AWSClient.ELBV2Client
@@ -264,9 +325,10 @@ func (e *Manager) updateTaskDefinition(newTD *ecs.TaskDefinition, oldTD *ecs.Tas
 
 	waitingTimeout := time.Now().Add(time.Duration(e.App.Timeout) * time.Second)
 	waiting := true
+	r := newRollout(since, deployment)
 
 	for waiting && time.Now().Before(waitingTimeout) {
-		d, err := isDeployed(svc, serviceName, e.App.Cluster)
+		d, err := r.watch(e, serviceName)
 		if err != nil {
 			return err
 		}
@@ -305,20 +367,9 @@ func (e *Manager) updateTaskDefinition(newTD *ecs.TaskDefinition, oldTD *ecs.Tas
 	return nil
 }
 
-func isDeployed(svc ecsiface.ECSAPI, name string, cluster string) (bool, error) {
-	dso, err := svc.DescribeServices(&ecs.DescribeServicesInput{
-		Cluster:  &cluster,
-		Services: []*string{&name},
-	})
-	if err != nil {
-		return false, err
-	}
-
-	if len(dso.Services) == 0 {
-		return false, nil
-	}
-
-	if len(dso.Services[0].Deployments) != 1 {
+// isDeployed reports whether all desired tasks of the service run its task definition
+func isDeployed(svc ecsiface.ECSAPI, service *ecs.Service, name string, cluster string) (bool, error) {
+	if len(service.Deployments) != 1 {
 		return false, nil
 	}
 
@@ -331,15 +382,15 @@ func isDeployed(svc ecsiface.ECSAPI, name string, cluster string) (bool, error)
 	}
 
 	if len(runningTasks.TaskArns) == 0 {
-		return *dso.Services[0].DesiredCount == 0, nil
+		return *service.DesiredCount == 0, nil
 	}
 
-	runningCount, err := getRunningTaskCount(cluster, runningTasks.TaskArns, *dso.Services[0].TaskDefinition, svc)
+	runningCount, err := getRunningTaskCount(cluster, runningTasks.TaskArns, *service.TaskDefinition, svc)
 	if err != nil {
 		return false, err
 	}
 
-	return runningCount == *dso.Services[0].DesiredCount, nil
+	return runningCount == *service.DesiredCount, nil
 }
 
 func getRunningTaskCount(cluster string, tasks []*string, serviceArn string, svc ecsiface.ECSAPI) (int64, error) {
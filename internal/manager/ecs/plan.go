@@ -0,0 +1,127 @@
+package ecs
+
+import (
+	"fmt"
+
+	"github.com/hazelops/ize/internal/aws/utils"
+)
+
+// ImageChange describes how the image of a task definition container changes on deploy
+type ImageChange struct {
+	Container string
+	Before    string
+	After     string
+}
+
+// TaskDefinitionDiff is the result of the deployment preview
+type TaskDefinitionDiff struct {
+	// TaskDefinition is the family:revision the service is running
+	TaskDefinition string
+	Images         []ImageChange
+	// Changes is the unified diff of the task definition when it's rendered from task_definition_file
+	Changes string
+	Skipped bool
+}
+
+// Changed reports whether the deploy would change the task definition or any container image
+func (d *TaskDefinitionDiff) Changed() bool {
+	if len(d.Changes) != 0 {
+		return true
+	}
+
+	for _, i := range d.Images {
+		if i.Before != i.After {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Plan computes the task definition diff between the running service revision
+// and the revision that would be registered by Deploy, without changing anything
+func (e *Manager) Plan() (*TaskDefinitionDiff, error) {
+	e.prepare()
+
+	if e.App.SkipDeploy {
+		return &TaskDefinitionDiff{Skipped: true}, nil
+	}
+
+	if len(e.App.AwsRegion) != 0 && len(e.App.AwsProfile) != 0 {
+		sess, err := utils.GetSession(&utils.SessionConfig{
+			Region:  e.App.AwsRegion,
+			Profile: e.App.AwsProfile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't get session: %w", err)
+		}
+
+		e.Project.SettingAWSClient(sess)
+	}
+
+	// the same image Deploy falls back to
+	image := e.App.Image
+	if image == "" {
+		image = fmt.Sprintf("%s/%s:%s",
+			e.App.DockerRegistry,
+			fmt.Sprintf("%s-%s", e.Project.Namespace, e.App.Name),
+			fmt.Sprintf("%s-%s", e.Project.Env, "latest"))
+	}
+
+	name := fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name)
+
+	current, base, err := e.taskDefinitions(name)
+	if err != nil {
+		return nil, fmt.Errorf("can't get task definitions of %s: %w", name, err)
+	}
+
+	diff := &TaskDefinitionDiff{
+		TaskDefinition: fmt.Sprintf("%s:%d", *current.TaskDefinition.Family, *current.TaskDefinition.Revision),
+	}
+
+	running := map[string]string{}
+	for _, c := range current.TaskDefinition.ContainerDefinitions {
+		running[*c.Name] = *c.Image
+	}
+
+	if len(e.App.TaskDefinitionFile) != 0 {
+		input, err := e.renderTaskDefinition(image)
+		if err != nil {
+			return nil, err
+		}
+
+		diff.Changes, err = diffTaskDefinitions(current, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range input.ContainerDefinitions {
+			diff.Images = append(diff.Images, ImageChange{
+				Container: *c.Name,
+				Before:    running[*c.Name],
+				After:     *c.Image,
+			})
+		}
+
+		return diff, nil
+	}
+
+	images := e.containerImages()
+
+	for _, c := range base.TaskDefinition.ContainerDefinitions {
+		after := *c.Image
+		if *c.Name == e.App.Name {
+			after = image
+		} else if i, ok := images[*c.Name]; ok {
+			after = i
+		}
+
+		diff.Images = append(diff.Images, ImageChange{
+			Container: *c.Name,
+			Before:    running[*c.Name],
+			After:     after,
+		})
+	}
+
+	return diff, nil
+}
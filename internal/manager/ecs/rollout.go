@@ -0,0 +1,129 @@
+package ecs
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pterm/pterm"
+)
+
+// errRolledBack is returned when the ECS deployment circuit breaker has rolled the service back
+var errRolledBack = errors.New("deployment failed and the service has been rolled back by the ECS deployment circuit breaker")
+
+// rollout tracks the deployment of a service started by UpdateService
+type rollout struct {
+	since time.Time
+	// deployment is the ID of the deployment created by UpdateService.
+	// The primary deployment is tracked if it's not known.
+	deployment  string
+	seen        map[string]bool
+	failedTasks int64
+}
+
+func newRollout(since time.Time, deployment string) *rollout {
+	return &rollout{
+		since:      since,
+		deployment: deployment,
+		seen:       map[string]bool{},
+	}
+}
+
+// watch prints new service events and reports whether the deployment is completed.
+// The rollout state of the deployment is used when ECS reports it, otherwise running tasks are counted.
+// The deployment is failed if it's replaced by another one, e.g. by the circuit breaker rollback.
+func (r *rollout) watch(e *Manager, name string) (bool, error) {
+	svc := e.Project.AWSClient.ECSClient
+
+	dso, err := svc.DescribeServices(&ecs.DescribeServicesInput{
+		Cluster:  &e.App.Cluster,
+		Services: []*string{&name},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if len(dso.Services) == 0 {
+		return false, nil
+	}
+
+	service := dso.Services[0]
+
+	r.printEvents(service.Events)
+
+	var primary, deployment *ecs.Deployment
+	for _, d := range service.Deployments {
+		if aws.StringValue(d.Status) == "PRIMARY" {
+			primary = d
+		}
+
+		if len(r.deployment) != 0 && aws.StringValue(d.Id) == r.deployment {
+			deployment = d
+		}
+	}
+
+	if len(r.deployment) == 0 {
+		deployment = primary
+	} else if deployment == nil || deployment != primary {
+		return false, r.replaced(e, deployment, primary)
+	}
+
+	if deployment == nil || deployment.RolloutState == nil {
+		return isDeployed(svc, service, name, e.App.Cluster)
+	}
+
+	if failed := aws.Int64Value(deployment.FailedTasks); failed > r.failedTasks {
+		pterm.Warning.Printfln("%d task(s) of the deployment failed to start", failed)
+		r.failedTasks = failed
+	}
+
+	switch aws.StringValue(deployment.RolloutState) {
+	case ecs.DeploymentRolloutStateCompleted:
+		return len(service.Deployments) == 1, nil
+	case ecs.DeploymentRolloutStateFailed:
+		pterm.Printfln("Deployment %s failed: %s", aws.StringValue(deployment.Id), aws.StringValue(deployment.RolloutStateReason))
+		if e.App.CircuitBreaker {
+			return false, errRolledBack
+		}
+
+		return false, fmt.Errorf("deployment failed: %s", aws.StringValue(deployment.RolloutStateReason))
+	default:
+		return false, nil
+	}
+}
+
+// replaced returns the error of the tracked deployment which is no longer the primary one.
+// The deployment may be already removed from the service.
+func (r *rollout) replaced(e *Manager, deployment, primary *ecs.Deployment) error {
+	if deployment != nil && aws.StringValue(deployment.RolloutState) == ecs.DeploymentRolloutStateFailed {
+		pterm.Printfln("Deployment %s failed: %s", r.deployment, aws.StringValue(deployment.RolloutStateReason))
+	}
+
+	if e.App.CircuitBreaker {
+		return errRolledBack
+	}
+
+	if primary == nil {
+		return fmt.Errorf("deployment %s has been replaced", r.deployment)
+	}
+
+	return fmt.Errorf("deployment %s has been replaced by deployment %s of %s", r.deployment, aws.StringValue(primary.Id), aws.StringValue(primary.TaskDefinition))
+}
+
+// printEvents prints service events created after the deployment has started in chronological order
+func (r *rollout) printEvents(events []*ecs.ServiceEvent) {
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+
+		id := aws.StringValue(event.Id)
+		if r.seen[id] || aws.TimeValue(event.CreatedAt).Before(r.since) {
+			continue
+		}
+
+		r.seen[id] = true
+
+		pterm.Printfln("%s %s", aws.TimeValue(event.CreatedAt).Format(time.Stamp), aws.StringValue(event.Message))
+	}
+}
@@ -0,0 +1,367 @@
+package ecs
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/pterm/pterm"
+)
+
+const (
+	strategyRolling   = "rolling"
+	strategyBlueGreen = "blue_green"
+	strategyCanary    = "canary"
+)
+
+// trafficShiftPollInterval is the interval of task set stability and target health checks
+var trafficShiftPollInterval = 10 * time.Second
+
+// deployTrafficShift deploys the app as a new task set behind the idle target group and
+// shifts listener traffic to it (at once for blue_green, in two steps for canary).
+// The traffic is shifted back if any target of the new task set becomes unhealthy during the bake time.
+func (e *Manager) deployTrafficShift(w io.Writer) error {
+	pterm.SetDefaultOutput(w)
+
+	if len(e.App.ListenerArn) == 0 || len(e.App.TargetGroupArns) != 2 {
+		return fmt.Errorf("%s deployment requires listener_arn and two target_group_arns", e.App.DeploymentStrategy)
+	}
+
+	svc := e.Project.AWSClient.ECSClient
+
+	name := fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name)
+
+	dso, err := getService(name, e.App.Cluster, svc)
+	if err != nil {
+		return err
+	}
+
+	service := dso.Services[0]
+
+	if service.DeploymentController == nil || *service.DeploymentController.Type != ecs.DeploymentControllerTypeExternal {
+		return fmt.Errorf("%s deployment requires %s service to use %s deployment controller", e.App.DeploymentStrategy, name, ecs.DeploymentControllerTypeExternal)
+	}
+
+	var primary *ecs.TaskSet
+	for _, ts := range service.TaskSets {
+		if aws.StringValue(ts.Status) == "PRIMARY" {
+			primary = ts
+		}
+	}
+
+	if primary == nil || len(primary.LoadBalancers) == 0 {
+		return fmt.Errorf("primary task set with a load balancer not found in %s service", name)
+	}
+
+	blue := aws.StringValue(primary.LoadBalancers[0].TargetGroupArn)
+
+	var green string
+	switch blue {
+	case e.App.TargetGroupArns[0]:
+		green = e.App.TargetGroupArns[1]
+	case e.App.TargetGroupArns[1]:
+		green = e.App.TargetGroupArns[0]
+	default:
+		return fmt.Errorf("target group %s of the primary task set is not listed in target_group_arns", blue)
+	}
+
+	dtdo, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: primary.TaskDefinition,
+		Include:        aws.StringSlice([]string{ecs.TaskDefinitionFieldTags}),
+	})
+	if err != nil {
+		return err
+	}
+
+	oldTaskDef := *dtdo.TaskDefinition
+
+	newTaskDef, err := e.nextTaskDefinition(dtdo, dtdo)
+	if err != nil {
+		return err
+	}
+
+	if err = e.preDeploy(w, newTaskDef); err != nil {
+		return err
+	}
+
+	input := &ecs.CreateTaskSetInput{
+		Cluster:        aws.String(e.App.Cluster),
+		Service:        aws.String(name),
+		TaskDefinition: newTaskDef.TaskDefinitionArn,
+		LoadBalancers: []*ecs.LoadBalancer{{
+			TargetGroupArn: aws.String(green),
+			ContainerName:  primary.LoadBalancers[0].ContainerName,
+			ContainerPort:  primary.LoadBalancers[0].ContainerPort,
+		}},
+		NetworkConfiguration: primary.NetworkConfiguration,
+		PlatformVersion:      primary.PlatformVersion,
+		ServiceRegistries:    primary.ServiceRegistries,
+		Scale: &ecs.Scale{
+			Unit:  aws.String(ecs.ScaleUnitPercent),
+			Value: aws.Float64(100),
+		},
+	}
+
+	if len(primary.CapacityProviderStrategy) != 0 {
+		input.CapacityProviderStrategy = primary.CapacityProviderStrategy
+	} else {
+		input.LaunchType = primary.LaunchType
+	}
+
+	pterm.Printfln("Creating new task set in %s target group", green)
+
+	ctso, err := svc.CreateTaskSet(input)
+	if err != nil {
+		return fmt.Errorf("unable to create task set: %w", err)
+	}
+
+	taskSet := ctso.TaskSet
+
+	if err = e.waitTaskSet(name, taskSet, green); err != nil {
+		if err := e.cleanupTaskSet(name, taskSet, newTaskDef); err != nil {
+			pterm.Println("Failed to cleanup task set:", err)
+		}
+
+		return fmt.Errorf("new task set didn't become healthy: %w", err)
+	}
+
+	// rollback shifts the traffic back to the previous task set (making it primary again if it's not) and deletes the new one
+	rollback := func(cause error, primarySwitched bool) error {
+		pterm.Printfln("Rolling back: %s", cause)
+
+		if primarySwitched {
+			_, err := svc.UpdateServicePrimaryTaskSet(&ecs.UpdateServicePrimaryTaskSetInput{
+				Cluster:        aws.String(e.App.Cluster),
+				Service:        aws.String(name),
+				PrimaryTaskSet: primary.TaskSetArn,
+			})
+			if err != nil {
+				return fmt.Errorf("deployment failed (%v) and primary task set can't be restored to %s: %w", cause, aws.StringValue(primary.TaskSetArn), err)
+			}
+		}
+
+		if err := e.shiftTraffic(blue, 100, green, 0); err != nil {
+			return fmt.Errorf("deployment failed (%v) and traffic can't be shifted back to %s: %w", cause, blue, err)
+		}
+
+		if err := e.cleanupTaskSet(name, taskSet, newTaskDef); err != nil {
+			pterm.Println("Failed to cleanup task set:", err)
+		}
+
+		return fmt.Errorf("deployment failed (%w), but traffic has been shifted back to task definition: %s:%d", cause, *oldTaskDef.Family, *oldTaskDef.Revision)
+	}
+
+	weights := []int64{100}
+	if e.App.DeploymentStrategy == strategyCanary {
+		weights = []int64{int64(e.App.CanaryPercent), 100}
+	}
+
+	for _, weight := range weights {
+		pterm.Printfln("Shifting %d%% of traffic to the new task set", weight)
+
+		if err = e.shiftTraffic(blue, 100-weight, green, weight); err != nil {
+			return rollback(fmt.Errorf("unable to shift traffic: %w", err), false)
+		}
+
+		if err = e.bake(green); err != nil {
+			return rollback(err, false)
+		}
+	}
+
+	_, err = svc.UpdateServicePrimaryTaskSet(&ecs.UpdateServicePrimaryTaskSetInput{
+		Cluster:        aws.String(e.App.Cluster),
+		Service:        aws.String(name),
+		PrimaryTaskSet: taskSet.TaskSetArn,
+	})
+	if err != nil {
+		return rollback(fmt.Errorf("unable to update primary task set: %w", err), false)
+	}
+
+	pterm.Println("Deleting previous task set")
+
+	_, err = svc.DeleteTaskSet(&ecs.DeleteTaskSetInput{
+		Cluster: aws.String(e.App.Cluster),
+		Service: aws.String(name),
+		TaskSet: primary.TaskSetArn,
+	})
+	if err != nil {
+		return rollback(fmt.Errorf("unable to delete previous task set: %w", err), true)
+	}
+
+	return e.pruneRevisions(name, newTaskDef)
+}
+
+// waitTaskSet waits for the task set to reach steady state and for its targets to become healthy
+func (e *Manager) waitTaskSet(name string, taskSet *ecs.TaskSet, targetGroup string) error {
+	svc := e.Project.AWSClient.ECSClient
+
+	pterm.Println("Waiting for the new task set to become healthy")
+
+	waitingTimeout := time.Now().Add(time.Duration(e.App.Timeout) * time.Second)
+
+	for time.Now().Before(waitingTimeout) {
+		dtso, err := svc.DescribeTaskSets(&ecs.DescribeTaskSetsInput{
+			Cluster:  aws.String(e.App.Cluster),
+			Service:  aws.String(name),
+			TaskSets: []*string{taskSet.TaskSetArn},
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(dtso.TaskSets) != 0 && aws.StringValue(dtso.TaskSets[0].StabilityStatus) == ecs.StabilityStatusSteadyState {
+			healthy, err := e.targetsHealthy(targetGroup)
+			if err != nil {
+				return err
+			}
+
+			if healthy {
+				return nil
+			}
+		}
+
+		time.Sleep(trafficShiftPollInterval)
+	}
+
+	return fmt.Errorf("timeout waiting for task set %s", aws.StringValue(taskSet.Id))
+}
+
+// bake watches target health of the target group during the bake time
+func (e *Manager) bake(targetGroup string) error {
+	pterm.Printfln("Baking for %ds", e.App.BakeTime)
+
+	bakeUntil := time.Now().Add(time.Duration(e.App.BakeTime) * time.Second)
+
+	for {
+		healthy, err := e.targetsHealthy(targetGroup)
+		if err != nil {
+			return err
+		}
+
+		if !healthy {
+			return fmt.Errorf("targets of %s became unhealthy", targetGroup)
+		}
+
+		if !time.Now().Before(bakeUntil) {
+			return nil
+		}
+
+		time.Sleep(trafficShiftPollInterval)
+	}
+}
+
+// targetsHealthy reports whether the target group has targets and all of them are healthy
+func (e *Manager) targetsHealthy(targetGroup string) (bool, error) {
+	dtho, err := e.Project.AWSClient.ELBV2Client.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{
+		TargetGroupArn: aws.String(targetGroup),
+	})
+	if err != nil {
+		return false, fmt.Errorf("can't describe target health: %w", err)
+	}
+
+	if len(dtho.TargetHealthDescriptions) == 0 {
+		return false, nil
+	}
+
+	for _, d := range dtho.TargetHealthDescriptions {
+		if d.TargetHealth == nil || aws.StringValue(d.TargetHealth.State) != elbv2.TargetHealthStateEnumHealthy {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// shiftTraffic sets weights of the blue and green target groups in the listener (or listener rule) forward action.
+// Other actions and the stickiness of the forward action are kept as they are.
+func (e *Manager) shiftTraffic(blue string, blueWeight int64, green string, greenWeight int64) error {
+	elb := e.Project.AWSClient.ELBV2Client
+	isRule := strings.Contains(e.App.ListenerArn, ":listener-rule/")
+
+	var actions []*elbv2.Action
+	if isRule {
+		dro, err := elb.DescribeRules(&elbv2.DescribeRulesInput{
+			RuleArns: aws.StringSlice([]string{e.App.ListenerArn}),
+		})
+		if err != nil {
+			return fmt.Errorf("can't describe listener rule: %w", err)
+		}
+		if len(dro.Rules) == 0 {
+			return fmt.Errorf("listener rule %s not found", e.App.ListenerArn)
+		}
+		actions = dro.Rules[0].Actions
+	} else {
+		dlo, err := elb.DescribeListeners(&elbv2.DescribeListenersInput{
+			ListenerArns: aws.StringSlice([]string{e.App.ListenerArn}),
+		})
+		if err != nil {
+			return fmt.Errorf("can't describe listener: %w", err)
+		}
+		if len(dlo.Listeners) == 0 {
+			return fmt.Errorf("listener %s not found", e.App.ListenerArn)
+		}
+		actions = dlo.Listeners[0].DefaultActions
+	}
+
+	var forward *elbv2.Action
+	for _, a := range actions {
+		switch aws.StringValue(a.Type) {
+		case elbv2.ActionTypeEnumForward:
+			forward = a
+		case elbv2.ActionTypeEnumAuthenticateOidc:
+			// client secrets aren't returned by describe calls
+			if a.AuthenticateOidcConfig != nil && a.AuthenticateOidcConfig.ClientSecret == nil {
+				a.AuthenticateOidcConfig.UseExistingClientSecret = aws.Bool(true)
+			}
+		}
+	}
+	if forward == nil {
+		return fmt.Errorf("%s has no forward action", e.App.ListenerArn)
+	}
+
+	if forward.ForwardConfig == nil {
+		forward.ForwardConfig = &elbv2.ForwardActionConfig{}
+	}
+	// a forward to several target groups can be set only in the forward config
+	forward.TargetGroupArn = nil
+	forward.ForwardConfig.TargetGroups = []*elbv2.TargetGroupTuple{
+		{TargetGroupArn: aws.String(blue), Weight: aws.Int64(blueWeight)},
+		{TargetGroupArn: aws.String(green), Weight: aws.Int64(greenWeight)},
+	}
+
+	if isRule {
+		_, err := elb.ModifyRule(&elbv2.ModifyRuleInput{
+			RuleArn: aws.String(e.App.ListenerArn),
+			Actions: actions,
+		})
+		return err
+	}
+
+	_, err := elb.ModifyListener(&elbv2.ModifyListenerInput{
+		ListenerArn:    aws.String(e.App.ListenerArn),
+		DefaultActions: actions,
+	})
+	return err
+}
+
+func (e *Manager) cleanupTaskSet(name string, taskSet *ecs.TaskSet, td *ecs.TaskDefinition) error {
+	svc := e.Project.AWSClient.ECSClient
+
+	pterm.Println("Deleting new task set")
+
+	_, err := svc.DeleteTaskSet(&ecs.DeleteTaskSetInput{
+		Cluster: aws.String(e.App.Cluster),
+		Service: aws.String(name),
+		TaskSet: taskSet.TaskSetArn,
+		Force:   aws.Bool(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	return deregisterTaskDefinition(svc, td)
+}
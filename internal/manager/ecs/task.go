@@ -0,0 +1,408 @@
+package ecs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/hazelops/ize/internal/config"
+	"github.com/pterm/pterm"
+)
+
+// taskPollInterval is the interval of one-off task status and log checks
+var taskPollInterval = 5 * time.Second
+
+// NetworkConfiguration is the network part of the terraform output stored in SSM
+type NetworkConfiguration struct {
+	SecurityGroups struct {
+		Value string `json:"value"`
+	} `json:"security_groups"`
+	Subnets struct {
+		Value [][]string `json:"value"`
+	} `json:"subnets"`
+	VpcPrivateSubnets struct {
+		Value []string `json:"value"`
+	} `json:"vpc_private_subnets"`
+	VpcPublicSubnets struct {
+		Value []string `json:"value"`
+	} `json:"vpc_public_subnets"`
+}
+
+// GetNetworkConfiguration reads the network configuration from the terraform output of the env
+func GetNetworkConfiguration(svc ssmiface.SSMAPI, env string) (NetworkConfiguration, error) {
+	resp, err := svc.GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(fmt.Sprintf("/%s/terraform-output", env)),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return NetworkConfiguration{}, fmt.Errorf("can't get terraform output: %w", err)
+	}
+
+	var value []byte
+
+	value, err = base64.StdEncoding.DecodeString(*resp.Parameter.Value)
+	if err != nil {
+		return NetworkConfiguration{}, fmt.Errorf("can't get terraform output: %w", err)
+	}
+
+	var output NetworkConfiguration
+
+	err = json.Unmarshal(value, &output)
+	if err != nil {
+		return NetworkConfiguration{}, fmt.Errorf("can't get network configuration: %w", err)
+	}
+
+	if len(output.VpcPrivateSubnets.Value) == 0 {
+		return NetworkConfiguration{}, fmt.Errorf("output private_subnets is missing. Please add it to your Terraform")
+	}
+
+	if len(output.SecurityGroups.Value) == 0 {
+		return NetworkConfiguration{}, fmt.Errorf("output security_groups is missing. Please add it to your Terraform")
+	}
+
+	return output, nil
+}
+
+// SecurityGroupIDs returns IDs of the security_groups output (a comma separated list)
+func (c NetworkConfiguration) SecurityGroupIDs() []string {
+	var ids []string
+	for _, id := range strings.Split(c.SecurityGroups.Value, ",") {
+		if id = strings.TrimSpace(id); len(id) != 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// Task is a one-off task started from a task definition of the app
+type Task struct {
+	Cluster        string
+	TaskDefinition string
+	// Container is the container the command is run in and whose logs are streamed
+	Container string
+	// Command overrides the command of the container if set
+	Command []string
+	// Environment is added to the environment of the container
+	Environment map[string]string
+	// Cpu and Memory override task level resources if set
+	Cpu    string
+	Memory string
+	// LaunchType is FARGATE by default. It's ignored if CapacityProvider is set
+	LaunchType       string
+	CapacityProvider string
+	LogGroup         string
+	Subnets          []string
+	SecurityGroups   []string
+}
+
+// TaskResult is the state of the stopped task
+type TaskResult struct {
+	TaskArn string
+	// ExitCode is nil if the container didn't exit by itself (e.g. it failed to start)
+	ExitCode      *int64
+	StoppedReason string
+}
+
+// RunTask starts the one-off Fargate task, streams logs of its container to w until the task stops
+// and returns the exit code of the container. The task is stopped if ctx is canceled.
+func RunTask(ctx context.Context, project *config.Project, task Task, w io.Writer) (*TaskResult, error) {
+	svc := project.AWSClient.ECSClient
+
+	// logs and task status are written concurrently
+	w = &syncWriter{w: w}
+
+	out, err := svc.RunTaskWithContext(ctx, task.input())
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ecs.ErrCodeClusterNotFoundException {
+			return nil, fmt.Errorf("ECS cluster %s not found", task.Cluster)
+		}
+
+		return nil, fmt.Errorf("can't run task: %w", err)
+	}
+
+	if len(out.Tasks) == 0 {
+		var reasons []string
+		for _, f := range out.Failures {
+			reasons = append(reasons, fmt.Sprintf("%s (%s)", aws.StringValue(f.Reason), aws.StringValue(f.Detail)))
+		}
+
+		return nil, fmt.Errorf("can't run task: %s", strings.Join(reasons, ", "))
+	}
+
+	taskArn := aws.StringValue(out.Tasks[0].TaskArn)
+	taskID := taskArn[strings.LastIndex(taskArn, "/")+1:]
+
+	fmt.Fprintf(w, "Started task %s\n", taskID)
+
+	logCtx, stopLogs := context.WithCancel(context.Background())
+	logsDone := make(chan struct{})
+
+	go func() {
+		streamTaskLogs(logCtx, project.AWSClient.CloudWatchLogsClient, task.LogGroup, fmt.Sprintf("main/%s/%s", task.Container, taskID), w)
+		close(logsDone)
+	}()
+
+	// The rest of the logs is fetched once the task stops
+	defer func() {
+		stopLogs()
+		<-logsDone
+	}()
+
+	status := ""
+
+	for {
+		dto, err := svc.DescribeTasks(&ecs.DescribeTasksInput{
+			Cluster: aws.String(task.Cluster),
+			Tasks:   aws.StringSlice([]string{taskArn}),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't describe task %s: %w", taskID, err)
+		}
+
+		if len(dto.Tasks) == 0 {
+			return nil, fmt.Errorf("task %s not found", taskID)
+		}
+
+		t := dto.Tasks[0]
+
+		if s := aws.StringValue(t.LastStatus); s != status {
+			status = s
+			fmt.Fprintf(w, "Task %s is %s\n", taskID, status)
+		}
+
+		if status == ecs.DesiredStatusStopped {
+			res := &TaskResult{
+				TaskArn:       taskArn,
+				StoppedReason: aws.StringValue(t.StoppedReason),
+			}
+
+			for _, c := range t.Containers {
+				if aws.StringValue(c.Name) == task.Container {
+					res.ExitCode = c.ExitCode
+				}
+			}
+
+			return res, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			_, err := svc.StopTask(&ecs.StopTaskInput{
+				Cluster: aws.String(task.Cluster),
+				Reason:  aws.String("Task stopped by IZE"),
+				Task:    aws.String(taskArn),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("can't stop task %s: %w", taskID, err)
+			}
+
+			return nil, ctx.Err()
+		case <-time.After(taskPollInterval):
+		}
+	}
+}
+
+// input returns the RunTask input of the task
+func (t Task) input() *ecs.RunTaskInput {
+	input := &ecs.RunTaskInput{
+		TaskDefinition: aws.String(t.TaskDefinition),
+		StartedBy:      aws.String("IZE"),
+		Cluster:        aws.String(t.Cluster),
+		NetworkConfiguration: &ecs.NetworkConfiguration{AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+			Subnets: aws.StringSlice(t.Subnets),
+		}},
+	}
+
+	if len(t.SecurityGroups) != 0 {
+		input.NetworkConfiguration.AwsvpcConfiguration.SecurityGroups = aws.StringSlice(t.SecurityGroups)
+	}
+
+	switch {
+	case len(t.CapacityProvider) != 0:
+		input.CapacityProviderStrategy = []*ecs.CapacityProviderStrategyItem{{
+			CapacityProvider: aws.String(t.CapacityProvider),
+			Weight:           aws.Int64(1),
+		}}
+	case len(t.LaunchType) != 0:
+		input.LaunchType = aws.String(t.LaunchType)
+	default:
+		input.LaunchType = aws.String(ecs.LaunchTypeFargate)
+	}
+
+	override := &ecs.ContainerOverride{
+		Name: aws.String(t.Container),
+	}
+
+	if len(t.Command) != 0 {
+		override.Command = aws.StringSlice(t.Command)
+	}
+
+	var names []string
+	for name := range t.Environment {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		override.Environment = append(override.Environment, &ecs.KeyValuePair{
+			Name:  aws.String(name),
+			Value: aws.String(t.Environment[name]),
+		})
+	}
+
+	overrides := &ecs.TaskOverride{}
+
+	if override.Command != nil || override.Environment != nil {
+		overrides.ContainerOverrides = []*ecs.ContainerOverride{override}
+	}
+
+	if len(t.Cpu) != 0 {
+		overrides.Cpu = aws.String(t.Cpu)
+	}
+
+	if len(t.Memory) != 0 {
+		overrides.Memory = aws.String(t.Memory)
+	}
+
+	if overrides.ContainerOverrides != nil || overrides.Cpu != nil || overrides.Memory != nil {
+		input.Overrides = overrides
+	}
+
+	return input
+}
+
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.w.Write(p)
+}
+
+// streamTaskLogs writes new events of the log stream to w until ctx is canceled.
+// Events written after the cancellation are fetched once more before returning.
+func streamTaskLogs(ctx context.Context, clw cloudwatchlogsiface.CloudWatchLogsAPI, logGroup string, logStream string, w io.Writer) {
+	var token *string
+
+	for {
+		done := ctx.Err() != nil
+
+		for {
+			out, err := clw.GetLogEvents(&cloudwatchlogs.GetLogEventsInput{
+				LogGroupName:  aws.String(logGroup),
+				LogStreamName: aws.String(logStream),
+				NextToken:     token,
+				StartFromHead: aws.Bool(true),
+			})
+			if err != nil {
+				// the stream doesn't exist until the container starts
+				break
+			}
+
+			for _, e := range out.Events {
+				fmt.Fprintln(w, aws.StringValue(e.Message))
+			}
+
+			if aws.StringValue(out.NextForwardToken) == aws.StringValue(token) {
+				break
+			}
+
+			token = out.NextForwardToken
+		}
+
+		if done {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(taskPollInterval):
+		}
+	}
+}
+
+// preDeploy runs the pre_deploy command of the app as a one-off task of the new task definition.
+// The task is stopped if it doesn't exit within the app timeout. The task definition is deregistered
+// if the command fails, so that it's not used by the next deployment.
+func (e *Manager) preDeploy(w io.Writer, td *ecs.TaskDefinition) error {
+	if e.App.PreDeploy == nil {
+		return nil
+	}
+
+	container := e.App.PreDeploy.Container
+	if len(container) == 0 {
+		container = e.App.Name
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(e.App.Timeout)*time.Second)
+	defer cancel()
+
+	err := e.runPreDeploy(ctx, w, td, container)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("task didn't exit in %ds and has been stopped", e.App.Timeout)
+	}
+
+	if err != nil {
+		if err := deregisterTaskDefinition(e.Project.AWSClient.ECSClient, td); err != nil {
+			pterm.Println("Failed to deregister task definition:", err)
+		}
+
+		return fmt.Errorf("pre-deploy command failed: %w", err)
+	}
+
+	return nil
+}
+
+func (e *Manager) runPreDeploy(ctx context.Context, w io.Writer, td *ecs.TaskDefinition, container string) error {
+	configuration, err := GetNetworkConfiguration(e.Project.AWSClient.SSMClient, e.Project.Env)
+	if err != nil {
+		return err
+	}
+
+	pterm.Printfln(`Running pre-deploy command in container "%s": %s`, container, strings.Join(e.App.PreDeploy.Command, " "))
+
+	res, err := RunTask(ctx, e.Project, Task{
+		Cluster:        e.App.Cluster,
+		TaskDefinition: aws.StringValue(td.TaskDefinitionArn),
+		Container:      container,
+		Command:        e.App.PreDeploy.Command,
+		LogGroup:       fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name),
+		Subnets:        configuration.VpcPrivateSubnets.Value,
+		SecurityGroups: configuration.SecurityGroupIDs(),
+	}, w)
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode == nil {
+		return fmt.Errorf("container %s didn't exit: %s", container, res.StoppedReason)
+	}
+
+	if *res.ExitCode != 0 {
+		return fmt.Errorf("exit code %d", *res.ExitCode)
+	}
+
+	pterm.Println("Pre-deploy command succeeded")
+
+	return nil
+}
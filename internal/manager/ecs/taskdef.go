@@ -0,0 +1,267 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/pterm/pterm"
+)
+
+// taskDefinitionData is the data available in the task_definition_file template
+type taskDefinitionData struct {
+	Name      string
+	Env       string
+	Namespace string
+	Tag       string
+	Image     string
+	// Images are images of the containers from the containers map by container name
+	Images         map[string]string
+	DockerRegistry string
+	Cluster        string
+	AwsRegion      string
+}
+
+// renderTaskDefinition renders task_definition_file of the app with the image being deployed.
+// The file has the same format as the output of "aws ecs describe-task-definition"
+// (optionally wrapped in "taskDefinition"), read-only fields are ignored.
+func (e *Manager) renderTaskDefinition(image string) (*ecs.RegisterTaskDefinitionInput, error) {
+	path := e.App.TaskDefinitionFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.App.Path, path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read task definition file: %w", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("can't parse task definition file %s: %w", path, err)
+	}
+
+	region := e.App.AwsRegion
+	if len(region) == 0 {
+		region = e.Project.AwsRegion
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, taskDefinitionData{
+		Name:           e.App.Name,
+		Env:            e.Project.Env,
+		Namespace:      e.Project.Namespace,
+		Tag:            e.Project.Tag,
+		Image:          image,
+		Images:         e.containerImages(),
+		DockerRegistry: e.App.DockerRegistry,
+		Cluster:        e.App.Cluster,
+		AwsRegion:      region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't render task definition file %s: %w", path, err)
+	}
+
+	var wrapped struct {
+		TaskDefinition *ecs.RegisterTaskDefinitionInput `json:"taskDefinition"`
+	}
+
+	if err = json.Unmarshal(buf.Bytes(), &wrapped); err != nil {
+		return nil, fmt.Errorf("can't decode task definition file %s: %w", path, err)
+	}
+
+	input := wrapped.TaskDefinition
+	if input == nil {
+		input = &ecs.RegisterTaskDefinitionInput{}
+		if err = json.Unmarshal(buf.Bytes(), input); err != nil {
+			return nil, fmt.Errorf("can't decode task definition file %s: %w", path, err)
+		}
+	}
+
+	if input.Family == nil {
+		input.Family = aws.String(fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name))
+	}
+
+	// Containers built by ize get the deployed images unless the file sets them
+	images := e.containerImages()
+	images[e.App.Name] = image
+
+	for _, c := range input.ContainerDefinitions {
+		if i, ok := images[aws.StringValue(c.Name)]; ok && len(aws.StringValue(c.Image)) == 0 {
+			c.Image = aws.String(i)
+		}
+	}
+
+	if err = input.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid task definition file %s: %w", path, err)
+	}
+
+	return input, nil
+}
+
+// registerTaskDefinitionFile registers task_definition_file of the app as a new revision
+// after printing how it differs from the running one
+func (e *Manager) registerTaskDefinitionFile(running *ecs.DescribeTaskDefinitionOutput) (*ecs.TaskDefinition, error) {
+	input, err := e.renderTaskDefinition(e.App.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := diffTaskDefinitions(running, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(diff) == 0 {
+		pterm.Printfln("No changes to the running task definition %s:%d", *running.TaskDefinition.Family, *running.TaskDefinition.Revision)
+	} else {
+		pterm.Printfln("Task definition changes:")
+		printDiff(diff)
+	}
+
+	pterm.Println("Creating new task definition revision")
+
+	rtdo, err := e.Project.AWSClient.ECSClient.RegisterTaskDefinition(input)
+	if err != nil {
+		return nil, err
+	}
+
+	pterm.Printfln("Successfully created revision: %s:%d", *rtdo.TaskDefinition.Family, *rtdo.TaskDefinition.Revision)
+
+	return rtdo.TaskDefinition, nil
+}
+
+// nextTaskDefinition registers the revision to deploy: the rendered task_definition_file if set,
+// otherwise a copy of the base revision with the new app image
+func (e *Manager) nextTaskDefinition(running, base *ecs.DescribeTaskDefinitionOutput) (*ecs.TaskDefinition, error) {
+	if len(e.App.TaskDefinitionFile) != 0 {
+		return e.registerTaskDefinitionFile(running)
+	}
+
+	td := *base.TaskDefinition
+
+	return e.registerTaskDefinition(&td, base.Tags)
+}
+
+// registerInput returns the input registering a copy of the task definition with the tags.
+// Every field of the task definition that can be registered is copied, so that
+// the new revision differs from the source only in what ize changes explicitly.
+func registerInput(td *ecs.TaskDefinition, tags []*ecs.Tag) *ecs.RegisterTaskDefinitionInput {
+	input := &ecs.RegisterTaskDefinitionInput{}
+
+	src := reflect.ValueOf(td).Elem()
+	dst := reflect.ValueOf(input).Elem()
+
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		v := src.FieldByName(field.Name)
+		if v.IsValid() && v.Type() == field.Type {
+			dst.Field(i).Set(v)
+		}
+	}
+
+	if len(tags) != 0 {
+		input.Tags = tags
+	}
+
+	return input
+}
+
+// diffTaskDefinitions returns a unified diff between the running task definition and the one
+// to be registered. Empty values are omitted from both sides, so the diff is empty if nothing changes.
+func diffTaskDefinitions(running *ecs.DescribeTaskDefinitionOutput, next *ecs.RegisterTaskDefinitionInput) (string, error) {
+	before, err := normalizedJSON(registerInput(running.TaskDefinition, running.Tags))
+	if err != nil {
+		return "", err
+	}
+
+	after, err := normalizedJSON(next)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(before),
+		B:        difflib.SplitLines(after),
+		FromFile: fmt.Sprintf("%s:%d", aws.StringValue(running.TaskDefinition.Family), aws.Int64Value(running.TaskDefinition.Revision)),
+		ToFile:   aws.StringValue(next.Family),
+		Context:  3,
+	})
+}
+
+// normalizedJSON returns indented JSON of v with sorted keys and without empty values
+func normalizedJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("can't encode task definition: %w", err)
+	}
+
+	var m interface{}
+	if err = json.Unmarshal(b, &m); err != nil {
+		return "", fmt.Errorf("can't decode task definition: %w", err)
+	}
+
+	b, err = json.MarshalIndent(prune(m), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("can't encode task definition: %w", err)
+	}
+
+	return string(b) + "\n", nil
+}
+
+func prune(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			val = prune(val)
+			if val == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = val
+		}
+		if len(v) == 0 {
+			return nil
+		}
+	case []interface{}:
+		var items []interface{}
+		for _, val := range v {
+			if val = prune(val); val != nil {
+				items = append(items, val)
+			}
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return items
+	}
+
+	return v
+}
+
+func printDiff(diff string) {
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			pterm.Println(pterm.Bold.Sprint(line))
+		case strings.HasPrefix(line, "+"):
+			pterm.Println(pterm.FgGreen.Sprint(line))
+		case strings.HasPrefix(line, "-"):
+			pterm.Println(pterm.FgRed.Sprint(line))
+		default:
+			pterm.Println(line)
+		}
+	}
+}
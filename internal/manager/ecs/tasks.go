@@ -0,0 +1,140 @@
+package ecs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+// RunningTask is a running task of an ECS service
+type RunningTask struct {
+	Arn              string
+	ID               string
+	AvailabilityZone string
+	StartedAt        time.Time
+	Health           string
+	// Containers are names of the task containers
+	Containers []string
+}
+
+// String returns a one line description of the task for task selection
+func (t RunningTask) String() string {
+	started := "pending"
+	if !t.StartedAt.IsZero() {
+		started = t.StartedAt.Local().Format(time.RFC822)
+	}
+
+	return fmt.Sprintf("%s  %s  %s  %s  [%s]", t.ID, t.AvailabilityZone, started, strings.ToLower(t.Health), strings.Join(t.Containers, ", "))
+}
+
+// HasContainer reports whether the task has the container
+func (t RunningTask) HasContainer(name string) bool {
+	for _, c := range t.Containers {
+		if c == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RunningTasks returns running tasks of the service, the oldest first
+func RunningTasks(svc ecsiface.ECSAPI, cluster, service string) ([]RunningTask, error) {
+	lto, err := svc.ListTasks(&ecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		DesiredStatus: aws.String(ecs.DesiredStatusRunning),
+		ServiceName:   aws.String(service),
+	})
+	if err != nil {
+		return nil, taskError(err, cluster)
+	}
+
+	if len(lto.TaskArns) == 0 {
+		return nil, nil
+	}
+
+	dto, err := svc.DescribeTasks(&ecs.DescribeTasksInput{
+		Cluster: aws.String(cluster),
+		Tasks:   lto.TaskArns,
+	})
+	if err != nil {
+		return nil, taskError(err, cluster)
+	}
+
+	var tasks []RunningTask
+
+	for _, t := range dto.Tasks {
+		arn := aws.StringValue(t.TaskArn)
+
+		task := RunningTask{
+			Arn:              arn,
+			ID:               arn[strings.LastIndex(arn, "/")+1:],
+			AvailabilityZone: aws.StringValue(t.AvailabilityZone),
+			StartedAt:        aws.TimeValue(t.StartedAt),
+			Health:           aws.StringValue(t.HealthStatus),
+		}
+
+		for _, c := range t.Containers {
+			task.Containers = append(task.Containers, aws.StringValue(c.Name))
+		}
+
+		sort.Strings(task.Containers)
+
+		tasks = append(tasks, task)
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].StartedAt.Equal(tasks[j].StartedAt) {
+			return tasks[i].ID < tasks[j].ID
+		}
+		return tasks[i].StartedAt.Before(tasks[j].StartedAt)
+	})
+
+	return tasks, nil
+}
+
+func taskError(err error, cluster string) error {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ecs.ErrCodeClusterNotFoundException {
+		return fmt.Errorf("ECS cluster %s not found", cluster)
+	}
+
+	return fmt.Errorf("can't get running tasks: %w", err)
+}
+
+// SSMTarget returns the SSM session target of the task container: ecs:<cluster>_<task id>_<container runtime id>
+func SSMTarget(svc ecsiface.ECSAPI, cluster, task, container string) (string, error) {
+	dto, err := svc.DescribeTasks(&ecs.DescribeTasksInput{
+		Cluster: aws.String(cluster),
+		Tasks:   aws.StringSlice([]string{task}),
+	})
+	if err != nil {
+		return "", taskError(err, cluster)
+	}
+
+	if len(dto.Tasks) == 0 {
+		return "", fmt.Errorf("task %s not found", task)
+	}
+
+	arn := aws.StringValue(dto.Tasks[0].TaskArn)
+	id := arn[strings.LastIndex(arn, "/")+1:]
+
+	for _, c := range dto.Tasks[0].Containers {
+		if aws.StringValue(c.Name) != container {
+			continue
+		}
+
+		if len(aws.StringValue(c.RuntimeId)) == 0 {
+			return "", fmt.Errorf("container %s of task %s is not running", container, id)
+		}
+
+		return fmt.Sprintf("ecs:%s_%s_%s", cluster, id, aws.StringValue(c.RuntimeId)), nil
+	}
+
+	return "", fmt.Errorf("task %s has no container %s", id, container)
+}
@@ -0,0 +1,242 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	dockerutils "github.com/hazelops/ize/internal/docker/utils"
+)
+
+const (
+	manifestsMount  = "/manifests"
+	kubeconfigMount = "/.kube/config"
+)
+
+func (e *Manager) deployWithDocker(w io.Writer) error {
+	dir, err := os.MkdirTemp("", fmt.Sprintf("ize-k8s-%s", e.App.Name))
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	data := e.manifestData()
+
+	var script []string
+
+	if len(e.App.Chart) != 0 {
+		cmd := []string{"helm", "upgrade", "--install", e.App.Name, e.App.Chart,
+			"--namespace", e.App.KubeNamespace,
+			"--set", fmt.Sprintf("image.repository=%s", data.Repository),
+			"--set", fmt.Sprintf("image.tag=%s", data.Tag),
+			"--wait",
+			"--timeout", fmt.Sprintf("%ds", e.App.Timeout),
+		}
+
+		if len(e.App.ValuesFile) != 0 {
+			values, err := renderManifests(e.App.ValuesFile, dir, data)
+			if err != nil {
+				return fmt.Errorf("can't render helm values: %w", err)
+			}
+
+			for _, v := range values {
+				cmd = append(cmd, "--values", filepath.Join(manifestsMount, filepath.Base(v)))
+			}
+		}
+
+		script = append(script, strings.Join(append(cmd, e.helmContextFlags()...), " "))
+	} else {
+		_, err = renderManifests(e.App.Manifests, dir, data)
+		if err != nil {
+			return fmt.Errorf("can't render manifests: %w", err)
+		}
+
+		kubectl := strings.Join(append([]string{"kubectl"}, e.kubectlContextFlags()...), " ")
+
+		script = append(script,
+			fmt.Sprintf("%s apply -f %s -o name | tee /tmp/applied", kubectl, manifestsMount),
+			fmt.Sprintf("for r in $(grep -E '^(deployment|statefulset|daemonset)\\.apps/' /tmp/applied); do %s rollout status $r --timeout=%ds || exit 1; done", kubectl, e.App.Timeout),
+		)
+	}
+
+	return e.runWithDocker(w, dir, strings.Join(script, " && "))
+}
+
+func (e *Manager) destroyWithDocker(w io.Writer) error {
+	dir, err := os.MkdirTemp("", fmt.Sprintf("ize-k8s-%s", e.App.Name))
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var script string
+
+	if len(e.App.Chart) != 0 {
+		script = strings.Join(append([]string{"helm", "uninstall", e.App.Name, "--namespace", e.App.KubeNamespace}, e.helmContextFlags()...), " ")
+	} else {
+		_, err = renderManifests(e.App.Manifests, dir, e.manifestData())
+		if err != nil {
+			return fmt.Errorf("can't render manifests: %w", err)
+		}
+
+		script = strings.Join(append(append([]string{"kubectl"}, e.kubectlContextFlags()...), "delete", "-f", manifestsMount, "--ignore-not-found"), " ")
+	}
+
+	return e.runWithDocker(w, dir, script)
+}
+
+// kubectlContextFlags are the same as kubectlFlags, but point to the kubeconfig mounted into the container
+func (e *Manager) kubectlContextFlags() []string {
+	flags := []string{"--namespace", e.App.KubeNamespace, "--kubeconfig", kubeconfigMount}
+
+	if len(e.App.KubeContext) != 0 {
+		flags = append(flags, "--context", e.App.KubeContext)
+	}
+
+	return flags
+}
+
+func (e *Manager) helmContextFlags() []string {
+	flags := []string{"--kubeconfig", kubeconfigMount}
+
+	if len(e.App.KubeContext) != 0 {
+		flags = append(flags, "--kube-context", e.App.KubeContext)
+	}
+
+	return flags
+}
+
+func (e *Manager) runWithDocker(w io.Writer, manifests string, script string) error {
+	cli, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		return err
+	}
+
+	imageRef, err := reference.ParseNormalizedNamed(k8sDeployImage)
+	if err != nil {
+		return fmt.Errorf("error parsing Docker image: %s", err)
+	}
+
+	imageList, err := cli.ImageList(context.Background(), types.ImageListOptions{
+		Filters: filters.NewArgs(filters.KeyValuePair{
+			Key:   "reference",
+			Value: reference.FamiliarString(imageRef),
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(imageList) == 0 {
+		resp, err := cli.ImagePull(context.Background(), reference.FamiliarString(imageRef), types.ImagePullOptions{})
+		if err != nil {
+			return err
+		}
+		defer resp.Close()
+
+		err = jsonmessage.DisplayJSONMessagesStream(resp, os.Stderr, os.Stderr.Fd(), true, nil)
+		if err != nil {
+			return fmt.Errorf("unable to stream pull logs to the terminal: %s", err)
+		}
+	}
+
+	kubeconfig := e.App.Kubeconfig
+	if len(kubeconfig) == 0 {
+		kubeconfig = os.Getenv("KUBECONFIG")
+	}
+	if len(kubeconfig) == 0 {
+		kubeconfig = filepath.Join(e.Project.Home, ".kube", "config")
+	}
+
+	env := []string{"HOME=/"}
+	if len(e.App.AwsProfile) != 0 {
+		env = append(env, fmt.Sprintf("AWS_PROFILE=%s", e.App.AwsProfile))
+	}
+
+	cfg := container.Config{
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          true,
+		Image:        k8sDeployImage,
+		User:         fmt.Sprintf("%v:%v", os.Getuid(), os.Getgid()),
+		WorkingDir:   e.App.Path,
+		Env:          env,
+		Entrypoint:   []string{"sh", "-c"},
+		Cmd:          []string{script},
+	}
+
+	hostconfig := container.HostConfig{
+		AutoRemove: true,
+		Mounts: []mount.Mount{
+			{
+				Type:   mount.TypeBind,
+				Source: fmt.Sprintf("%v/.aws", e.Project.Home),
+				Target: "/.aws",
+			},
+			{
+				Type:   mount.TypeBind,
+				Source: kubeconfig,
+				Target: kubeconfigMount,
+			},
+			{
+				Type:   mount.TypeBind,
+				Source: manifests,
+				Target: manifestsMount,
+			},
+			{
+				Type:   mount.TypeBind,
+				Source: e.Project.RootDir,
+				Target: e.Project.RootDir,
+			},
+		},
+	}
+
+	cr, err := cli.ContainerCreate(context.Background(), &cfg, &hostconfig, &network.NetworkingConfig{}, nil, e.App.Name)
+	if err != nil {
+		return err
+	}
+
+	err = cli.ContainerStart(context.Background(), cr.ID, types.ContainerStartOptions{})
+	if err != nil {
+		return err
+	}
+
+	dockerutils.SetupSignalHandlers(cli, cr.ID)
+
+	out, err := cli.ContainerLogs(context.Background(), cr.ID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Timestamps: false,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer out.Close()
+
+	io.Copy(w, out)
+
+	wait, errC := cli.ContainerWait(context.Background(), cr.ID, container.WaitConditionRemoved)
+
+	select {
+	case status := <-wait:
+		if status.StatusCode == 0 {
+			return nil
+		}
+		return fmt.Errorf("container exit status code %d", status.StatusCode)
+	case err := <-errC:
+		return err
+	}
+}
@@ -0,0 +1,39 @@
+package k8s
+
+import (
+	"text/template"
+
+	"github.com/hazelops/ize/internal/config"
+)
+
+func (e *Manager) Explain() error {
+	e.prepare()
+	return e.Project.Generate(deployK8sAppTmpl, template.FuncMap{
+		"app": func() config.K8s {
+			return *e.App
+		},
+		"image": e.image,
+	})
+}
+
+var deployK8sAppTmpl = `
+# Authenticate the Docker CLI to registry
+aws ecr get-login-password --region {{.AwsRegion}} | docker login --username AWS --password-stdin {{.DockerRegistry}}
+
+# Push an image to Amazon ECR
+docker push {{.DockerRegistry}}/{{.Namespace}}-{{app.Name}}:{{.Tag}} && \
+docker push {{.DockerRegistry}}/{{.Namespace}}-{{app.Name}}:{{.Env}}-latest
+{{if app.Chart}}
+# Install or upgrade the helm release
+helm upgrade --install {{app.Name}} {{app.Chart}} \
+    --namespace {{app.KubeNamespace}} {{if app.ValuesFile}}\
+    --values {{app.ValuesFile}} {{end}}\
+    --set image.tag={{.Tag}} \
+    --wait --timeout {{app.Timeout}}s
+{{else}}
+# Apply manifests (image references should point to {{image}})
+kubectl apply --namespace {{app.KubeNamespace}} -f {{app.Manifests}}
+
+# Wait for the rollout
+kubectl rollout status --namespace {{app.KubeNamespace}} deployment/{{app.Name}} --timeout={{app.Timeout}}s
+{{end}}`
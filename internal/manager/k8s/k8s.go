@@ -12,7 +12,6 @@ import (
 
 	"github.com/hazelops/ize/internal/aws/utils"
 	"github.com/hazelops/ize/internal/config"
-	"github.com/hazelops/ize/pkg/templates"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecr"
@@ -23,11 +22,13 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-const k8sDeployImage = "hazelops/k8s-deploy:latest"
+const k8sDeployImage = "alpine/k8s:1.24.0"
 
 type Manager struct {
 	Project *config.Project
 	App     *config.K8s
+
+	kube *kubeClient
 }
 
 func (e *Manager) prepare() {
@@ -46,8 +47,20 @@ func (e *Manager) prepare() {
 		}
 	}
 
-	if len(e.App.Cluster) == 0 {
-		e.App.Cluster = fmt.Sprintf("%s-%s", e.Project.Env, e.Project.Namespace)
+	if len(e.App.Chart) == 0 && len(e.App.Manifests) == 0 {
+		e.App.Manifests = filepath.Join(e.App.Path, "k8s")
+	}
+
+	if len(e.App.Manifests) != 0 && !filepath.IsAbs(e.App.Manifests) {
+		e.App.Manifests = filepath.Join(e.Project.RootDir, e.App.Manifests)
+	}
+
+	if len(e.App.ValuesFile) != 0 && !filepath.IsAbs(e.App.ValuesFile) {
+		e.App.ValuesFile = filepath.Join(e.Project.RootDir, e.App.ValuesFile)
+	}
+
+	if len(e.App.KubeNamespace) == 0 {
+		e.App.KubeNamespace = "default"
 	}
 
 	if len(e.App.DockerRegistry) == 0 {
@@ -59,7 +72,7 @@ func (e *Manager) prepare() {
 	}
 }
 
-// Deploy deploys app container to k8s via k8s deploy
+// Deploy renders app manifests (or helm values) with the new image and applies them to the k8s cluster
 func (e *Manager) Deploy(ui terminal.UI) error {
 	e.prepare()
 
@@ -85,28 +98,11 @@ func (e *Manager) Deploy(ui terminal.UI) error {
 		return nil
 	}
 
-	if e.App.Unsafe && e.Project.PreferRuntime == "native" {
-		pterm.Warning.Println(templates.Dedent(`
-			deployment will be accelerated (unsafe):
-			- Health Check Interval: 5s
-			- Health Check Timeout: 2s
-			- Healthy Threshold Count: 2
-			- Unhealthy Threshold Count: 2`))
-	}
-
 	s := sg.Add("%s: deploying app container...", e.App.Name)
 	defer func() { s.Abort(); time.Sleep(50 * time.Millisecond) }()
 
-	if e.App.Image == "" {
-		e.App.Image = fmt.Sprintf("%s/%s:%s",
-			e.App.DockerRegistry,
-			fmt.Sprintf("%s-%s", e.Project.Namespace, e.App.Name),
-			fmt.Sprintf("%s-%s", e.Project.Env, "latest"))
-	}
-
 	if e.Project.PreferRuntime == "native" {
 		err := e.deployLocal(s.TermOutput())
-		pterm.SetDefaultOutput(os.Stdout)
 		if err != nil {
 			return fmt.Errorf("unable to deploy app: %w", err)
 		}
@@ -124,38 +120,18 @@ func (e *Manager) Deploy(ui terminal.UI) error {
 	return nil
 }
 
+// Redeploy restarts workloads of the app through the Kubernetes API, so their pods are replaced with the deployed image
 func (e *Manager) Redeploy(ui terminal.UI) error {
 	e.prepare()
 
 	sg := ui.StepGroup()
 	defer sg.Wait()
 
-	if len(e.App.AwsRegion) != 0 && len(e.App.AwsProfile) != 0 {
-		sess, err := utils.GetSession(&utils.SessionConfig{
-			Region:  e.App.AwsRegion,
-			Profile: e.App.AwsProfile,
-		})
-		if err != nil {
-			return fmt.Errorf("can't get session: %w", err)
-		}
-
-		e.Project.SettingAWSClient(sess)
-	}
-
-	s := sg.Add("%s: redeploying app container...", e.App.Name)
+	s := sg.Add("%s: redeploying app...", e.App.Name)
 	defer func() { s.Abort(); time.Sleep(50 * time.Millisecond) }()
 
-	if e.Project.PreferRuntime == "native" {
-		err := e.redeployLocal(s.TermOutput())
-		pterm.SetDefaultOutput(os.Stdout)
-		if err != nil {
-			return fmt.Errorf("unable to redeploy app: %w", err)
-		}
-	} else {
-		err := e.redeployWithDocker(s.TermOutput())
-		if err != nil {
-			return fmt.Errorf("unable to redeploy app: %w", err)
-		}
+	if err := e.restartLocal(s.TermOutput()); err != nil {
+		return fmt.Errorf("unable to redeploy app: %w", err)
 	}
 
 	s.Done()
@@ -315,41 +291,22 @@ func (e *Manager) Build(ui terminal.UI) error {
 	return nil
 }
 
-func definitionsToBulletItems(definitions *k8s.ListTaskDefinitionsOutput) []pterm.BulletListItem {
-	var items []pterm.BulletListItem
-	for _, arn := range definitions.TaskDefinitionArns {
-		items = append(items, pterm.BulletListItem{Level: 0, Text: *arn})
-	}
-
-	return items
-}
-
 func (e *Manager) Destroy(ui terminal.UI, autoApprove bool) error {
+	e.prepare()
+
 	sg := ui.StepGroup()
 	defer sg.Wait()
 
-	s := sg.Add("%s: destroying task defintions...", e.App.Name)
+	s := sg.Add("%s: destroying k8s resources...", e.App.Name)
 	defer func() { s.Abort(); time.Sleep(time.Millisecond * 200) }()
 
-	name := fmt.Sprintf("%s-%s", e.Project.Env, e.App.Name)
-
-	svc := e.Project.AWSClient.k8sClient
-
-	definitions, err := svc.ListTaskDefinitions(&k8s.ListTaskDefinitionsInput{
-		FamilyPrefix: &name,
-		Sort:         aws.String(k8s.SortOrderDesc),
-	})
-	if err != nil {
-		return fmt.Errorf("can't get list task definitions of '%s': %v", name, err)
-	}
-
 	if !autoApprove {
 		pterm.SetDefaultOutput(s.TermOutput())
 
-		pterm.Printfln("this will destroy the following:")
-		pterm.DefaultBulletList.WithItems(definitionsToBulletItems(definitions)).Render()
-
-		isContinue, err := pterm.DefaultInteractiveConfirm.WithDefaultText("Continue?").Show()
+		isContinue, err := pterm.DefaultInteractiveConfirm.WithDefaultText(
+			fmt.Sprintf("This will destroy %s resources in %s namespace. Continue?", e.App.Name, e.App.KubeNamespace),
+		).Show()
+		pterm.SetDefaultOutput(os.Stdout)
 		if err != nil {
 			return err
 		}
@@ -359,12 +316,15 @@ func (e *Manager) Destroy(ui terminal.UI, autoApprove bool) error {
 		}
 	}
 
-	for _, tda := range definitions.TaskDefinitionArns {
-		_, err := e.Project.AWSClient.k8sClient.DeregisterTaskDefinition(&k8s.DeregisterTaskDefinitionInput{
-			TaskDefinition: tda,
-		})
+	if e.Project.PreferRuntime == "native" {
+		err := e.destroyLocal(s.TermOutput())
 		if err != nil {
-			return fmt.Errorf("can't deregister task definition '%s': %v", *tda, err)
+			return fmt.Errorf("unable to destroy app: %w", err)
+		}
+	} else {
+		err := e.destroyWithDocker(s.TermOutput())
+		if err != nil {
+			return fmt.Errorf("unable to destroy app: %w", err)
 		}
 	}
 
@@ -374,3 +334,15 @@ func (e *Manager) Destroy(ui terminal.UI, autoApprove bool) error {
 
 	return nil
 }
+
+// image returns the image reference that is going to be deployed
+func (e *Manager) image() string {
+	if len(e.App.Image) != 0 {
+		return e.App.Image
+	}
+
+	return fmt.Sprintf("%s/%s:%s",
+		e.App.DockerRegistry,
+		fmt.Sprintf("%s-%s", e.Project.Namespace, e.App.Name),
+		e.Project.Tag)
+}
@@ -0,0 +1,308 @@
+package k8s
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hazelops/ize/internal/config"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func Test_renderManifests(t *testing.T) {
+	data := manifestData{
+		App:           "goblin",
+		Image:         "test.dkr.ecr.us-east-1.amazonaws.com/testnut-goblin:abc123",
+		Repository:    "test.dkr.ecr.us-east-1.amazonaws.com/testnut-goblin",
+		Tag:           "abc123",
+		Env:           "testnut",
+		Namespace:     "nutcorp",
+		KubeNamespace: "default",
+	}
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "success",
+			files: map[string]string{
+				"deployment.yaml": "image: {{.Image}}\nenv: {{.Env}}",
+				"service.yml":     "name: {{.App}}",
+				"README.md":       "{{.Unknown}}",
+			},
+			want: map[string]string{
+				"deployment.yaml": "image: test.dkr.ecr.us-east-1.amazonaws.com/testnut-goblin:abc123\nenv: testnut",
+				"service.yml":     "name: goblin",
+			},
+		},
+		{
+			name: "unknown key",
+			files: map[string]string{
+				"deployment.yaml": "image: {{.Unknown}}",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := t.TempDir()
+			dst := t.TempDir()
+
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := renderManifests(src, dst, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderManifests() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("renderManifests() rendered %d files, want %d", len(got), len(tt.want))
+			}
+
+			for name, content := range tt.want {
+				b, err := os.ReadFile(filepath.Join(dst, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if string(b) != content {
+					t.Errorf("renderManifests() %s = %q, want %q", name, string(b), content)
+				}
+			}
+		})
+	}
+}
+
+func Test_decodeManifests(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"app.yaml":  "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: goblin\n---\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: goblin\n",
+		"list.json": `{"apiVersion": "v1", "kind": "List", "items": [{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "goblin"}}]}`,
+	}
+
+	var paths []string
+	for _, name := range []string{"app.yaml", "list.json"} {
+		paths = append(paths, filepath.Join(dir, name))
+		if err := os.WriteFile(paths[len(paths)-1], []byte(files[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	objs, err := decodeManifests(paths)
+	if err != nil {
+		t.Fatalf("decodeManifests() error = %v", err)
+	}
+
+	var got []string
+	for _, obj := range objs {
+		got = append(got, resourceName(obj))
+	}
+
+	want := []string{"deployment/goblin", "service/goblin", "configmap/goblin"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeManifests() = %v, want %v", got, want)
+	}
+
+	if !isWorkload(objs[0]) || isWorkload(objs[1]) {
+		t.Errorf("isWorkload() of deployment and service = %v, %v, want true, false", isWorkload(objs[0]), isWorkload(objs[1]))
+	}
+
+	unnamed := filepath.Join(dir, "unnamed.yaml")
+	if err = os.WriteFile(unnamed, []byte("apiVersion: v1\nkind: Service\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = decodeManifests([]string{unnamed}); err == nil {
+		t.Errorf("decodeManifests() error = nil, want error for resource without name")
+	}
+}
+
+func Test_rolloutStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      map[string]interface{}
+		wantDone bool
+		wantErr  bool
+	}{
+		{
+			name: "deployment is not observed",
+			obj: map[string]interface{}{"kind": "Deployment", "metadata": map[string]interface{}{"generation": int64(2)},
+				"status": map[string]interface{}{"observedGeneration": int64(1)}},
+		},
+		{
+			name: "deployment with old replicas",
+			obj: map[string]interface{}{"kind": "Deployment", "spec": map[string]interface{}{"replicas": int64(2)},
+				"status": map[string]interface{}{"replicas": int64(3), "updatedReplicas": int64(2), "availableReplicas": int64(2)}},
+		},
+		{
+			name: "deployment is rolled out",
+			obj: map[string]interface{}{"kind": "Deployment", "spec": map[string]interface{}{"replicas": int64(2)},
+				"status": map[string]interface{}{"replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(2)}},
+			wantDone: true,
+		},
+		{
+			name: "deployment exceeded progress deadline",
+			obj: map[string]interface{}{"kind": "Deployment", "status": map[string]interface{}{"conditions": []interface{}{
+				map[string]interface{}{"type": "Progressing", "reason": "ProgressDeadlineExceeded"},
+			}}},
+			wantErr: true,
+		},
+		{
+			name: "statefulset with old revision",
+			obj: map[string]interface{}{"kind": "StatefulSet", "status": map[string]interface{}{"readyReplicas": int64(1),
+				"currentRevision": "goblin-1", "updateRevision": "goblin-2"}},
+		},
+		{
+			name: "statefulset is rolled out",
+			obj: map[string]interface{}{"kind": "StatefulSet", "status": map[string]interface{}{"readyReplicas": int64(1),
+				"currentRevision": "goblin-2", "updateRevision": "goblin-2"}},
+			wantDone: true,
+		},
+		{
+			name: "daemonset with unavailable pods",
+			obj: map[string]interface{}{"kind": "DaemonSet", "status": map[string]interface{}{"desiredNumberScheduled": int64(3),
+				"updatedNumberScheduled": int64(3), "numberAvailable": int64(2)}},
+		},
+		{
+			name: "daemonset is rolled out",
+			obj: map[string]interface{}{"kind": "DaemonSet", "status": map[string]interface{}{"desiredNumberScheduled": int64(3),
+				"updatedNumberScheduled": int64(3), "numberAvailable": int64(3)}},
+			wantDone: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done, msg, err := rolloutStatus(&unstructured.Unstructured{Object: tt.obj})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rolloutStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if done != tt.wantDone {
+				t.Errorf("rolloutStatus() done = %v, want %v", done, tt.wantDone)
+			}
+
+			if !done && !tt.wantErr && len(msg) == 0 {
+				t.Errorf("rolloutStatus() returned no message while rollout isn't done")
+			}
+		})
+	}
+}
+
+func TestManager_restartLocal(t *testing.T) {
+	defer func(interval time.Duration) { rolloutPollInterval = interval }(rolloutPollInterval)
+	rolloutPollInterval = time.Millisecond
+
+	deployment := func(name string, release string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("apps/v1")
+		obj.SetKind("Deployment")
+		obj.SetName(name)
+		obj.SetNamespace("default")
+		obj.SetAnnotations(map[string]string{
+			helmReleaseNameAnnotation:      release,
+			helmReleaseNamespaceAnnotation: "default",
+		})
+		_ = unstructured.SetNestedField(obj.Object, int64(1), "spec", "replicas")
+		_ = unstructured.SetNestedField(obj.Object, map[string]interface{}{
+			"replicas": int64(1), "updatedReplicas": int64(1), "availableReplicas": int64(1),
+		}, "status")
+		return obj
+	}
+
+	scheme := runtime.NewScheme()
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+		{Group: "apps", Version: "v1", Resource: "deployments"}:  "DeploymentList",
+		{Group: "apps", Version: "v1", Resource: "statefulsets"}: "StatefulSetList",
+		{Group: "apps", Version: "v1", Resource: "daemonsets"}:   "DaemonSetList",
+	}, deployment("goblin", "goblin"), deployment("squibby", "squibby"))
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	e := &Manager{
+		Project: &config.Project{RootDir: t.TempDir()},
+		App:     &config.K8s{Name: "goblin", Chart: "charts/goblin", KubeNamespace: "default", Timeout: 5},
+		kube:    &kubeClient{dynamic: dyn, mapper: mapper, namespace: "default"},
+	}
+
+	if err := e.restartLocal(io.Discard); err != nil {
+		t.Fatalf("restartLocal() error = %v", err)
+	}
+
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	for name, want := range map[string]bool{"goblin": true, "squibby": false} {
+		obj, err := dyn.Resource(gvr).Namespace("default").Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, restarted, _ := unstructured.NestedString(obj.Object, "spec", "template", "metadata", "annotations", restartedAtAnnotation)
+		if restarted != want {
+			t.Errorf("deployment %s restarted = %v, want %v", name, restarted, want)
+		}
+	}
+
+	e.App.Name = "unknown"
+	if err := e.restartLocal(io.Discard); err == nil {
+		t.Errorf("restartLocal() error = nil, want error when release has no workloads")
+	}
+}
+
+func TestManager_manifestData(t *testing.T) {
+	tests := []struct {
+		name           string
+		app            *config.K8s
+		wantImage      string
+		wantRepository string
+		wantTag        string
+	}{
+		{
+			name:           "default image",
+			app:            &config.K8s{Name: "goblin", DockerRegistry: "localhost:5000"},
+			wantImage:      "localhost:5000/nutcorp-goblin:abc123",
+			wantRepository: "localhost:5000/nutcorp-goblin",
+			wantTag:        "abc123",
+		},
+		{
+			name:           "custom image",
+			app:            &config.K8s{Name: "goblin", Image: "nginx:1.23"},
+			wantImage:      "nginx:1.23",
+			wantRepository: "nginx",
+			wantTag:        "1.23",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Manager{
+				Project: &config.Project{Namespace: "nutcorp", Env: "testnut", Tag: "abc123"},
+				App:     tt.app,
+			}
+
+			got := e.manifestData()
+			if got.Image != tt.wantImage || got.Repository != tt.wantRepository || got.Tag != tt.wantTag {
+				t.Errorf("manifestData() = %+v, want image %s, repository %s, tag %s", got, tt.wantImage, tt.wantRepository, tt.wantTag)
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// fieldManager owns the fields of resources applied by ize
+	fieldManager = "ize"
+
+	// restartedAtAnnotation is the pod template annotation which is changed to restart workloads, the same one kubectl rollout restart uses
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+)
+
+// rolloutPollInterval is how often the status of workloads is checked while waiting for the rollout
+var rolloutPollInterval = 2 * time.Second
+
+// workloads are resources which support the rollout status
+var workloads = []schema.GroupVersionResource{
+	{Group: "apps", Version: "v1", Resource: "deployments"},
+	{Group: "apps", Version: "v1", Resource: "statefulsets"},
+	{Group: "apps", Version: "v1", Resource: "daemonsets"},
+}
+
+// kubeClient applies resources of the app through the Kubernetes API
+type kubeClient struct {
+	dynamic   dynamic.Interface
+	mapper    meta.RESTMapper
+	namespace string
+}
+
+func (e *Manager) kubeClient() (*kubeClient, error) {
+	if e.kube != nil {
+		return e.kube, nil
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = e.App.Kubeconfig
+
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{
+		CurrentContext: e.App.KubeContext,
+	}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("can't load kubeconfig: %w", err)
+	}
+
+	// credentials of EKS clusters are issued by aws cli, it has to use the profile of the app
+	if cfg.ExecProvider != nil && len(e.App.AwsProfile) != 0 {
+		cfg.ExecProvider.Env = append(cfg.ExecProvider.Env, api.ExecEnvVar{Name: "AWS_PROFILE", Value: e.App.AwsProfile})
+	}
+
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("can't create discovery client: %w", err)
+	}
+
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("can't create kubernetes client: %w", err)
+	}
+
+	e.kube = &kubeClient{
+		dynamic:   dyn,
+		mapper:    restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc)),
+		namespace: e.App.KubeNamespace,
+	}
+
+	return e.kube, nil
+}
+
+// resource returns the client of the resource kind, namespaced resources without a namespace go to the app namespace
+func (k *kubeClient) resource(obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvk := obj.GroupVersionKind()
+
+	mapping, err := k.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("can't get resource of %s: %w", gvk, err)
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return k.dynamic.Resource(mapping.Resource), nil
+	}
+
+	if len(obj.GetNamespace()) == 0 {
+		obj.SetNamespace(k.namespace)
+	}
+
+	return k.dynamic.Resource(mapping.Resource).Namespace(obj.GetNamespace()), nil
+}
+
+// apply creates or updates the resource with server-side apply
+func (k *kubeClient) apply(ctx context.Context, obj *unstructured.Unstructured) error {
+	ri, err := k.resource(obj)
+	if err != nil {
+		return err
+	}
+
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	force := true
+
+	_, err = ri.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        &force,
+	})
+	if err != nil {
+		return fmt.Errorf("can't apply %s: %w", resourceName(obj), err)
+	}
+
+	return nil
+}
+
+// delete deletes the resource, missing resources are ignored
+func (k *kubeClient) delete(ctx context.Context, obj *unstructured.Unstructured) error {
+	ri, err := k.resource(obj)
+	if err != nil {
+		return err
+	}
+
+	policy := metav1.DeletePropagationBackground
+
+	err = ri.Delete(ctx, obj.GetName(), metav1.DeleteOptions{PropagationPolicy: &policy})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("can't delete %s: %w", resourceName(obj), err)
+	}
+
+	return nil
+}
+
+// restart changes the pod template of the workload, so its pods are replaced
+func (k *kubeClient) restart(ctx context.Context, obj *unstructured.Unstructured) error {
+	ri, err := k.resource(obj)
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = ri.Patch(ctx, obj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager})
+	if err != nil {
+		return fmt.Errorf("can't restart %s: %w", resourceName(obj), err)
+	}
+
+	return nil
+}
+
+// releaseWorkloads returns workloads installed by the helm release
+func (k *kubeClient) releaseWorkloads(ctx context.Context, release string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+
+	for _, gvr := range workloads {
+		list, err := k.dynamic.Resource(gvr).Namespace(k.namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("can't list %s: %w", gvr.Resource, err)
+		}
+
+		for i := range list.Items {
+			annotations := list.Items[i].GetAnnotations()
+			if annotations[helmReleaseNameAnnotation] == release && annotations[helmReleaseNamespaceAnnotation] == k.namespace {
+				objs = append(objs, &list.Items[i])
+			}
+		}
+	}
+
+	return objs, nil
+}
+
+// waitForRollout waits until the rollout of the workload is complete
+func (k *kubeClient) waitForRollout(obj *unstructured.Unstructured, timeout time.Duration) error {
+	ri, err := k.resource(obj)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var last string
+
+	for {
+		current, err := ri.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("rollout of %s didn't complete in %s", resourceName(obj), timeout)
+			}
+
+			return fmt.Errorf("can't get %s: %w", resourceName(obj), err)
+		}
+
+		done, msg, err := rolloutStatus(current)
+		if err != nil {
+			return err
+		}
+
+		if done {
+			pterm.Printfln("%s successfully rolled out", resourceName(obj))
+			return nil
+		}
+
+		if msg != last {
+			pterm.Printfln("Waiting for %s rollout: %s", resourceName(obj), msg)
+			last = msg
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("rollout of %s didn't complete in %s: %s", resourceName(obj), timeout, msg)
+		case <-time.After(rolloutPollInterval):
+		}
+	}
+}
+
+// rolloutStatus reports whether the rollout of the workload is complete and what it waits for otherwise,
+// the same way kubectl rollout status does
+func rolloutStatus(obj *unstructured.Unstructured) (bool, string, error) {
+	status := func(fields ...string) int64 {
+		v, _, _ := unstructured.NestedInt64(obj.Object, append([]string{"status"}, fields...)...)
+		return v
+	}
+
+	if obj.GetGeneration() > status("observedGeneration") {
+		return false, "waiting for the spec update to be observed", nil
+	}
+
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+
+	updated := status("updatedReplicas")
+
+	switch obj.GetKind() {
+	case "Deployment":
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, c := range conditions {
+			condition, _ := c.(map[string]interface{})
+			if condition["type"] == "Progressing" && condition["reason"] == "ProgressDeadlineExceeded" {
+				return false, "", fmt.Errorf("deployment %s exceeded its progress deadline", obj.GetName())
+			}
+		}
+
+		switch {
+		case updated < replicas:
+			return false, fmt.Sprintf("%d out of %d new replicas have been updated", updated, replicas), nil
+		case status("replicas") > updated:
+			return false, fmt.Sprintf("%d old replicas are pending termination", status("replicas")-updated), nil
+		case status("availableReplicas") < updated:
+			return false, fmt.Sprintf("%d of %d updated replicas are available", status("availableReplicas"), updated), nil
+		}
+	case "StatefulSet":
+		strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type")
+		if strategy == "OnDelete" {
+			return true, "", nil
+		}
+
+		if ready := status("readyReplicas"); ready < replicas {
+			return false, fmt.Sprintf("%d of %d replicas are ready", ready, replicas), nil
+		}
+
+		partition, found, _ := unstructured.NestedInt64(obj.Object, "spec", "updateStrategy", "rollingUpdate", "partition")
+		if found && partition > 0 {
+			if updated < replicas-partition {
+				return false, fmt.Sprintf("%d out of %d new replicas have been updated", updated, replicas-partition), nil
+			}
+
+			return true, "", nil
+		}
+
+		current, _, _ := unstructured.NestedString(obj.Object, "status", "currentRevision")
+		revision, _, _ := unstructured.NestedString(obj.Object, "status", "updateRevision")
+		if current != revision {
+			return false, fmt.Sprintf("%d out of %d new replicas have been updated to revision %s", updated, replicas, revision), nil
+		}
+	case "DaemonSet":
+		strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type")
+		if strategy == "OnDelete" {
+			return true, "", nil
+		}
+
+		desired := status("desiredNumberScheduled")
+
+		if scheduled := status("updatedNumberScheduled"); scheduled < desired {
+			return false, fmt.Sprintf("%d out of %d new pods have been updated", scheduled, desired), nil
+		}
+
+		if available := status("numberAvailable"); available < desired {
+			return false, fmt.Sprintf("%d of %d updated pods are available", available, desired), nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// isWorkload reports whether the resource supports the rollout status
+func isWorkload(obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+
+	for _, w := range workloads {
+		if gvk.Group == w.Group && strings.ToLower(gvk.Kind)+"s" == w.Resource {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decodeManifests decodes resources of YAML or JSON manifest files, items of lists are returned as separate resources
+func decodeManifests(files []string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+
+	for _, f := range files {
+		file, err := os.Open(f)
+		if err != nil {
+			return nil, err
+		}
+
+		d := yaml.NewYAMLOrJSONDecoder(file, 4096)
+
+		for {
+			obj := &unstructured.Unstructured{}
+
+			err = d.Decode(&obj.Object)
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("can't decode %s: %w", f, err)
+			}
+
+			// empty documents
+			if len(obj.Object) == 0 {
+				continue
+			}
+
+			if len(obj.GetKind()) == 0 || len(obj.GetName()) == 0 && !obj.IsList() {
+				file.Close()
+				return nil, fmt.Errorf("can't decode %s: kind and name must be set for every resource", f)
+			}
+
+			if !obj.IsList() {
+				objs = append(objs, obj)
+				continue
+			}
+
+			err = obj.EachListItem(func(item runtime.Object) error {
+				objs = append(objs, item.(*unstructured.Unstructured))
+				return nil
+			})
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("can't decode %s: %w", f, err)
+			}
+		}
+
+		file.Close()
+	}
+
+	return objs, nil
+}
+
+// resourceName returns the name of the resource in the kind/name form
+func resourceName(obj *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s/%s", strings.ToLower(obj.GetKind()), obj.GetName())
+}
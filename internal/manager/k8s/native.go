@@ -0,0 +1,330 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pterm/pterm"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// manifestData is passed to the manifests and helm values templates
+type manifestData struct {
+	App           string
+	Image         string
+	Repository    string
+	Tag           string
+	Env           string
+	Namespace     string
+	KubeNamespace string
+}
+
+func (e *Manager) manifestData() manifestData {
+	image := e.image()
+
+	repository := image
+	tag := e.Project.Tag
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		repository = image[:i]
+		tag = image[i+1:]
+	}
+
+	return manifestData{
+		App:           e.App.Name,
+		Image:         image,
+		Repository:    repository,
+		Tag:           tag,
+		Env:           e.Project.Env,
+		Namespace:     e.Project.Namespace,
+		KubeNamespace: e.App.KubeNamespace,
+	}
+}
+
+func (e *Manager) deployLocal(w io.Writer) error {
+	pterm.SetDefaultOutput(w)
+	defer pterm.SetDefaultOutput(os.Stdout)
+
+	dir, err := os.MkdirTemp("", fmt.Sprintf("ize-k8s-%s", e.App.Name))
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	data := e.manifestData()
+
+	pterm.Printfln("Deploying image: %s", data.Image)
+
+	if len(e.App.Chart) != 0 {
+		args := []string{"upgrade", "--install", e.App.Name, e.App.Chart,
+			"--namespace", e.App.KubeNamespace,
+			"--set", fmt.Sprintf("image.repository=%s", data.Repository),
+			"--set", fmt.Sprintf("image.tag=%s", data.Tag),
+			"--wait",
+			"--timeout", fmt.Sprintf("%ds", e.App.Timeout),
+		}
+
+		if len(e.App.ValuesFile) != 0 {
+			values, err := renderManifests(e.App.ValuesFile, dir, data)
+			if err != nil {
+				return fmt.Errorf("can't render helm values: %w", err)
+			}
+
+			for _, v := range values {
+				args = append(args, "--values", v)
+			}
+		}
+
+		pterm.Printfln("Running helm upgrade for %s release", e.App.Name)
+
+		return e.run(w, nil, "helm", append(args, e.helmFlags()...)...)
+	}
+
+	objs, err := e.resources(dir, data)
+	if err != nil {
+		return err
+	}
+
+	kube, err := e.kubeClient()
+	if err != nil {
+		return err
+	}
+
+	pterm.Printfln("Applying %d resource(s) to %s namespace", len(objs), e.App.KubeNamespace)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(e.App.Timeout)*time.Second)
+	defer cancel()
+
+	for _, obj := range objs {
+		if err = kube.apply(ctx, obj); err != nil {
+			return err
+		}
+
+		pterm.Printfln("%s applied", resourceName(obj))
+	}
+
+	for _, obj := range objs {
+		if !isWorkload(obj) {
+			continue
+		}
+
+		if err = kube.waitForRollout(obj, time.Duration(e.App.Timeout)*time.Second); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *Manager) destroyLocal(w io.Writer) error {
+	pterm.SetDefaultOutput(w)
+	defer pterm.SetDefaultOutput(os.Stdout)
+
+	if len(e.App.Chart) != 0 {
+		return e.run(w, nil, "helm", append([]string{"uninstall", e.App.Name, "--namespace", e.App.KubeNamespace}, e.helmFlags()...)...)
+	}
+
+	dir, err := os.MkdirTemp("", fmt.Sprintf("ize-k8s-%s", e.App.Name))
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	objs, err := e.resources(dir, e.manifestData())
+	if err != nil {
+		return err
+	}
+
+	kube, err := e.kubeClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(e.App.Timeout)*time.Second)
+	defer cancel()
+
+	// dependents such as workloads go after the resources they use
+	for i := len(objs) - 1; i >= 0; i-- {
+		if err = kube.delete(ctx, objs[i]); err != nil {
+			return err
+		}
+
+		pterm.Printfln("%s deleted", resourceName(objs[i]))
+	}
+
+	return nil
+}
+
+// restartLocal restarts workloads of the app and waits for their rollout
+func (e *Manager) restartLocal(w io.Writer) error {
+	pterm.SetDefaultOutput(w)
+	defer pterm.SetDefaultOutput(os.Stdout)
+
+	kube, err := e.kubeClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(e.App.Timeout)*time.Second)
+	defer cancel()
+
+	var objs []*unstructured.Unstructured
+
+	if len(e.App.Chart) != 0 {
+		objs, err = kube.releaseWorkloads(ctx, e.App.Name)
+		if err != nil {
+			return err
+		}
+	} else {
+		dir, err := os.MkdirTemp("", fmt.Sprintf("ize-k8s-%s", e.App.Name))
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		objs, err = e.resources(dir, e.manifestData())
+		if err != nil {
+			return err
+		}
+	}
+
+	var restarted []*unstructured.Unstructured
+
+	for _, obj := range objs {
+		if !isWorkload(obj) {
+			continue
+		}
+
+		if err = kube.restart(ctx, obj); err != nil {
+			return err
+		}
+
+		pterm.Printfln("%s restarted", resourceName(obj))
+		restarted = append(restarted, obj)
+	}
+
+	if len(restarted) == 0 {
+		return fmt.Errorf("no deployments, statefulsets or daemonsets of %s found", e.App.Name)
+	}
+
+	for _, obj := range restarted {
+		if err = kube.waitForRollout(obj, time.Duration(e.App.Timeout)*time.Second); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resources renders manifests of the app into the dir and decodes resources from them
+func (e *Manager) resources(dir string, data manifestData) ([]*unstructured.Unstructured, error) {
+	manifests, err := renderManifests(e.App.Manifests, dir, data)
+	if err != nil {
+		return nil, fmt.Errorf("can't render manifests: %w", err)
+	}
+
+	objs, err := decodeManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("no manifests found in %s", e.App.Manifests)
+	}
+
+	return objs, nil
+}
+
+func (e *Manager) run(w io.Writer, env []string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	cmd.Dir = e.App.Path
+	cmd.Env = append(os.Environ(), env...)
+	if len(e.App.AwsProfile) != 0 {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("AWS_PROFILE=%s", e.App.AwsProfile))
+	}
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w", name, args[0], err)
+	}
+
+	return nil
+}
+
+func (e *Manager) helmFlags() []string {
+	var flags []string
+
+	if len(e.App.Kubeconfig) != 0 {
+		flags = append(flags, "--kubeconfig", e.App.Kubeconfig)
+	}
+
+	if len(e.App.KubeContext) != 0 {
+		flags = append(flags, "--kube-context", e.App.KubeContext)
+	}
+
+	return flags
+}
+
+// renderManifests renders a manifest file or every manifest of a directory into the dst directory
+// and returns the paths of rendered files
+func renderManifests(src string, dst string, data manifestData) ([]string, error) {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{src}
+	if fi.IsDir() {
+		files = nil
+
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			switch filepath.Ext(entry.Name()) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					files = append(files, filepath.Join(src, entry.Name()))
+				}
+			}
+		}
+	}
+
+	var rendered []string
+
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := template.New(filepath.Base(f)).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("can't parse %s: %w", f, err)
+		}
+
+		out, err := os.Create(filepath.Join(dst, filepath.Base(f)))
+		if err != nil {
+			return nil, err
+		}
+
+		err = t.Execute(out, data)
+		out.Close()
+		if err != nil {
+			return nil, fmt.Errorf("can't render %s: %w", f, err)
+		}
+
+		rendered = append(rendered, out.Name())
+	}
+
+	return rendered, nil
+}
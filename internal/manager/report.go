@@ -0,0 +1,88 @@
+package manager
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Result statuses
+const (
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+	ResultSkipped   = "skipped"
+)
+
+// Result is the outcome of processing a single app or stack
+type Result struct {
+	Name     string
+	Status   string
+	Duration time.Duration
+	Err      error
+}
+
+// Report collects results of one or more graph walks. It is safe for concurrent use
+type Report struct {
+	lock    sync.Mutex
+	results []Result
+}
+
+// Run applies the function and records its result with the time it took
+func (r *Report) Run(name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+
+	res := Result{
+		Name:     name,
+		Status:   ResultSucceeded,
+		Duration: time.Since(start),
+	}
+
+	if err != nil {
+		res.Status = ResultFailed
+		res.Err = err
+	}
+
+	r.add(res)
+
+	return err
+}
+
+// Skip records apps that were not processed
+func (r *Report) Skip(names ...string) {
+	sort.Strings(names)
+
+	for _, name := range names {
+		r.add(Result{Name: name, Status: ResultSkipped})
+	}
+}
+
+// Results returns recorded results in the order they were finished
+func (r *Report) Results() []Result {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]Result(nil), r.results...)
+}
+
+// Failed returns names of the failed apps
+func (r *Report) Failed() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var names []string
+	for _, res := range r.results {
+		if res.Status == ResultFailed {
+			names = append(names, res.Name)
+		}
+	}
+
+	return names
+}
+
+func (r *Report) add(res Result) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.results = append(r.results, res)
+}
@@ -17,6 +17,7 @@ type requirements struct {
 	ssmplugin  bool
 	structure  bool
 	nvm        bool
+	helm       bool
 }
 
 func CheckRequirements(options ...Option) error {
@@ -32,6 +33,13 @@ func CheckRequirements(options ...Option) error {
 		}
 	}
 
+	if r.helm {
+		err := checkHelm()
+		if err != nil {
+			return err
+		}
+	}
+
 	if r.structure {
 		if !isStructured() {
 			pterm.Warning.Println("is not an ize-structured directory. Please run ize init or cd into an ize-structured directory.")
@@ -89,6 +97,12 @@ func WithNVM() Option {
 	}
 }
 
+func WithHelm() Option {
+	return func(r *requirements) {
+		r.helm = true
+	}
+}
+
 func checkNVM() error {
 	if len(os.Getenv("NVM_DIR")) == 0 {
 		return errors.New("nvm is not installed (visit https://github.com/nvm-sh/nvm)")
@@ -97,6 +111,15 @@ func checkNVM() error {
 	return nil
 }
 
+func checkHelm() error {
+	exist, _ := CheckCommand("helm", []string{"version"})
+	if !exist {
+		return errors.New("helm is not installed (visit https://helm.sh/docs/intro/install/)")
+	}
+
+	return nil
+}
+
 func checkDocker() error {
 	exist, _ := CheckCommand("docker", []string{"info"})
 	if !exist {
@@ -11,6 +11,8 @@ var valid = map[string]interface{}{
 	"ecs": map[string]interface{}{
 		"goblin":  map[string]interface{}{"cluster": "testnut-nutcorp", "skip_deploy": true, "timeout": 600},
 		"squibby": map[string]interface{}{"timeout": 1200, "unsafe": true}},
+	"k8s": map[string]interface{}{
+		"gremlin": map[string]interface{}{"kube_namespace": "testnut", "manifests": "apps/gremlin/k8s", "timeout": 600}},
 	"env":            "testnut",
 	"env_dir":        "/home/testnut/example/.ize/env/testnut",
 	"home":           "/home/testnut",
@@ -22,6 +24,24 @@ var valid = map[string]interface{}{
 		"infra": map[string]interface{}{"aws_profile": "testnut", "root_domain_name": "examples.ize.sh", "version": "1.1.6"}},
 	"terraform_version": "1.2.6",
 	"tf_log":            "",
+	"tunnel": map[string]interface{}{
+		"ssh_private_key": "/home/testnut/.ssh/id_rsa",
+		"db":              map[string]interface{}{"bastion_instance_id": "i-xxxxxxxxxxxxxxxxx", "forward_host": []interface{}{"db.internal:5432:15432"}},
+		"cache":           map[string]interface{}{"forward_host": []interface{}{"cache.internal:6379"}}},
+}
+
+var invalidTunnelProfile = map[string]interface{}{
+	"aws_profile":    "testnut",
+	"aws_region":     "us-east-1",
+	"env":            "testnut",
+	"env_dir":        "/home/testnut/example/.ize/env/testnut",
+	"home":           "/home/testnut",
+	"ize_dir":        "/home/testnut/example/.ize",
+	"namespace":      "testnut",
+	"prefer_runtime": "native",
+	"root_dir":       "/home/testnut/ize/example",
+	"tunnel": map[string]interface{}{
+		"db": map[string]interface{}{"bastion": "i-xxxxxxxxxxxxxxxxx"}},
 }
 
 var invalidParameter = map[string]interface{}{
@@ -84,6 +104,7 @@ func TestValidate(t *testing.T) {
 		{name: "valid deprecated", args: args{config: validDeprecated}, wantErr: false},
 		{name: "invalid parameter", args: args{config: invalidParameter}, wantErr: true},
 		{name: "invalid type", args: args{config: invalidType}, wantErr: true},
+		{name: "invalid tunnel profile", args: args{config: invalidTunnelProfile}, wantErr: true},
 		{name: "empty", args: args{config: map[string]interface{}{}}, wantErr: true},
 	}
 	for _, tt := range tests {
@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is a difference of a single key between local and remote secrets
+type Change struct {
+	Key    string
+	Type   string
+	Local  string
+	Remote string
+}
+
+// Diff compares local values with the remote ones. Keys that exist only locally are added,
+// keys that exist only remotely are removed. Changes are sorted by key
+func Diff(local, remote map[string]string) []Change {
+	var changes []Change
+
+	for key, value := range local {
+		remoteValue, ok := remote[key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Key: key, Type: Added, Local: value})
+		case remoteValue != value:
+			changes = append(changes, Change{Key: key, Type: Changed, Local: value, Remote: remoteValue})
+		}
+	}
+
+	for key, value := range remote {
+		if _, ok := local[key]; !ok {
+			changes = append(changes, Change{Key: key, Type: Removed, Remote: value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes
+}
+
+// Mask hides the value so it can be printed. Nothing of the value is kept, not even its length
+func Mask(value string) string {
+	if len(value) == 0 {
+		return ""
+	}
+
+	return strings.Repeat("*", 8)
+}
+
+// IsNotFound reports whether the error means that nothing is stored under the path yet
+func IsNotFound(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		return true
+	}
+
+	return os.IsNotExist(err)
+}
@@ -0,0 +1,57 @@
+package secrets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		local  map[string]string
+		remote map[string]string
+		want   []Change
+	}{
+		{
+			name:   "equal",
+			local:  map[string]string{"API_KEY": "one"},
+			remote: map[string]string{"API_KEY": "one"},
+			want:   nil,
+		},
+		{
+			name:   "added, removed and changed",
+			local:  map[string]string{"API_KEY": "one", "DB_PASSWORD": "new", "TOKEN": "three"},
+			remote: map[string]string{"API_KEY": "one", "DB_PASSWORD": "old", "OLD_TOKEN": "four"},
+			want: []Change{
+				{Key: "DB_PASSWORD", Type: Changed, Local: "new", Remote: "old"},
+				{Key: "OLD_TOKEN", Type: Removed, Remote: "four"},
+				{Key: "TOKEN", Type: Added, Local: "three"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.local, tt.remote); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "short", want: "********"},
+		{value: "a-long-secret-value", want: "********"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := Mask(tt.value); got != tt.want {
+				t.Errorf("Mask() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
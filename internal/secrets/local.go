@@ -0,0 +1,189 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseEnv is the environment variable the local backend passphrase is read from
+const PassphraseEnv = "IZE_SECRETS_PASSPHRASE"
+
+// localFile is the format of the local backend file. Like in sops, only values are encrypted,
+// so keys stay readable in diffs. Every value is sealed with AES-256-GCM using its key
+// as additional data, the encryption key is derived from the passphrase with scrypt.
+type localFile struct {
+	Salt   string            `json:"salt"`
+	Values map[string]string `json:"values"`
+}
+
+// localBackend stores secrets in an encrypted file that can be committed along with the env directory
+type localBackend struct {
+	passphrase string
+}
+
+func (b *localBackend) Push(path string, values map[string]string, overwrite bool) error {
+	current, salt, err := b.read(path)
+	if os.IsNotExist(err) {
+		current = map[string]string{}
+		salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if !overwrite {
+		var existing []string
+		for key := range values {
+			if _, ok := current[key]; ok {
+				existing = append(existing, key)
+			}
+		}
+
+		if len(existing) != 0 {
+			sort.Strings(existing)
+			return fmt.Errorf("secret already exists (%s), you can use --force to overwrite it", strings.Join(existing, ", "))
+		}
+	}
+
+	for key, value := range values {
+		current[key] = value
+	}
+
+	return b.write(path, current, salt)
+}
+
+func (b *localBackend) Pull(path string) (map[string]string, error) {
+	values, _, err := b.read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func (b *localBackend) Remove(path string) error {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (b *localBackend) Delete(path string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	current, salt, err := b.read(path)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		delete(current, key)
+	}
+
+	return b.write(path, current, salt)
+}
+
+// write encrypts values with the key derived from the salt and stores them to the file
+func (b *localBackend) write(path string, values map[string]string, salt []byte) error {
+	aead, err := b.cipher(salt)
+	if err != nil {
+		return err
+	}
+
+	f := localFile{
+		Salt:   base64.StdEncoding.EncodeToString(salt),
+		Values: map[string]string{},
+	}
+
+	for key, value := range values {
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return err
+		}
+
+		f.Values[key] = base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(value), []byte(key)))
+	}
+
+	out, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0775); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, out, 0600)
+}
+
+// read decrypts the file and returns its values and salt
+func (b *localBackend) read(path string) (map[string]string, []byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var f localFile
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, nil, fmt.Errorf("can't parse %s: %w", path, err)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(f.Salt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't parse %s: invalid salt: %w", path, err)
+	}
+
+	aead, err := b.cipher(salt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := map[string]string{}
+
+	for key, value := range f.Values {
+		sealed, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return nil, nil, fmt.Errorf("can't parse %s: invalid value of %s", path, key)
+		}
+
+		plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(key))
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't decrypt %s of %s: wrong passphrase or the file is corrupted", key, path)
+		}
+
+		values[key] = string(plain)
+	}
+
+	return values, salt, nil
+}
+
+func (b *localBackend) cipher(salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(b.passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
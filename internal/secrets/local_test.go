@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLocalBackend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets", "squibby.enc.json")
+
+	b := &localBackend{passphrase: "test"}
+
+	err := b.Push(path, map[string]string{"API_KEY": "secret one", "DB_PASSWORD": "secret two"}, false)
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(content), "secret one") || !strings.Contains(string(content), "API_KEY") {
+		t.Errorf("values must be encrypted and keys kept readable: %s", content)
+	}
+
+	if err := b.Push(path, map[string]string{"API_KEY": "new"}, false); err == nil {
+		t.Errorf("Push() without overwrite must fail on existing keys")
+	}
+
+	if err := b.Push(path, map[string]string{"API_KEY": "new"}, true); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	got, err := b.Pull(path)
+	if err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+
+	want := map[string]string{"API_KEY": "new", "DB_PASSWORD": "secret two"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Pull() = %v, want %v", got, want)
+	}
+
+	if _, err := (&localBackend{passphrase: "wrong"}).Pull(path); err == nil {
+		t.Errorf("Pull() with a wrong passphrase must fail")
+	}
+
+	if err := b.Delete(path, []string{"DB_PASSWORD"}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	got, err = b.Pull(path)
+	if err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+
+	want = map[string]string{"API_KEY": "new"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Pull() after Delete() = %v, want %v", got, want)
+	}
+
+	if err := b.Remove(path); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Remove() must delete the file")
+	}
+}
@@ -0,0 +1,96 @@
+package secrets
+
+import (
+	"fmt"
+
+	"github.com/hazelops/ize/internal/config"
+)
+
+const (
+	SSM            = "ssm"
+	SecretsManager = "secrets-manager"
+	Local          = "local"
+)
+
+// Backend is a storage of app secrets. Secrets of an app are stored under a path
+// (a parameter path, a secret name or a file, depending on the backend)
+type Backend interface {
+	// Push stores values under the path. Existing values are only replaced if overwrite is set
+	Push(path string, values map[string]string, overwrite bool) error
+	// Pull returns all values stored under the path
+	Pull(path string) (map[string]string, error)
+	// Remove deletes all values stored under the path
+	Remove(path string) error
+	// Delete deletes only the keys stored under the path
+	Delete(path string, keys []string) error
+}
+
+// Options are backend specific settings
+type Options struct {
+	// App is the name of the app secrets belong to
+	App string
+	// IncludeStrings makes SSM backend pull plaintext String parameters too
+	IncludeStrings bool
+	// Passphrase is used by the local backend to encrypt values
+	Passphrase string
+}
+
+// New returns the backend of the kind
+func New(kind string, project *config.Project, opts Options) (Backend, error) {
+	switch kind {
+	case SSM:
+		return &ssmBackend{
+			client:         project.AWSClient.SSMClient,
+			app:            opts.App,
+			includeStrings: opts.IncludeStrings,
+		}, nil
+	case SecretsManager:
+		return &secretsManagerBackend{
+			client: project.AWSClient.SecretsManagerClient,
+			app:    opts.App,
+		}, nil
+	case Local:
+		if len(opts.Passphrase) == 0 {
+			return nil, fmt.Errorf("%s backend requires a passphrase (set %s)", Local, PassphraseEnv)
+		}
+
+		return &localBackend{
+			passphrase: opts.Passphrase,
+		}, nil
+	default:
+		return nil, fmt.Errorf("backend with type %s not found or not supported", kind)
+	}
+}
+
+// AppBackend returns the backend configured for the app in ize.toml or ssm if it isn't set
+func AppBackend(project *config.Project, app string) string {
+	var backend string
+
+	if a, ok := project.Ecs[app]; ok {
+		backend = a.SecretsBackend
+	}
+	if a, ok := project.K8s[app]; ok {
+		backend = a.SecretsBackend
+	}
+	if a, ok := project.Serverless[app]; ok {
+		backend = a.SecretsBackend
+	}
+
+	if len(backend) == 0 {
+		return SSM
+	}
+
+	return backend
+}
+
+// DefaultPath returns the path secrets of the app are stored under by default
+func DefaultPath(kind string, project *config.Project, app string) string {
+	switch kind {
+	case SecretsManager:
+		return fmt.Sprintf("%s/%s", project.Env, app)
+	case Local:
+		return fmt.Sprintf("%s/%s/%s.enc.json", project.EnvDir, "secrets", app)
+	default:
+		return fmt.Sprintf("/%s/%s", project.Env, app)
+	}
+}
@@ -0,0 +1,137 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+)
+
+// secretsManagerBackend stores all secrets of the app as a single JSON secret, so the secret
+// can be rotated and referenced from task definitions as a whole or by a JSON key
+type secretsManagerBackend struct {
+	client secretsmanageriface.SecretsManagerAPI
+	app    string
+}
+
+func (b *secretsManagerBackend) Push(path string, values map[string]string, overwrite bool) error {
+	current, err := b.Pull(path)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		secret, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+
+		_, err = b.client.CreateSecret(&secretsmanager.CreateSecretInput{
+			Name:         aws.String(path),
+			SecretString: aws.String(string(secret)),
+			Tags: []*secretsmanager.Tag{
+				{
+					Key:   aws.String("Application"),
+					Value: aws.String(b.app),
+				},
+			},
+		})
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !overwrite {
+		var existing []string
+		for key := range values {
+			if _, ok := current[key]; ok {
+				existing = append(existing, key)
+			}
+		}
+
+		if len(existing) != 0 {
+			sort.Strings(existing)
+			return fmt.Errorf("secret already exists (%s), you can use --force to overwrite it", strings.Join(existing, ", "))
+		}
+	}
+
+	for key, value := range values {
+		current[key] = value
+	}
+
+	secret, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.client.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(path),
+		SecretString: aws.String(string(secret)),
+	})
+
+	return err
+}
+
+func (b *secretsManagerBackend) Pull(path string) (map[string]string, error) {
+	out, err := b.client.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(path),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+
+	if out.SecretString == nil {
+		return values, nil
+	}
+
+	if err := json.Unmarshal([]byte(*out.SecretString), &values); err != nil {
+		return nil, fmt.Errorf("secret %s is not a JSON object of strings: %w", path, err)
+	}
+
+	return values, nil
+}
+
+func (b *secretsManagerBackend) Remove(path string) error {
+	_, err := b.client.DeleteSecret(&secretsmanager.DeleteSecretInput{
+		SecretId: aws.String(path),
+	})
+
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		return nil
+	}
+
+	return err
+}
+
+func (b *secretsManagerBackend) Delete(path string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	current, err := b.Pull(path)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		delete(current, key)
+	}
+
+	secret, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.client.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(path),
+		SecretString: aws.String(string(secret)),
+	})
+
+	return err
+}
@@ -0,0 +1,87 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/golang/mock/gomock"
+	"github.com/hazelops/ize/pkg/mocks"
+)
+
+//go:generate mockgen -package=mocks -destination ../../pkg/mocks/mock_secretsmanager.go github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface SecretsManagerAPI
+
+func TestSecretsManagerBackend_Push(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    map[string]string
+		overwrite bool
+		mockSM    func(m *mocks.MockSecretsManagerAPI)
+		wantErr   bool
+	}{
+		{
+			name:   "create secret",
+			values: map[string]string{"API_KEY": "one"},
+			mockSM: func(m *mocks.MockSecretsManagerAPI) {
+				m.EXPECT().GetSecretValue(gomock.Any()).Return(nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "", nil)).Times(1)
+				m.EXPECT().CreateSecret(gomock.Any()).DoAndReturn(func(input *secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error) {
+					if *input.SecretString != `{"API_KEY":"one"}` {
+						t.Errorf("CreateSecret() secret = %s", *input.SecretString)
+					}
+					return &secretsmanager.CreateSecretOutput{}, nil
+				}).Times(1)
+			},
+		},
+		{
+			name:   "merge into existing secret",
+			values: map[string]string{"API_KEY": "one"},
+			mockSM: func(m *mocks.MockSecretsManagerAPI) {
+				m.EXPECT().GetSecretValue(gomock.Any()).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"DB_PASSWORD":"two"}`),
+				}, nil).Times(1)
+				m.EXPECT().PutSecretValue(gomock.Any()).DoAndReturn(func(input *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error) {
+					if *input.SecretString != `{"API_KEY":"one","DB_PASSWORD":"two"}` {
+						t.Errorf("PutSecretValue() secret = %s", *input.SecretString)
+					}
+					return &secretsmanager.PutSecretValueOutput{}, nil
+				}).Times(1)
+			},
+		},
+		{
+			name:   "existing key without overwrite",
+			values: map[string]string{"API_KEY": "one"},
+			mockSM: func(m *mocks.MockSecretsManagerAPI) {
+				m.EXPECT().GetSecretValue(gomock.Any()).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"API_KEY":"old"}`),
+				}, nil).Times(1)
+			},
+			wantErr: true,
+		},
+		{
+			name:      "existing key with overwrite",
+			values:    map[string]string{"API_KEY": "one"},
+			overwrite: true,
+			mockSM: func(m *mocks.MockSecretsManagerAPI) {
+				m.EXPECT().GetSecretValue(gomock.Any()).Return(&secretsmanager.GetSecretValueOutput{
+					SecretString: aws.String(`{"API_KEY":"old"}`),
+				}, nil).Times(1)
+				m.EXPECT().PutSecretValue(gomock.Any()).Return(&secretsmanager.PutSecretValueOutput{}, nil).Times(1)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockSMAPI := mocks.NewMockSecretsManagerAPI(ctrl)
+			tt.mockSM(mockSMAPI)
+
+			b := &secretsManagerBackend{client: mockSMAPI, app: "squibby"}
+			if err := b.Push("test/squibby", tt.values, tt.overwrite); (err != nil) != tt.wantErr {
+				t.Errorf("Push() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
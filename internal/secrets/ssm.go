@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// ssmBackend stores every secret as a SecureString parameter under the path
+type ssmBackend struct {
+	client         ssmiface.SSMAPI
+	app            string
+	includeStrings bool
+}
+
+func (b *ssmBackend) Push(path string, values map[string]string, overwrite bool) error {
+	for key, value := range values {
+		name := fmt.Sprintf("%s/%s", path, key)
+
+		_, err := b.client.PutParameter(&ssm.PutParameterInput{
+			Name:      &name,
+			Value:     aws.String(value),
+			Type:      aws.String(ssm.ParameterTypeSecureString),
+			Overwrite: aws.Bool(overwrite),
+		})
+
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "ParameterAlreadyExists":
+				return fmt.Errorf("secret already exists, you can use --force to overwrite it")
+			default:
+				return err
+			}
+		}
+
+		key := key
+		_, err = b.client.AddTagsToResource(&ssm.AddTagsToResourceInput{
+			ResourceId:   &name,
+			ResourceType: aws.String("Parameter"),
+			Tags: []*ssm.Tag{
+				{
+					Key:   aws.String("Application"),
+					Value: &b.app,
+				},
+				{
+					Key:   aws.String("EnvVarName"),
+					Value: &key,
+				},
+			},
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Pull returns parameters directly under the path by their names relative to it.
+// Nested parameters are not secrets of the path, so they are not pulled.
+func (b *ssmBackend) Pull(path string) (map[string]string, error) {
+	typeValues := []string{"SecureString"}
+	if b.includeStrings {
+		typeValues = []string{"SecureString", "String"}
+	}
+
+	return b.parameters(path, []*ssm.ParameterStringFilter{
+		{
+			Key:    aws.String("Type"),
+			Values: aws.StringSlice(typeValues),
+		},
+	}, true)
+}
+
+func (b *ssmBackend) Remove(path string) error {
+	values, err := b.parameters(path, nil, false)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return b.Delete(path, keys)
+}
+
+// parameters returns values of parameters directly under the path by their names relative to it
+func (b *ssmBackend) parameters(path string, filters []*ssm.ParameterStringFilter, decrypt bool) (map[string]string, error) {
+	values := map[string]string{}
+	prefix := strings.TrimSuffix(path, "/") + "/"
+
+	var nextToken *string
+
+	for {
+		params, err := b.client.GetParametersByPath(&ssm.GetParametersByPathInput{
+			Path:             aws.String(path),
+			Recursive:        aws.Bool(false),
+			WithDecryption:   aws.Bool(decrypt),
+			NextToken:        nextToken,
+			ParameterFilters: filters,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, param := range params.Parameters {
+			values[strings.TrimPrefix(*param.Name, prefix)] = *param.Value
+		}
+
+		if params.NextToken == nil {
+			break
+		}
+
+		nextToken = params.NextToken
+	}
+
+	return values, nil
+}
+
+func (b *ssmBackend) Delete(path string, keys []string) error {
+	// DeleteParameters accepts at most 10 names per call
+	for i := 0; i < len(keys); i += 10 {
+		end := i + 10
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		var names []*string
+		for _, key := range keys[i:end] {
+			names = append(names, aws.String(fmt.Sprintf("%s/%s", path, key)))
+		}
+
+		_, err := b.client.DeleteParameters(&ssm.DeleteParametersInput{
+			Names: names,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
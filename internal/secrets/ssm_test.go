@@ -0,0 +1,81 @@
+package secrets
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/golang/mock/gomock"
+	"github.com/hazelops/ize/pkg/mocks"
+)
+
+func TestSSMBackend_Pull(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSSM := mocks.NewMockSSMAPI(ctrl)
+	mockSSM.EXPECT().GetParametersByPath(gomock.Any()).DoAndReturn(func(input *ssm.GetParametersByPathInput) (*ssm.GetParametersByPathOutput, error) {
+		if aws.BoolValue(input.Recursive) {
+			t.Errorf("GetParametersByPath() is recursive")
+		}
+
+		if got := aws.StringValueSlice(input.ParameterFilters[0].Values); !reflect.DeepEqual(got, []string{"SecureString", "String"}) {
+			t.Errorf("GetParametersByPath() types = %v", got)
+		}
+
+		if input.NextToken == nil {
+			return &ssm.GetParametersByPathOutput{
+				Parameters: []*ssm.Parameter{{Name: aws.String("/dev/goblin/API_KEY"), Value: aws.String("one")}},
+				NextToken:  aws.String("next"),
+			}, nil
+		}
+
+		return &ssm.GetParametersByPathOutput{
+			Parameters: []*ssm.Parameter{{Name: aws.String("/dev/goblin/DB_PASSWORD"), Value: aws.String("two")}},
+		}, nil
+	}).Times(2)
+
+	b := &ssmBackend{client: mockSSM, app: "goblin", includeStrings: true}
+
+	got, err := b.Pull("/dev/goblin")
+	if err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+
+	want := map[string]string{"API_KEY": "one", "DB_PASSWORD": "two"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Pull() = %v, want %v", got, want)
+	}
+}
+
+func TestSSMBackend_Remove(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var params []*ssm.Parameter
+	var want []string
+	for _, key := range []string{"A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K"} {
+		params = append(params, &ssm.Parameter{Name: aws.String("/dev/goblin/" + key), Value: aws.String(key)})
+		want = append(want, "/dev/goblin/"+key)
+	}
+
+	var deleted []string
+
+	mockSSM := mocks.NewMockSSMAPI(ctrl)
+	mockSSM.EXPECT().GetParametersByPath(gomock.Any()).Return(&ssm.GetParametersByPathOutput{Parameters: params}, nil).Times(1)
+	mockSSM.EXPECT().DeleteParameters(gomock.Any()).DoAndReturn(func(input *ssm.DeleteParametersInput) (*ssm.DeleteParametersOutput, error) {
+		deleted = append(deleted, aws.StringValueSlice(input.Names)...)
+		return &ssm.DeleteParametersOutput{}, nil
+	}).Times(2)
+
+	b := &ssmBackend{client: mockSSM, app: "goblin"}
+
+	if err := b.Remove("/dev/goblin"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(deleted, want) {
+		t.Errorf("Remove() deleted = %v, want %v", deleted, want)
+	}
+}
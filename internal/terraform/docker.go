@@ -234,7 +234,7 @@ func (d *docker) RunUI(ui terminal.UI) error {
 	select {
 	case status := <-wait:
 		if status.StatusCode != 0 {
-			return fmt.Errorf("container exit status code %d\n", status.StatusCode)
+			return &ExitError{Code: int(status.StatusCode)}
 		}
 		s.Done()
 		return nil
@@ -371,7 +371,7 @@ func (d *docker) Run() error {
 	select {
 	case status := <-wait:
 		if status.StatusCode != 0 {
-			return fmt.Errorf("container exit status code %d\n", status.StatusCode)
+			return &ExitError{Code: int(status.StatusCode)}
 		}
 		return nil
 	case err := <-errC:
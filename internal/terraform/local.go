@@ -132,7 +132,7 @@ func runCommand(cmd *exec.Cmd, out io.Writer) (stdout, stderr string, err error)
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			if s, ok := exitErr.Sys().(syscall.WaitStatus); ok {
-				err = fmt.Errorf("exit status: %d", s.ExitStatus())
+				err = &ExitError{Code: s.ExitStatus()}
 			}
 		}
 	}
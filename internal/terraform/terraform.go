@@ -1,6 +1,7 @@
 package terraform
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/hazelops/ize/pkg/terminal"
@@ -13,3 +14,12 @@ type Terraform interface {
 	NewCmd(cmd []string)
 	SetOut(out io.Writer)
 }
+
+// ExitError is returned when terraform exits with a non-zero code
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status: %d", e.Code)
+}
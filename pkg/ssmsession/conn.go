@@ -0,0 +1,109 @@
+package ssmsession
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// Dial starts the plugin serving the session over its standard streams and returns the connection to the remote port,
+// e.g. to sshd of an AWS-StartSSHSession session. Messages of the plugin are written to stderr. Closing the connection stops the plugin.
+func (s SSMPluginCommand) Dial(output *ssm.StartSessionOutput, input *ssm.StartSessionInput, stderr io.Writer) (net.Conn, error) {
+	cmd, err := s.PortForwardingCommand(output, input)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Stderr = stderr
+
+	return NewPluginConn(cmd, aws.StringValue(input.Target))
+}
+
+// NewPluginConn starts the command and returns the connection over its stdin and stdout
+func NewPluginConn(cmd *exec.Cmd, target string) (net.Conn, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to %s: %w", target, err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to %s: %w", target, err)
+	}
+
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("can't start %s: %w", cmd.Path, err)
+	}
+
+	return &pluginConn{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: stdout,
+		addr:   pluginAddr(target),
+	}, nil
+}
+
+// pluginConn is a net.Conn over standard streams of the plugin. Deadlines are not supported.
+type pluginConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	addr   pluginAddr
+	once   sync.Once
+}
+
+func (c *pluginConn) Read(b []byte) (int, error) {
+	return c.stdout.Read(b)
+}
+
+func (c *pluginConn) Write(b []byte) (int, error) {
+	return c.stdin.Write(b)
+}
+
+// Close stops the plugin and waits for it to exit
+func (c *pluginConn) Close() error {
+	c.once.Do(func() {
+		_ = c.stdin.Close()
+		_ = c.cmd.Process.Kill()
+		_ = c.cmd.Wait()
+	})
+
+	return nil
+}
+
+func (c *pluginConn) LocalAddr() net.Addr {
+	return pluginAddr("local")
+}
+
+func (c *pluginConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func (c *pluginConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+func (c *pluginConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (c *pluginConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// pluginAddr is the SSM session target
+type pluginAddr string
+
+func (a pluginAddr) Network() string {
+	return "ssm"
+}
+
+func (a pluginAddr) String() string {
+	return string(a)
+}
@@ -1,23 +1,29 @@
 package ssmsession
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/Netflix/go-expect"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/hazelops/ize/pkg/term"
 )
 
 const (
 	ssmPluginBinaryName = "session-manager-plugin"
 	startSessionAction  = "StartSession"
+	// exitCodeMarker precedes the exit code of a command wrapped by WrapCommand in the session output
+	exitCodeMarker = "__IZE_EXIT_CODE__:"
 )
 
 type SSMPluginRunner interface {
@@ -85,6 +91,96 @@ func (s SSMPluginCommand) StartInteractive(ssmSession *ecs.Session) error {
 	return nil
 }
 
+// WrapCommand returns the shell command running the command and printing its exit code,
+// so that StartNonInteractive can return it. ECS runs every command in an interactive session,
+// which doesn't pass the exit code of the command.
+func WrapCommand(command string) string {
+	return fmt.Sprintf(`/bin/sh -c '(%s); echo "%s$?"'`, strings.ReplaceAll(command, "'", `'\''`), exitCodeMarker)
+}
+
+// StartNonInteractive runs the session of a command wrapped by WrapCommand without a local terminal.
+// It writes the output of the command to w line by line without messages of the plugin and returns the exit code of the command.
+func (s SSMPluginCommand) StartNonInteractive(ssmSession *ecs.Session, w io.Writer) (int, error) {
+	response, err := json.Marshal(ssmSession)
+	if err != nil {
+		return 0, fmt.Errorf("marshal session response: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+
+	cmd := exec.Command(ssmPluginBinaryName, []string{string(response), s.region, startSessionAction}...)
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	exitCode := -1
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			line := strings.TrimRight(scanner.Text(), "\r")
+
+			switch {
+			case strings.HasPrefix(line, "Starting session with SessionId"),
+				strings.HasPrefix(line, "Exiting session with sessionId"),
+				strings.HasPrefix(line, "\x1b]0;"):
+				continue
+			case strings.Contains(line, exitCodeMarker):
+				// output of the command may not end with a newline
+				i := strings.LastIndex(line, exitCodeMarker)
+				if code, err := strconv.Atoi(line[i+len(exitCodeMarker):]); err == nil {
+					exitCode = code
+					if i == 0 {
+						continue
+					}
+					line = line[:i]
+				}
+			}
+
+			fmt.Fprintln(w, line)
+		}
+
+		// drain the rest, so that the plugin doesn't block on a long line
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	_, _, pluginExitCode, err := s.Run(cmd)
+	pw.Close()
+	<-done
+
+	if err != nil {
+		return 0, fmt.Errorf("start session: %w", err)
+	}
+
+	if exitCode == -1 {
+		return 0, fmt.Errorf("session ended without exit code of the command (plugin exit code %d)", pluginExitCode)
+	}
+
+	return exitCode, nil
+}
+
+// PortForwardingCommand returns the plugin command serving the port forwarding session started with the input.
+// The command keeps running until the session is terminated.
+func (s SSMPluginCommand) PortForwardingCommand(output *ssm.StartSessionOutput, input *ssm.StartSessionInput) (*exec.Cmd, error) {
+	response, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("marshal session response: %w", err)
+	}
+
+	parameters, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("marshal session parameters: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("https://ssm.%s.amazonaws.com", s.region)
+
+	return exec.Command(ssmPluginBinaryName, string(response), s.region, startSessionAction, "", string(parameters), endpoint), nil
+}
+
 func (s SSMPluginCommand) Run(cmd *exec.Cmd) (stdout, stderr string, exitCode int, err error) {
 
 	if err = cmd.Start(); err != nil {